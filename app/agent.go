@@ -0,0 +1,178 @@
+// Package app builds the agent's dependency graph from the config, so the
+// server and the commands that run the agent (cmd/retry) wire it the same way.
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	firebase "firebase.google.com/go/v4"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/googlegenai"
+	"github.com/jomei/notionapi"
+
+	"youdoyou-server/channel"
+	"youdoyou-server/config"
+	"youdoyou-server/lock"
+	"youdoyou-server/model"
+	"youdoyou-server/notify"
+	"youdoyou-server/queue"
+	"youdoyou-server/repository"
+	"youdoyou-server/search"
+	"youdoyou-server/service"
+	"youdoyou-server/tool"
+)
+
+// threadLockMargin keeps a thread's lease a little longer than the job deadline
+const threadLockMargin = time.Minute
+
+// Agent holds the agent and the repositories and services around it
+type Agent struct {
+	Genkit *genkit.Genkit
+	// ChatRepo pushes and posts (Slack / LINE) every assistant message it saves
+	ChatRepo         repository.ChatRepository
+	NotificationRepo repository.NotificationRepository
+	ToolPolicyRepo   repository.ToolPolicyRepository
+	TaskRepo         repository.TaskRepository
+	ScheduleRepo     repository.ScheduleRepository
+	Searcher         *search.Searcher
+	ToolFactory      *tool.ToolFactory
+	ToolPolicies     *service.ToolPolicyService
+	AgentService     *service.AgentService
+	RetryService     *service.RetryService
+	// Worker runs queued jobs with a deadline, one run per thread at a time
+	Worker *queue.Worker
+	// SlackAdapter / LineAdapter are nil when the channel is not configured
+	SlackAdapter channel.Adapter
+	LineAdapter  channel.Adapter
+
+	mcpTools *tool.MCPTools
+}
+
+// Options are what differs between the binaries
+type Options struct {
+	// Concurrency is the number of jobs the worker runs at once
+	Concurrency int
+	// LockBackend overrides cfg.ThreadLockBackend when set
+	LockBackend string
+}
+
+// NewAgent builds the agent. Call Close when done.
+func NewAgent(ctx context.Context, cfg *config.Config, client *firestore.Client, firebaseApp *firebase.App, opts Options) (*Agent, error) {
+	switch cfg.ToolRouting {
+	case service.RoutingModel, service.RoutingRules, service.RoutingOff:
+	default:
+		return nil, fmt.Errorf("unknown TOOL_ROUTING %q (want model, rules or off)", cfg.ToolRouting)
+	}
+
+	a := &Agent{
+		Genkit: genkit.Init(ctx,
+			genkit.WithPlugins(&googlegenai.GoogleAI{APIKey: cfg.GoogleGenaiApiKey}),
+			genkit.WithDefaultModel("googleai/gemini-3-flash-preview"),
+		),
+		NotificationRepo: repository.NewFirestoreNotificationRepository(client),
+		ToolPolicyRepo:   repository.NewFirestoreToolPolicyRepository(client),
+		TaskRepo:         repository.NewFirestoreTaskRepository(client),
+		ScheduleRepo:     repository.NewFirestoreScheduleRepository(client),
+	}
+
+	chatRepo, err := a.chatRepository(ctx, cfg, client, firebaseApp)
+	if err != nil {
+		return nil, err
+	}
+	a.ChatRepo = chatRepo
+
+	// External MCP servers (optional)
+	var mcpConfig *tool.MCPConfig
+	if cfg.MCPConfigPath != "" {
+		if mcpConfig, err = tool.LoadMCPConfig(cfg.MCPConfigPath); err != nil {
+			return nil, fmt.Errorf("failed to load MCP config: %w", err)
+		}
+	}
+	a.mcpTools = tool.ConnectMCPServers(ctx, a.Genkit, mcpConfig)
+
+	notionRepo := repository.NewNotionRepository(notionapi.NewClient(notionapi.Token(cfg.NotionToken)))
+	a.Searcher = search.NewSearcher(repository.NewFirestoreSearchRepository(client), chatRepo)
+	a.ToolFactory = tool.NewToolFactory(a.Genkit, chatRepo, nil, notionRepo, a.TaskRepo, a.ScheduleRepo, a.Searcher, a.mcpTools)
+	a.ToolPolicies = service.NewToolPolicyService(a.ToolPolicyRepo, service.ToolPolicyDefaults{
+		Groups:              cfg.ToolGroups,
+		ReadOnlyUsers:       cfg.ToolReadOnlyUsers,
+		PrivateThreadWrites: cfg.ToolPrivateThreadWrites,
+	})
+	a.AgentService = service.NewAgentService(chatRepo, nil, notionRepo, a.Genkit, a.ToolFactory, a.ToolPolicies,
+		service.NewToolExecutor(cfg.ToolConcurrency, cfg.ToolTimeout),
+		service.NewWorkflowRouter(a.Genkit, cfg.ToolRouting),
+		service.NewTitleService(chatRepo, a.Genkit))
+	a.RetryService = service.NewRetryService(repository.NewFirestoreFailedRunRepository(client), chatRepo, a.AgentService, cfg.RetryMaxAttempts, cfg.RetryBaseDelay)
+
+	locker, err := newLocker(cfg, client, opts.LockBackend)
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	serializer := service.NewThreadSerializer(locker, a.RetryService.Run)
+	a.Worker = queue.NewWorker(func(ctx context.Context, job queue.Job) error {
+		return serializer.Run(ctx, job.ThreadID, service.ChatOptions{
+			ReplyTo:    job.MessageID,
+			BranchID:   job.BranchID,
+			Retry:      job.Retry,
+			Initiate:   job.Prompt,
+			ScheduleID: job.ScheduleID,
+		})
+	}, opts.Concurrency, cfg.JobTimeout)
+	return a, nil
+}
+
+// Close disconnects the external MCP servers
+func (a *Agent) Close() {
+	a.mcpTools.Close()
+}
+
+// chatRepository wraps the Firestore repository so that every assistant
+// message is pushed, and posted back to its Slack / LINE conversation. The
+// channel adapters of the configured channels are set on a.
+func (a *Agent) chatRepository(ctx context.Context, cfg *config.Config, client *firestore.Client, firebaseApp *firebase.App) (repository.ChatRepository, error) {
+	chatRepo := repository.NewFirestoreChatRepository(client)
+
+	switch cfg.PushBackend {
+	case "fcm":
+		sender, err := notify.NewFCMSender(ctx, firebaseApp)
+		if err != nil {
+			return nil, err
+		}
+		chatRepo = service.NewNotifyingChatRepository(chatRepo, service.NewNotificationService(a.NotificationRepo, chatRepo, sender))
+	case "off":
+	default:
+		return nil, fmt.Errorf("unknown PUSH_BACKEND %q (want fcm or off)", cfg.PushBackend)
+	}
+
+	outbound := make(map[string]channel.Outbound)
+	if cfg.SlackSigningSecret != "" && cfg.SlackBotToken != "" {
+		a.SlackAdapter = channel.NewSlackAdapter(cfg.SlackSigningSecret)
+		outbound[model.ChannelSlack] = channel.NewSlackClient(cfg.SlackBotToken)
+	}
+	if cfg.LineChannelSecret != "" && cfg.LineChannelAccessToken != "" {
+		a.LineAdapter = channel.NewLineAdapter(cfg.LineChannelSecret)
+		outbound[model.ChannelLine] = channel.NewLineClient(cfg.LineChannelAccessToken)
+	}
+	if len(outbound) > 0 {
+		chatRepo = service.NewChannelChatRepository(chatRepo, outbound)
+	}
+	return chatRepo, nil
+}
+
+func newLocker(cfg *config.Config, client *firestore.Client, backend string) (lock.Locker, error) {
+	if backend == "" {
+		backend = cfg.ThreadLockBackend
+	}
+	switch backend {
+	case "firestore":
+		return lock.NewFirestoreLocker(client, cfg.JobTimeout+threadLockMargin), nil
+	case "memory":
+		return lock.NewMemoryLocker(), nil
+	default:
+		return nil, fmt.Errorf("unknown THREAD_LOCK_BACKEND %q (want firestore or memory)", backend)
+	}
+}
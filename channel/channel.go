@@ -0,0 +1,52 @@
+// Package channel connects chat platforms (Slack, LINE) to the agent. An
+// Adapter verifies and parses a platform's webhook; an Outbound posts the
+// agent's replies back through the platform's API.
+package channel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"youdoyou-server/model"
+)
+
+// ErrBadSignature is returned by Adapter.Parse for requests that were not
+// signed by the platform
+var ErrBadSignature = errors.New("invalid webhook signature")
+
+// Message is a user message received from a platform
+type Message struct {
+	// Channel is model.ChannelSlack or model.ChannelLine
+	Channel string
+	// ConversationKey identifies the platform conversation a thread continues
+	ConversationKey string
+	// UserID is the sender's ID on the platform
+	UserID string
+	Text   string
+	// Reply is where answers in this conversation are posted
+	Reply  model.ChannelRef
+	SentAt time.Time
+	// EventID is the platform's ID of the event (Slack event_id, LINE
+	// webhookEventId). Redeliveries of an event carry the same ID.
+	EventID string
+}
+
+// Webhook is a verified webhook request
+type Webhook struct {
+	// Challenge must be echoed back in the response (Slack URL verification)
+	Challenge string
+	Messages  []Message
+}
+
+type Adapter interface {
+	// Parse verifies the request signature and returns the user messages the
+	// request carries. Events the agent should not answer are dropped.
+	Parse(header http.Header, body []byte) (*Webhook, error)
+}
+
+type Outbound interface {
+	// Post sends text to the conversation to
+	Post(ctx context.Context, to model.ChannelRef, text string) error
+}
@@ -0,0 +1,140 @@
+package channel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"youdoyou-server/model"
+)
+
+// LineAdapter handles the LINE Messaging API webhook. A LINE chat has no
+// threads, so each 1:1 chat, group or room continues one youdoyou thread.
+// In groups and rooms the agent only answers messages that mention it.
+type LineAdapter struct {
+	channelSecret string
+}
+
+func NewLineAdapter(channelSecret string) *LineAdapter {
+	return &LineAdapter{channelSecret: channelSecret}
+}
+
+type linePayload struct {
+	Events []lineEvent `json:"events"`
+}
+
+type lineEvent struct {
+	Type           string `json:"type"`
+	WebhookEventID string `json:"webhookEventId"`
+	Timestamp      int64  `json:"timestamp"` // milliseconds
+	Source         struct {
+		Type    string `json:"type"` // user, group or room
+		UserID  string `json:"userId"`
+		GroupID string `json:"groupId"`
+		RoomID  string `json:"roomId"`
+	} `json:"source"`
+	Message struct {
+		Type    string `json:"type"`
+		Text    string `json:"text"`
+		Mention *struct {
+			Mentionees []struct {
+				Index  int  `json:"index"`
+				Length int  `json:"length"`
+				IsSelf bool `json:"isSelf"`
+			} `json:"mentionees"`
+		} `json:"mention"`
+	} `json:"message"`
+}
+
+func (a *LineAdapter) Parse(header http.Header, body []byte) (*Webhook, error) {
+	if err := a.verify(header, body); err != nil {
+		return nil, err
+	}
+
+	var payload linePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse LINE webhook: %w", err)
+	}
+
+	hook := &Webhook{}
+	for _, event := range payload.Events {
+		if event.Type != "message" || event.Message.Type != "text" || event.Source.UserID == "" {
+			continue
+		}
+
+		target := event.Source.UserID
+		text := event.Message.Text
+		if event.Source.Type != "user" {
+			target = event.Source.GroupID + event.Source.RoomID
+			var ok bool
+			if text, ok = lineMentioned(event); !ok {
+				continue
+			}
+		}
+		text = strings.TrimSpace(text)
+		if text == "" || target == "" {
+			continue
+		}
+
+		hook.Messages = append(hook.Messages, Message{
+			Channel:         model.ChannelLine,
+			ConversationKey: target,
+			UserID:          event.Source.UserID,
+			Text:            text,
+			Reply:           model.ChannelRef{Channel: model.ChannelLine, Target: target},
+			SentAt:          time.UnixMilli(event.Timestamp),
+			EventID:         event.WebhookEventID,
+		})
+	}
+	return hook, nil
+}
+
+// verify checks X-Line-Signature: base64 HMAC-SHA256 of the body
+func (a *LineAdapter) verify(header http.Header, body []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(header.Get("X-Line-Signature"))
+	if err != nil {
+		return ErrBadSignature
+	}
+	mac := hmac.New(sha256.New, []byte(a.channelSecret))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// lineMentioned returns the text without the mentions of the bot, and whether
+// the bot was mentioned at all
+func lineMentioned(event lineEvent) (string, bool) {
+	if event.Message.Mention == nil {
+		return "", false
+	}
+	// Mention offsets count UTF-16 code units
+	text := []rune(event.Message.Text)
+	mentioned := false
+	var kept []rune
+	pos := 0 // UTF-16 offset of text[i]
+	for _, r := range text {
+		inMention := false
+		for _, m := range event.Message.Mention.Mentionees {
+			if m.IsSelf && pos >= m.Index && pos < m.Index+m.Length {
+				inMention, mentioned = true, true
+				break
+			}
+		}
+		if !inMention {
+			kept = append(kept, r)
+		}
+		if r >= 0x10000 {
+			pos += 2
+		} else {
+			pos++
+		}
+	}
+	return string(kept), mentioned
+}
@@ -0,0 +1,87 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"youdoyou-server/model"
+)
+
+const lineAPIURL = "https://api.line.me/v2/bot"
+
+// LINE limits a text message to 5000 characters and a push to 5 messages
+const (
+	lineMaxText     = 5000
+	lineMaxMessages = 5
+)
+
+// LineClient sends messages with the LINE Messaging API. Replies use the
+// push API: reply tokens expire within a minute, sooner than a long run ends.
+type LineClient struct {
+	accessToken string
+	baseURL     string
+	httpClient  *http.Client
+}
+
+func NewLineClient(channelAccessToken string) *LineClient {
+	return &LineClient{
+		accessToken: channelAccessToken,
+		baseURL:     lineAPIURL,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type lineTextMessage struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func (c *LineClient) Post(ctx context.Context, to model.ChannelRef, text string) error {
+	var messages []lineTextMessage
+	for _, chunk := range splitText(text, lineMaxText, lineMaxMessages) {
+		messages = append(messages, lineTextMessage{Type: "text", Text: chunk})
+	}
+	body, err := json.Marshal(map[string]any{
+		"to":       to.Target,
+		"messages": messages,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/message/push", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push to LINE: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to push to LINE: status %d: %s", resp.StatusCode, detail)
+	}
+	return nil
+}
+
+// splitText cuts text into at most max chunks of size runes; the last chunk
+// is truncated if text is longer than that
+func splitText(text string, size int, max int) []string {
+	runes := []rune(text)
+	var chunks []string
+	for len(runes) > 0 && len(chunks) < max {
+		n := min(size, len(runes))
+		chunks = append(chunks, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return chunks
+}
@@ -0,0 +1,96 @@
+package channel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+
+	"youdoyou-server/model"
+)
+
+const lineSecret = "line-secret"
+
+// lineHeader signs body like LINE does
+func lineHeader(body string) http.Header {
+	mac := hmac.New(sha256.New, []byte(lineSecret))
+	mac.Write([]byte(body))
+	header := http.Header{}
+	header.Set("X-Line-Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestLineParsesUserMessage(t *testing.T) {
+	body := `{"events":[{"type":"message","webhookEventId":"E1","timestamp":1700000000000,"source":{"type":"user","userId":"U1"},"message":{"type":"text","text":" 買い物リスト "}}]}`
+
+	hook, err := NewLineAdapter(lineSecret).Parse(lineHeader(body), []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hook.Messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(hook.Messages))
+	}
+	msg := hook.Messages[0]
+	if msg.Text != "買い物リスト" || msg.ConversationKey != "U1" || msg.UserID != "U1" || msg.EventID != "E1" {
+		t.Errorf("message = %+v", msg)
+	}
+	if want := (model.ChannelRef{Channel: model.ChannelLine, Target: "U1"}); msg.Reply != want {
+		t.Errorf("Reply = %+v, want %+v", msg.Reply, want)
+	}
+}
+
+func TestLineRejectsBadSignature(t *testing.T) {
+	body := `{"events":[]}`
+
+	cases := map[string]http.Header{
+		"tampered body": lineHeader(`{"events":[{}]}`),
+		"not base64":    {"X-Line-Signature": {"???"}},
+		"unsigned":      {},
+	}
+	for name, header := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewLineAdapter(lineSecret).Parse(header, []byte(body)); !errors.Is(err, ErrBadSignature) {
+				t.Errorf("err = %v, want ErrBadSignature", err)
+			}
+		})
+	}
+}
+
+func TestLineAnswersGroupsOnlyWhenMentioned(t *testing.T) {
+	// "@bot 天気は？": the mention covers the first 4 UTF-16 units
+	body := `{"events":[
+		{"type":"message","source":{"type":"group","groupId":"G1","userId":"U1"},"message":{"type":"text","text":"おはよう"}},
+		{"type":"message","source":{"type":"group","groupId":"G1","userId":"U1"},"message":{"type":"text","text":"@bot 天気は？","mention":{"mentionees":[{"index":0,"length":4,"isSelf":true}]}}},
+		{"type":"message","source":{"type":"group","groupId":"G1","userId":"U1"},"message":{"type":"text","text":"@someone hi","mention":{"mentionees":[{"index":0,"length":8,"isSelf":false}]}}}
+	]}`
+
+	hook, err := NewLineAdapter(lineSecret).Parse(lineHeader(body), []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hook.Messages) != 1 {
+		t.Fatalf("got %+v, want only the message mentioning the bot", hook.Messages)
+	}
+	if msg := hook.Messages[0]; msg.Text != "天気は？" || msg.ConversationKey != "G1" {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestLineIgnoresOtherEvents(t *testing.T) {
+	body := `{"events":[
+		{"type":"follow","source":{"type":"user","userId":"U1"}},
+		{"type":"message","source":{"type":"user","userId":"U1"},"message":{"type":"sticker"}},
+		{"type":"message","source":{"type":"user"},"message":{"type":"text","text":"no sender"}}
+	]}`
+
+	hook, err := NewLineAdapter(lineSecret).Parse(lineHeader(body), []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hook.Messages) != 0 {
+		t.Errorf("got %+v, want no messages", hook.Messages)
+	}
+}
@@ -0,0 +1,137 @@
+package channel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"youdoyou-server/model"
+)
+
+// slackMaxSkew rejects signed requests older than this, against replays
+const slackMaxSkew = 5 * time.Minute
+
+// slackMention matches user mentions such as <@U0123ABCD>
+var slackMention = regexp.MustCompile(`<@[A-Z0-9]+>`)
+
+// SlackAdapter handles the Slack Events API. The app should subscribe to
+// app_mention and message.im: the agent answers mentions and direct
+// messages, each Slack thread continuing one youdoyou thread.
+type SlackAdapter struct {
+	signingSecret string
+	now           func() time.Time
+}
+
+func NewSlackAdapter(signingSecret string) *SlackAdapter {
+	return &SlackAdapter{signingSecret: signingSecret, now: time.Now}
+}
+
+type slackPayload struct {
+	Type      string     `json:"type"`
+	Challenge string     `json:"challenge"`
+	TeamID    string     `json:"team_id"`
+	EventID   string     `json:"event_id"`
+	Event     slackEvent `json:"event"`
+}
+
+type slackEvent struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+func (a *SlackAdapter) Parse(header http.Header, body []byte) (*Webhook, error) {
+	if err := a.verify(header, body); err != nil {
+		return nil, err
+	}
+
+	var payload slackPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse Slack event: %w", err)
+	}
+	if payload.Type == "url_verification" {
+		return &Webhook{Challenge: payload.Challenge}, nil
+	}
+
+	// Slack retries when the first delivery took over 3 seconds, but that
+	// delivery was still handled
+	if header.Get("X-Slack-Retry-Reason") == "http_timeout" {
+		return &Webhook{}, nil
+	}
+
+	event := payload.Event
+	if payload.Type != "event_callback" || !slackAnswers(event) {
+		return &Webhook{}, nil
+	}
+
+	text := strings.TrimSpace(slackMention.ReplaceAllString(event.Text, ""))
+	if text == "" {
+		return &Webhook{}, nil
+	}
+	threadTS := event.ThreadTS
+	if threadTS == "" {
+		threadTS = event.TS
+	}
+	return &Webhook{Messages: []Message{{
+		Channel:         model.ChannelSlack,
+		ConversationKey: payload.TeamID + ":" + event.Channel + ":" + threadTS,
+		UserID:          payload.TeamID + ":" + event.User,
+		Text:            text,
+		Reply:           model.ChannelRef{Channel: model.ChannelSlack, Target: event.Channel, ThreadKey: threadTS},
+		SentAt:          slackTime(event.TS, a.now()),
+		EventID:         payload.EventID,
+	}}}, nil
+}
+
+// verify checks the v0 signature: HMAC-SHA256 of "v0:<timestamp>:<body>"
+func (a *SlackAdapter) verify(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if skew := a.now().Sub(time.Unix(sec, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return ErrBadSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(a.signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// slackAnswers reports whether the event is a user's mention of the app or
+// direct message to it. Edits, bot posts (including our own replies) and
+// other subtypes are ignored.
+func slackAnswers(event slackEvent) bool {
+	if event.Subtype != "" || event.BotID != "" || event.User == "" {
+		return false
+	}
+	return event.Type == "app_mention" || (event.Type == "message" && event.ChannelType == "im")
+}
+
+// slackTime converts a message ts ("1700000000.123456") to a time
+func slackTime(ts string, fallback time.Time) time.Time {
+	sec, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return fallback
+	}
+	return time.UnixMicro(int64(sec * 1e6))
+}
@@ -0,0 +1,65 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"youdoyou-server/model"
+)
+
+const slackAPIURL = "https://slack.com/api"
+
+// SlackClient posts messages with the Slack Web API (chat.postMessage)
+type SlackClient struct {
+	botToken   string
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewSlackClient(botToken string) *SlackClient {
+	return &SlackClient{
+		botToken:   botToken,
+		baseURL:    slackAPIURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *SlackClient) Post(ctx context.Context, to model.ChannelRef, text string) error {
+	body, err := json.Marshal(map[string]string{
+		"channel":   to.Target,
+		"thread_ts": to.ThreadKey,
+		"text":      text,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+c.botToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The Web API answers 200 with ok=false on most errors
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to post to Slack: status %d", resp.StatusCode)
+	}
+	if !result.OK {
+		return fmt.Errorf("failed to post to Slack: %s", result.Error)
+	}
+	return nil
+}
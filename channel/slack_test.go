@@ -0,0 +1,122 @@
+package channel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+)
+
+const slackSecret = "slack-secret"
+
+var slackNow = time.Unix(1700000000, 0)
+
+func newTestSlackAdapter() *SlackAdapter {
+	a := NewSlackAdapter(slackSecret)
+	a.now = func() time.Time { return slackNow }
+	return a
+}
+
+// slackHeader signs body like Slack does at the given time
+func slackHeader(body string, at time.Time) http.Header {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(slackSecret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestSlackParsesMention(t *testing.T) {
+	body := `{"type":"event_callback","team_id":"T1","event_id":"Ev1","event":{"type":"app_mention","user":"U1","text":"<@UBOT> 明日の予定は？","channel":"C1","ts":"1700000000.000100"}}`
+
+	hook, err := newTestSlackAdapter().Parse(slackHeader(body, slackNow), []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hook.Messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(hook.Messages))
+	}
+	msg := hook.Messages[0]
+	if msg.Text != "明日の予定は？" {
+		t.Errorf("Text = %q, want the mention removed", msg.Text)
+	}
+	if msg.ConversationKey != "T1:C1:1700000000.000100" || msg.UserID != "T1:U1" || msg.EventID != "Ev1" {
+		t.Errorf("message = %+v", msg)
+	}
+	want := model.ChannelRef{Channel: model.ChannelSlack, Target: "C1", ThreadKey: "1700000000.000100"}
+	if msg.Reply != want {
+		t.Errorf("Reply = %+v, want %+v", msg.Reply, want)
+	}
+}
+
+func TestSlackRejectsBadSignature(t *testing.T) {
+	body := `{"type":"event_callback","team_id":"T1","event":{"type":"app_mention","user":"U1","text":"hi","channel":"C1","ts":"1"}}`
+
+	cases := map[string]http.Header{
+		"tampered body": slackHeader(`{"type":"event_callback"}`, slackNow),
+		"stale request": slackHeader(body, slackNow.Add(-10*time.Minute)),
+		"unsigned":      {},
+	}
+	for name, header := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := newTestSlackAdapter().Parse(header, []byte(body)); !errors.Is(err, ErrBadSignature) {
+				t.Errorf("err = %v, want ErrBadSignature", err)
+			}
+		})
+	}
+}
+
+func TestSlackURLVerification(t *testing.T) {
+	body := `{"type":"url_verification","challenge":"abc123"}`
+
+	hook, err := newTestSlackAdapter().Parse(slackHeader(body, slackNow), []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hook.Challenge != "abc123" || len(hook.Messages) != 0 {
+		t.Errorf("hook = %+v, want only the challenge", hook)
+	}
+}
+
+func TestSlackIgnoresBotAndOtherMessages(t *testing.T) {
+	cases := map[string]string{
+		"own reply":      `{"type":"event_callback","team_id":"T1","event":{"type":"message","channel_type":"im","bot_id":"B1","user":"U1","text":"done","channel":"D1","ts":"1"}}`,
+		"bot subtype":    `{"type":"event_callback","team_id":"T1","event":{"type":"message","channel_type":"im","subtype":"bot_message","text":"done","channel":"D1","ts":"1"}}`,
+		"edit":           `{"type":"event_callback","team_id":"T1","event":{"type":"message","channel_type":"im","subtype":"message_changed","channel":"D1","ts":"1"}}`,
+		"channel chat":   `{"type":"event_callback","team_id":"T1","event":{"type":"message","channel_type":"channel","user":"U1","text":"hi","channel":"C1","ts":"1"}}`,
+		"mention only":   `{"type":"event_callback","team_id":"T1","event":{"type":"app_mention","user":"U1","text":"<@UBOT>","channel":"C1","ts":"1"}}`,
+		"other callback": `{"type":"app_rate_limited","team_id":"T1"}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			hook, err := newTestSlackAdapter().Parse(slackHeader(body, slackNow), []byte(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hook.Messages) != 0 {
+				t.Errorf("got %+v, want no messages", hook.Messages)
+			}
+		})
+	}
+}
+
+func TestSlackParsesDirectMessage(t *testing.T) {
+	body := `{"type":"event_callback","team_id":"T1","event_id":"Ev2","event":{"type":"message","channel_type":"im","user":"U1","text":"hi","channel":"D1","ts":"2","thread_ts":"1"}}`
+
+	hook, err := newTestSlackAdapter().Parse(slackHeader(body, slackNow), []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hook.Messages) != 1 || hook.Messages[0].ConversationKey != "T1:D1:1" {
+		t.Errorf("messages = %+v, want one in the Slack thread T1:D1:1", hook.Messages)
+	}
+}
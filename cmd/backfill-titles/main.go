@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"youdoyou-server/config"
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+	"youdoyou-server/service"
+
+	"cloud.google.com/go/firestore"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/googlegenai"
+)
+
+func main() {
+	// Parse flags
+	dryRun := flag.Bool("dry-run", false, "Print generated titles without saving them")
+	limit := flag.Int("limit", 0, "Maximum number of threads to title (0 = no limit)")
+	flag.Parse()
+
+	ctx := context.Background()
+	cfg := config.LoadConfig()
+
+	// Initialize Firestore
+	client, err := firestore.NewClient(ctx, cfg.FirestoreProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Failed to close Firestore client: %v", err)
+		}
+	}()
+
+	// Initialize Genkit
+	g := genkit.Init(ctx, genkit.WithPlugins(&googlegenai.GoogleAI{APIKey: cfg.GoogleGenaiApiKey}))
+
+	repo := repository.NewFirestoreChatRepository(client)
+	titleService := service.NewTitleService(repo, g)
+
+	docs, err := client.Collection("threads").Documents(ctx).GetAll()
+	if err != nil {
+		log.Fatalf("Failed to list threads: %v", err)
+	}
+
+	titled := 0
+	for _, doc := range docs {
+		if *limit > 0 && titled >= *limit {
+			break
+		}
+
+		var thread model.ChatThread
+		if err := doc.DataTo(&thread); err != nil {
+			log.Printf("Skipping thread %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		thread.ID = doc.Ref.ID
+		if thread.Title != "" {
+			continue
+		}
+
+		reply, err := firstReply(ctx, client, thread.ID)
+		if err != nil {
+			log.Printf("Skipping thread %s: %v", thread.ID, err)
+			continue
+		}
+		if reply == "" {
+			// Not answered yet; the agent will title it after its first reply
+			continue
+		}
+
+		if *dryRun {
+			out, err := titleService.Generate(ctx, thread.FirstMessage, reply)
+			if err != nil {
+				log.Printf("Failed to generate title for %s: %v", thread.ID, err)
+				continue
+			}
+			fmt.Printf("[%s] %s %v (dry run)\n", thread.ID, out.Title, out.Tags)
+		} else {
+			if err := titleService.TitleThread(ctx, &thread, reply); err != nil {
+				log.Printf("Failed to title thread %s: %v", thread.ID, err)
+				continue
+			}
+			fmt.Printf("[%s] %s %v\n", thread.ID, thread.Title, thread.Tags)
+		}
+		titled++
+	}
+
+	fmt.Printf("✅ Titled %d thread(s)\n", titled)
+}
+
+// firstReply returns the content of the earliest assistant message in the thread
+func firstReply(ctx context.Context, client *firestore.Client, threadID string) (string, error) {
+	docs, err := client.Collection("threads").Doc(threadID).Collection("messages").
+		Where("role", "==", model.RoleAssistant).
+		OrderBy("createdAt", firestore.Asc).
+		Limit(1).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return "", err
+	}
+	if len(docs) == 0 {
+		return "", nil
+	}
+
+	var msg model.ChatMessage
+	if err := docs[0].DataTo(&msg); err != nil {
+		return "", err
+	}
+	return msg.Content, nil
+}
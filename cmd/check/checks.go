@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+	"youdoyou-server/schema"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// Issue is a single inconsistency found by a check
+type Issue struct {
+	Check     string `json:"check"`
+	ThreadID  string `json:"threadId"`
+	MessageID string `json:"messageId,omitempty"`
+	Detail    string `json:"detail"`
+	Fixable   bool   `json:"fixable"`
+	Fixed     bool   `json:"fixed"`
+
+	fix func(ctx context.Context) error
+}
+
+// Check inspects the inventory and reports issues. Issues that can be repaired
+// without losing data carry a fix function.
+type Check struct {
+	Name        string
+	Description string
+	Run         func(inv *inventory) []Issue
+}
+
+// inventory is a snapshot of threads and messages loaded once for all checks
+type inventory struct {
+	client   *firestore.Client
+	counters *repository.FirestoreCounterRepository
+	threads  map[string]*model.ChatThread
+	messages map[string][]model.ChatMessage // by parent thread ID
+}
+
+var allChecks = []Check{
+	{
+		Name:        "memorized-until",
+		Description: "memorizedUntil is after the thread's latest message",
+		Run:         checkMemorizedUntil,
+	},
+	{
+		Name:        "counters",
+		Description: "replyCount / unreadCount do not match the messages subcollection",
+		Run:         checkCounters,
+	},
+	{
+		Name:        "unknown-role",
+		Description: "message role is not one of the schema enum values",
+		Run:         checkUnknownRole,
+	},
+	{
+		Name:        "orphaned-messages",
+		Description: "messages subcollection exists without a parent thread document",
+		Run:         checkOrphanedMessages,
+	},
+}
+
+func loadInventory(ctx context.Context, client *firestore.Client, threadID string) (*inventory, error) {
+	inv := &inventory{
+		client:   client,
+		counters: repository.NewFirestoreCounterRepository(client),
+		threads:  make(map[string]*model.ChatThread),
+		messages: make(map[string][]model.ChatMessage),
+	}
+
+	var threadDocs []*firestore.DocumentSnapshot
+	var msgQuery firestore.Query
+	if threadID != "" {
+		doc, err := client.Collection("threads").Doc(threadID).Get(ctx)
+		if err != nil && (doc == nil || doc.Exists()) {
+			return nil, fmt.Errorf("failed to get thread: %w", err)
+		}
+		if doc.Exists() {
+			threadDocs = append(threadDocs, doc)
+		}
+		msgQuery = client.Collection("threads").Doc(threadID).Collection("messages").Query
+	} else {
+		docs, err := client.Collection("threads").Documents(ctx).GetAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list threads: %w", err)
+		}
+		threadDocs = docs
+		msgQuery = client.CollectionGroup("messages").Query
+	}
+
+	for _, doc := range threadDocs {
+		var thread model.ChatThread
+		if err := doc.DataTo(&thread); err != nil {
+			return nil, fmt.Errorf("failed to parse thread %s: %w", doc.Ref.ID, err)
+		}
+		thread.ID = doc.Ref.ID
+		inv.threads[thread.ID] = &thread
+	}
+
+	iter := msgQuery.Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate messages: %w", err)
+		}
+		var msg model.ChatMessage
+		if err := doc.DataTo(&msg); err != nil {
+			return nil, fmt.Errorf("failed to parse message %s: %w", doc.Ref.ID, err)
+		}
+		msg.ID = doc.Ref.ID
+		parentID := doc.Ref.Parent.Parent.ID
+		inv.messages[parentID] = append(inv.messages[parentID], msg)
+	}
+
+	return inv, nil
+}
+
+func checkMemorizedUntil(inv *inventory) []Issue {
+	var issues []Issue
+	for id, thread := range inv.threads {
+		var latest time.Time
+		for _, msg := range inv.messages[id] {
+			if msg.CreatedAt.After(latest) {
+				latest = msg.CreatedAt
+			}
+		}
+		if latest.IsZero() || !thread.MemorizedUntil.After(latest) {
+			continue
+		}
+
+		ref := inv.client.Collection("threads").Doc(id)
+		issues = append(issues, Issue{
+			ThreadID: id,
+			Detail:   fmt.Sprintf("memorizedUntil %s > latest message %s", thread.MemorizedUntil.Format(time.RFC3339), latest.Format(time.RFC3339)),
+			Fixable:  true,
+			fix: func(ctx context.Context) error {
+				// Clamp to the latest message so new messages are not hidden from the agent
+				_, err := ref.Update(ctx, []firestore.Update{{Path: "memorizedUntil", Value: latest}})
+				return err
+			},
+		})
+	}
+	return issues
+}
+
+func checkCounters(inv *inventory) []Issue {
+	var issues []Issue
+	for id, thread := range inv.threads {
+		// Same definition as repository.Recount: every message counts
+		var actual repository.ThreadCounters
+		for _, msg := range inv.messages[id] {
+			actual.ReplyCount++
+			if msg.Role == model.RoleAssistant && msg.CreatedAt.After(thread.LastReadAt) {
+				actual.UnreadCount++
+			}
+		}
+		if actual.ReplyCount == thread.ReplyCount && actual.UnreadCount == thread.UnreadCount {
+			continue
+		}
+
+		threadID := id
+		issues = append(issues, Issue{
+			ThreadID: threadID,
+			Detail: fmt.Sprintf("replyCount %d (actual %d), unreadCount %d (actual %d)",
+				thread.ReplyCount, actual.ReplyCount, thread.UnreadCount, actual.UnreadCount),
+			Fixable: true,
+			fix: func(ctx context.Context) error {
+				// Recounted with the write, in case messages arrived since the snapshot
+				_, err := inv.counters.Repair(ctx, threadID)
+				return err
+			},
+		})
+	}
+	return issues
+}
+
+func checkUnknownRole(inv *inventory) []Issue {
+	roles := map[string]bool{}
+	if s, err := schema.Default(); err == nil {
+		if c, ok := s.Collection("threads", "messages"); ok {
+			for _, f := range c.Fields {
+				if f.Name == "role" {
+					for _, v := range f.Enum {
+						roles[v] = true
+					}
+				}
+			}
+		}
+	}
+
+	var issues []Issue
+	for threadID, msgs := range inv.messages {
+		for _, msg := range msgs {
+			if roles[msg.Role] {
+				continue
+			}
+			issues = append(issues, Issue{
+				ThreadID:  threadID,
+				MessageID: msg.ID,
+				Detail:    fmt.Sprintf("unknown role %q", msg.Role),
+			})
+		}
+	}
+	return issues
+}
+
+func checkOrphanedMessages(inv *inventory) []Issue {
+	var issues []Issue
+	for threadID, msgs := range inv.messages {
+		if _, ok := inv.threads[threadID]; ok {
+			continue
+		}
+		// Not fixable: whether to delete the messages or recreate the thread is a human decision
+		issues = append(issues, Issue{
+			ThreadID: threadID,
+			Detail:   fmt.Sprintf("%d message(s) without a thread document", len(msgs)),
+		})
+	}
+	return issues
+}
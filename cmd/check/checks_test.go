@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/option"
+)
+
+// newInventory builds an inventory without reading Firestore. The client is
+// only used to build document references, so it never connects.
+func newInventory(t *testing.T, threads []model.ChatThread, messages map[string][]model.ChatMessage) *inventory {
+	t.Helper()
+	client, err := firestore.NewClient(context.Background(), "test-project", option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	inv := &inventory{client: client, threads: make(map[string]*model.ChatThread), messages: messages}
+	for i := range threads {
+		inv.threads[threads[i].ID] = &threads[i]
+	}
+	return inv
+}
+
+func TestChecks(t *testing.T) {
+	base := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	inv := newInventory(t,
+		[]model.ChatThread{
+			// Consistent
+			{ID: "ok", ReplyCount: 2, UnreadCount: 1, LastReadAt: base, MemorizedUntil: base},
+			// Counters off, memorized past the latest message
+			{ID: "bad", ReplyCount: 5, UnreadCount: 0, LastReadAt: base, MemorizedUntil: base.Add(time.Hour)},
+		},
+		map[string][]model.ChatMessage{
+			"ok": {
+				{ID: "m1", Role: model.RoleUser, CreatedAt: base},
+				{ID: "m2", Role: model.RoleAssistant, CreatedAt: base.Add(time.Minute)},
+			},
+			"bad": {
+				{ID: "m3", Role: "robot", CreatedAt: base},
+				{ID: "m4", Role: model.RoleAssistant, CreatedAt: base.Add(time.Minute)},
+			},
+			"gone": {{ID: "m5", Role: model.RoleUser, CreatedAt: base}},
+		},
+	)
+
+	cases := []struct {
+		check   func(*inventory) []Issue
+		thread  string
+		detail  string
+		fixable bool
+	}{
+		{checkMemorizedUntil, "bad", "memorizedUntil 2026-03-02T11:00:00Z > latest message 2026-03-02T10:01:00Z", true},
+		{checkCounters, "bad", "replyCount 5 (actual 2), unreadCount 0 (actual 1)", true},
+		{checkUnknownRole, "bad", `unknown role "robot"`, false},
+		{checkOrphanedMessages, "gone", "1 message(s) without a thread document", false},
+	}
+	for _, c := range cases {
+		issues := c.check(inv)
+		if len(issues) != 1 {
+			t.Errorf("%s: issues = %+v, want one", c.detail, issues)
+			continue
+		}
+		issue := issues[0]
+		if issue.ThreadID != c.thread || issue.Detail != c.detail || issue.Fixable != c.fixable || (issue.fix != nil) != c.fixable {
+			t.Errorf("issue = %+v, want %s on %s (fixable %v)", issue, c.detail, c.thread, c.fixable)
+		}
+	}
+}
+
+func TestSelectChecks(t *testing.T) {
+	checks, err := selectChecks("counters, unknown-role")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 2 || checks[0].Name != "counters" || checks[1].Name != "unknown-role" {
+		t.Errorf("checks = %+v, want counters and unknown-role", checks)
+	}
+	if all, _ := selectChecks(""); len(all) != len(allChecks) {
+		t.Errorf("selectChecks(\"\") = %d checks, want all", len(all))
+	}
+	if _, err := selectChecks("nope"); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("selectChecks(nope) = %v, want an error", err)
+	}
+}
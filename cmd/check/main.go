@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
 	"youdoyou-server/config"
 	"youdoyou-server/repository"
@@ -13,6 +18,33 @@ import (
 )
 
 func main() {
+	// Parse flags
+	format := flag.String("format", "table", "Output format: table or json")
+	fix := flag.Bool("fix", false, "Repair issues that are safe to fix automatically")
+	only := flag.String("only", "", "Comma-separated check names to run (default: all)")
+	list := flag.Bool("list", false, "List available checks and exit")
+	unmemorized := flag.Bool("unmemorized", false, "Print the unmemorized messages of the thread instead of running checks")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: check [flags] [thread-id]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *list {
+		for _, c := range allChecks {
+			fmt.Printf("%-18s %s\n", c.Name, c.Description)
+		}
+		return
+	}
+	if *format != "table" && *format != "json" {
+		log.Fatalf("Unknown format %q (want table or json)", *format)
+	}
+
+	checks, err := selectChecks(*only)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	ctx := context.Background()
 	cfg := config.LoadConfig()
 
@@ -27,13 +59,136 @@ func main() {
 		}
 	}()
 
-	repo := repository.NewFirestoreChatRepository(client)
+	threadID := flag.Arg(0)
+
+	if *unmemorized {
+		if threadID == "" {
+			threadID = "test-thread-e2e"
+		}
+		printUnmemorized(ctx, client, threadID)
+		return
+	}
 
-	threadID := "test-thread-e2e"
-	if len(os.Args) > 1 {
-		threadID = os.Args[1]
+	inv, err := loadInventory(ctx, client, threadID)
+	if err != nil {
+		log.Fatalf("Failed to load data: %v", err)
+	}
+
+	var issues []Issue
+	for _, c := range checks {
+		found := c.Run(inv)
+		sort.Slice(found, func(i, j int) bool {
+			if found[i].ThreadID != found[j].ThreadID {
+				return found[i].ThreadID < found[j].ThreadID
+			}
+			return found[i].MessageID < found[j].MessageID
+		})
+		for i := range found {
+			found[i].Check = c.Name
+		}
+		issues = append(issues, found...)
+	}
+
+	if *fix {
+		for i := range issues {
+			if issues[i].fix == nil {
+				continue
+			}
+			if err := issues[i].fix(ctx); err != nil {
+				log.Printf("Failed to fix %s on %s: %v", issues[i].Check, issues[i].ThreadID, err)
+				continue
+			}
+			issues[i].Fixed = true
+		}
 	}
 
+	switch *format {
+	case "json":
+		writeJSON(issues)
+	default:
+		writeTable(issues, *fix)
+	}
+
+	// Exit non-zero while unresolved issues remain so the command can gate CI
+	for _, issue := range issues {
+		if !issue.Fixed {
+			os.Exit(1)
+		}
+	}
+}
+
+func selectChecks(only string) ([]Check, error) {
+	if only == "" {
+		return allChecks, nil
+	}
+	var selected []Check
+	for _, name := range strings.Split(only, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, c := range allChecks {
+			if c.Name == name {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown check %q (use --list)", name)
+		}
+	}
+	return selected, nil
+}
+
+func writeJSON(issues []Issue) {
+	if issues == nil {
+		issues = []Issue{}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(map[string]any{"issues": issues}); err != nil {
+		log.Fatalf("Failed to encode issues: %v", err)
+	}
+}
+
+func writeTable(issues []Issue, fix bool) {
+	if len(issues) == 0 {
+		fmt.Println("✅ No issues found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tTHREAD\tMESSAGE\tFIXABLE\tSTATUS\tDETAIL")
+	for _, issue := range issues {
+		status := "open"
+		if issue.Fixed {
+			status = "fixed"
+		}
+		fixable := "no"
+		if issue.Fixable {
+			fixable = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", issue.Check, issue.ThreadID, issue.MessageID, fixable, status, issue.Detail)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Failed to write table: %v", err)
+	}
+
+	fixable := 0
+	for _, issue := range issues {
+		if issue.Fixable && !issue.Fixed {
+			fixable++
+		}
+	}
+	fmt.Printf("\n⚠️  %d issue(s)", len(issues))
+	if !fix && fixable > 0 {
+		fmt.Printf(", %d fixable with --fix", fixable)
+	}
+	fmt.Println()
+}
+
+func printUnmemorized(ctx context.Context, client *firestore.Client, threadID string) {
+	repo := repository.NewFirestoreChatRepository(client)
+
 	history, err := repo.GetUnmemorizedMessages(ctx, threadID)
 	if err != nil {
 		log.Fatalf("Failed to get unmemorized messages: %v", err)
@@ -42,7 +197,6 @@ func main() {
 	fmt.Printf("--- Unmemorized Messages for %s ---\n", threadID)
 	for _, msg := range history {
 		fmt.Printf("[%s] %s ID:%s : %s\n", msg.CreatedAt.Format("15:04:05"), msg.Role, msg.ID, msg.Content)
-
 	}
 	fmt.Printf("---------------------------------------\n")
 }
@@ -96,8 +96,9 @@ func main() {
 	// Create message
 	msg := &model.ChatMessage{
 		ThreadID:  finalThreadID,
-		Role:      "user",
+		Role:      model.RoleUser,
 		Content:   *message,
+		Status:    model.MessageStatusPending,
 		CreatedAt: time.Now(),
 	}
 
@@ -118,6 +119,8 @@ func createThread(ctx context.Context, client *firestore.Client, thread *model.C
 	_, err := client.Collection("threads").Doc(thread.ID).Set(ctx, map[string]interface{}{
 		"userId":         thread.UserID,
 		"firstMessage":   thread.FirstMessage,
+		"title":          thread.Title,
+		"tags":           thread.Tags,
 		"unreadCount":    thread.UnreadCount,
 		"lastReadAt":     thread.LastReadAt,
 		"replyCount":     thread.ReplyCount,
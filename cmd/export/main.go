@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"youdoyou-server/config"
+	"youdoyou-server/export"
+	"youdoyou-server/repository"
+
+	"cloud.google.com/go/firestore"
+)
+
+func main() {
+	// Parse flags
+	threadID := flag.String("thread-id", "", "Thread ID to export (required)")
+	format := flag.String("format", "json", "Output format: json or markdown")
+	out := flag.String("out", "", "Output file (default: stdout)")
+	flag.Parse()
+
+	if *threadID == "" {
+		fmt.Println("Error: --thread-id is required")
+		fmt.Println("")
+		fmt.Println("Usage:")
+		fmt.Println("  go run ./cmd/export --thread-id basic-thread --out basic.json")
+		fmt.Println("  go run ./cmd/export --thread-id basic-thread --format markdown")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	cfg := config.LoadConfig()
+
+	// Initialize Firestore
+	client, err := firestore.NewClient(ctx, cfg.FirestoreProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Failed to close Firestore client: %v", err)
+		}
+	}()
+
+	repo := repository.NewFirestoreChatRepository(client)
+
+	doc, err := export.Export(ctx, repo, *threadID)
+	if err != nil {
+		log.Fatalf("Failed to export thread: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Printf("Failed to close output file: %v", err)
+			}
+		}()
+		w = f
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(doc)
+	case "markdown", "md":
+		_, err = io.WriteString(w, export.Markdown(doc))
+	default:
+		log.Fatalf("Unknown format: %s", *format)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write export: %v", err)
+	}
+
+	log.Printf("✅ Exported thread %s (%d messages)", *threadID, len(doc.Messages))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"youdoyou-server/config"
+	"youdoyou-server/export"
+	"youdoyou-server/repository"
+	"youdoyou-server/search"
+
+	"cloud.google.com/go/firestore"
+)
+
+func main() {
+	// Parse flags
+	threadID := flag.String("thread-id", "", "Restore under a different thread ID (optional)")
+	overwrite := flag.Bool("overwrite", false, "Overwrite the thread if it already exists")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Println("Error: export file is required")
+		fmt.Println("")
+		fmt.Println("Usage:")
+		fmt.Println("  go run ./cmd/import basic.json")
+		fmt.Println("  go run ./cmd/import --thread-id copied-thread basic.json")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to open export file: %v", err)
+	}
+	doc, err := export.Decode(f)
+	if closeErr := f.Close(); closeErr != nil {
+		log.Printf("Failed to close export file: %v", closeErr)
+	}
+	if err != nil {
+		log.Fatalf("Failed to read export: %v", err)
+	}
+
+	ctx := context.Background()
+	cfg := config.LoadConfig()
+
+	// Initialize Firestore
+	client, err := firestore.NewClient(ctx, cfg.FirestoreProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Failed to close Firestore client: %v", err)
+		}
+	}()
+
+	repo := repository.NewFirestoreChatRepository(client)
+
+	id, err := export.Import(ctx, repo, doc, export.ImportOptions{
+		ThreadID:  *threadID,
+		Overwrite: *overwrite,
+	})
+	if err != nil {
+		log.Fatalf("Failed to import thread: %v", err)
+	}
+
+	// RestoreThread dropped the thread's old search entries
+	searcher := search.NewSearcher(repository.NewFirestoreSearchRepository(client), repo)
+	if err := searcher.IndexThread(ctx, id, doc.Messages); err != nil {
+		log.Fatalf("Failed to index thread %s: %v", id, err)
+	}
+
+	log.Printf("✅ Imported thread %s (%d messages)", id, len(doc.Messages))
+}
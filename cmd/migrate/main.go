@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"youdoyou-server/config"
+	"youdoyou-server/migration"
+
+	"cloud.google.com/go/firestore"
+)
+
+func main() {
+	// Parse flags
+	dryRun := flag.Bool("dry-run", false, "Report the documents that would change without writing")
+	to := flag.Int("to", 0, "Apply migrations up to this version (0 = all)")
+	status := flag.Bool("status", false, "Show applied and pending migrations")
+	batchSize := flag.Int("batch-size", 200, "Documents per page (the resume cursor advances per page)")
+	flag.Parse()
+
+	ctx := context.Background()
+	cfg := config.LoadConfig()
+
+	// Initialize Firestore
+	client, err := firestore.NewClient(ctx, cfg.FirestoreProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Failed to close Firestore client: %v", err)
+		}
+	}()
+
+	migrations := migration.All(client)
+	runner := migration.NewRunner(client, migrations)
+	runner.DryRun = *dryRun
+	runner.BatchSize = *batchSize
+
+	if *status {
+		states, err := runner.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to load migration status: %v", err)
+		}
+		for _, m := range migrations {
+			state := states[m.Version]
+			switch {
+			case state == nil:
+				fmt.Printf("%04d_%s: pending\n", m.Version, m.Name)
+			case state.Status == migration.StatusDone:
+				fmt.Printf("%04d_%s: done at %s (%d updated)\n", m.Version, m.Name, state.FinishedAt.Format("2006-01-02 15:04:05"), state.Updated)
+			default:
+				fmt.Printf("%04d_%s: %s (step %d, cursor %q)\n", m.Version, m.Name, state.Status, state.Step, state.Cursor)
+			}
+		}
+		return
+	}
+
+	if err := runner.Run(ctx, *to); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Println("✅ Migrations complete")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"youdoyou-server/config"
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+	"youdoyou-server/search"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+func main() {
+	ctx := context.Background()
+	cfg := config.LoadConfig()
+
+	// Initialize Firestore
+	client, err := firestore.NewClient(ctx, cfg.FirestoreProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Failed to close Firestore client: %v", err)
+		}
+	}()
+
+	chatRepo := repository.NewFirestoreChatRepository(client)
+	searcher := search.NewSearcher(repository.NewFirestoreSearchRepository(client), chatRepo)
+
+	// Index a single thread if given, otherwise every message (collection group)
+	query := client.CollectionGroup("messages").Query
+	if len(os.Args) > 1 {
+		query = client.Collection("threads").Doc(os.Args[1]).Collection("messages").Query
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	indexed := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Failed to iterate messages: %v", err)
+		}
+
+		var msg model.ChatMessage
+		if err := doc.DataTo(&msg); err != nil {
+			log.Printf("Skipping message %s: %v", doc.Ref.Path, err)
+			continue
+		}
+		msg.ID = doc.Ref.ID
+		msg.ThreadID = doc.Ref.Parent.Parent.ID
+
+		if err := searcher.IndexMessage(ctx, &msg); err != nil {
+			log.Fatalf("Failed to index message %s: %v", doc.Ref.Path, err)
+		}
+		indexed++
+	}
+
+	fmt.Printf("✅ Indexed %d message(s)\n", indexed)
+}
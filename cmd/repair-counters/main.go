@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"youdoyou-server/config"
+	"youdoyou-server/repository"
+
+	"cloud.google.com/go/firestore"
+)
+
+func main() {
+	// Parse flags
+	dryRun := flag.Bool("dry-run", false, "Only report drifted threads without writing")
+	flag.Parse()
+
+	// Remaining args are thread IDs; none means every thread
+	threadIDs := flag.Args()
+
+	ctx := context.Background()
+	cfg := config.LoadConfig()
+
+	// Initialize Firestore
+	client, err := firestore.NewClient(ctx, cfg.FirestoreProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Failed to close Firestore client: %v", err)
+		}
+	}()
+
+	repo := repository.NewFirestoreCounterRepository(client)
+
+	drifts, err := repo.FindDrift(ctx, threadIDs)
+	if err != nil {
+		log.Fatalf("Failed to check counters: %v", err)
+	}
+
+	if len(drifts) == 0 {
+		fmt.Println("All thread counters are consistent")
+		return
+	}
+
+	for _, d := range drifts {
+		if !*dryRun {
+			// Recounted with the write, in case messages arrived since the check
+			repaired, err := repo.Repair(ctx, d.ThreadID)
+			if err != nil {
+				log.Fatalf("Failed to repair thread %s: %v", d.ThreadID, err)
+			}
+			d.Actual = repaired
+		}
+		fmt.Printf("[%s] replyCount %d -> %d, unreadCount %d -> %d\n",
+			d.ThreadID,
+			d.Stored.ReplyCount, d.Actual.ReplyCount,
+			d.Stored.UnreadCount, d.Actual.UnreadCount)
+	}
+
+	if *dryRun {
+		fmt.Printf("%d thread(s) drifted (dry run, nothing written)\n", len(drifts))
+	} else {
+		fmt.Printf("✅ Repaired %d thread(s)\n", len(drifts))
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"youdoyou-server/app"
+	"youdoyou-server/config"
+	"youdoyou-server/queue"
+	"youdoyou-server/repository"
+
+	"cloud.google.com/go/firestore"
+	firebase "firebase.google.com/go/v4"
+)
+
+func main() {
+	// Parse flags
+	dryRun := flag.Bool("dry-run", false, "List due failed runs without re-running them")
+	limit := flag.Int("limit", 10, "Maximum number of runs to retry")
+	force := flag.Bool("force", false, "Retry pending runs even if their backoff has not elapsed")
+	flag.Parse()
+
+	ctx := context.Background()
+	cfg := config.LoadConfig()
+
+	// Initialize Firestore
+	client, err := firestore.NewClient(ctx, cfg.FirestoreProjectID)
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			log.Printf("Failed to close Firestore client: %v", err)
+		}
+	}()
+
+	failedRunRepo := repository.NewFirestoreFailedRunRepository(client)
+
+	dueBy := time.Now()
+	if *force {
+		dueBy = dueBy.Add(100 * 365 * 24 * time.Hour)
+	}
+
+	if *dryRun {
+		runs, err := failedRunRepo.ListDueFailedRuns(ctx, dueBy, *limit)
+		if err != nil {
+			log.Fatalf("Failed to list failed runs: %v", err)
+		}
+		for _, run := range runs {
+			fmt.Printf("[%s] thread=%s message=%s attempts=%d next=%s %s: %s\n",
+				run.ID, run.ThreadID, run.MessageID, run.Attempts,
+				run.NextAttemptAt.Format("2006-01-02 15:04:05"), run.ErrorCode, run.Error)
+		}
+		fmt.Printf("%d run(s) due (dry run)\n", len(runs))
+		return
+	}
+
+	// Build the agent the same way as the server: replies of successful retries
+	// are pushed and posted back to Slack / LINE. Retries take the thread's
+	// lease like the server's jobs (the memory lock would not see the server's
+	// runs), and run one at a time before the command exits.
+	firebaseApp, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: cfg.FirestoreProjectID})
+	if err != nil {
+		log.Fatalf("Failed to initialize Firebase: %v", err)
+	}
+	agent, err := app.NewAgent(ctx, cfg, client, firebaseApp, app.Options{Concurrency: 1, LockBackend: "firestore"})
+	if err != nil {
+		log.Fatalf("❌ Failed to build the agent: %v", err)
+	}
+	defer agent.Close()
+
+	summary, err := agent.RetryService.RetryDue(ctx, queue.NewInlineQueue(agent.Worker), dueBy, *limit)
+	if err != nil {
+		log.Fatalf("❌ Retry failed: %v", err)
+	}
+	fmt.Printf("✅ Retried %d run(s), %d already answered\n", summary.Queued, summary.Resolved)
+}
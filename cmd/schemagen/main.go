@@ -0,0 +1,247 @@
+// Command schemagen generates the Firestore model structs and enum constants
+// from schema/firestore.yaml. It is run by `go generate ./model`.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"youdoyou-server/schema"
+)
+
+// initialisms are rendered in upper case, following Go naming conventions
+var initialisms = map[string]bool{"id": true, "url": true, "ai": true, "api": true, "uri": true, "http": true, "json": true}
+
+type generator struct {
+	buf     bytes.Buffer
+	structs []string
+	seen    map[string]bool
+	consts  []string
+	values  map[string]string
+}
+
+func main() {
+	schemaPath := flag.String("schema", "schema/firestore.yaml", "Path to the schema YAML")
+	out := flag.String("out", "model/firestore_gen.go", "Output Go file")
+	pkg := flag.String("package", "model", "Package name of the generated file")
+	flag.Parse()
+
+	data, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		log.Fatalf("Failed to read schema: %v", err)
+	}
+	s, err := schema.Parse(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	g := &generator{seen: make(map[string]bool), values: make(map[string]string)}
+	names := make([]string, 0, len(s.Collections))
+	for name := range s.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := g.collection(name, s.Collections[name]); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	src, err := g.render(*pkg)
+	if err != nil {
+		log.Fatalf("Failed to format generated code: %v", err)
+	}
+	// #nosec G306 -- generated source is world-readable like the rest of the tree
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+}
+
+func (g *generator) collection(path string, c schema.Collection) error {
+	if c.GoType == "" {
+		return fmt.Errorf("collection %s: goType is required", path)
+	}
+	doc := fmt.Sprintf("%s is a document in %s. %s", c.GoType, path, c.Description)
+	if err := g.structType(c.GoType, doc, c.Fields, true); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(c.Subcollections))
+	for name := range c.Subcollections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := g.collection(path+"/{id}/"+name, c.Subcollections[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) structType(name string, doc string, fields []schema.Field, isDocument bool) error {
+	if g.seen[name] {
+		return nil
+	}
+	g.seen[name] = true
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s\n", strings.TrimSpace(doc))
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	if isDocument {
+		b.WriteString("ID string `json:\"id,omitempty\" firestore:\"-\"`\n")
+	}
+
+	var nested []func() error
+	for _, f := range fields {
+		goType, err := g.fieldType(name, f, &nested)
+		if err != nil {
+			return err
+		}
+		if f.Description != "" {
+			fmt.Fprintf(&b, "// %s\n", f.Description)
+		}
+		tag := f.Name
+		if f.Omitempty {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "%s %s `json:%q firestore:%q`\n", goName(f), goType, tag, tag)
+
+		if len(f.Enum) > 0 {
+			g.enum(name, f)
+		}
+	}
+	b.WriteString("}\n")
+	g.structs = append(g.structs, b.String())
+
+	for _, fn := range nested {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) fieldType(parent string, f schema.Field, nested *[]func() error) (string, error) {
+	if f.GoKind != "" && f.Type != "map" && f.Type != "array" {
+		return f.GoKind, nil
+	}
+
+	switch f.Type {
+	case "string":
+		return "string", nil
+	case "boolean":
+		return "bool", nil
+	case "number":
+		return "float64", nil
+	case "timestamp":
+		return "time.Time", nil
+	case "map":
+		if f.GoType == "" {
+			if f.GoKind != "" {
+				return f.GoKind, nil
+			}
+			return "map[string]interface{}", nil
+		}
+		*nested = append(*nested, func() error {
+			return g.structType(f.GoType, fmt.Sprintf("%s is the %s field of %s.", f.GoType, f.Name, parent), f.Fields, false)
+		})
+		if f.Pointer {
+			return "*" + f.GoType, nil
+		}
+		return f.GoType, nil
+	case "array":
+		if f.Items == nil {
+			return "[]interface{}", nil
+		}
+		item := schema.Field{Name: f.Name, Type: f.Items.Type, GoType: f.Items.GoType, Fields: f.Items.Fields}
+		elem, err := g.fieldType(parent, item, nested)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	default:
+		return "", fmt.Errorf("%s.%s: unsupported type %q", parent, f.Name, f.Type)
+	}
+}
+
+func (g *generator) enum(parent string, f schema.Field) {
+	prefix := f.EnumPrefix
+	if prefix == "" {
+		prefix = parent + goName(f)
+	}
+	for _, v := range f.Enum {
+		name := prefix + exported(v)
+		if _, ok := g.values[name]; ok {
+			continue
+		}
+		g.values[name] = v
+		g.consts = append(g.consts, name)
+	}
+}
+
+func (g *generator) render(pkg string) ([]byte, error) {
+	g.buf.WriteString("// Code generated by cmd/schemagen from schema/firestore.yaml; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&g.buf, "package %s\n\n", pkg)
+	g.buf.WriteString("import \"time\"\n\n")
+
+	if len(g.consts) > 0 {
+		g.buf.WriteString("// Enum values declared in the schema\nconst (\n")
+		for _, name := range g.consts {
+			fmt.Fprintf(&g.buf, "%s = %q\n", name, g.values[name])
+		}
+		g.buf.WriteString(")\n\n")
+	}
+
+	for _, s := range g.structs {
+		g.buf.WriteString(s)
+		g.buf.WriteString("\n")
+	}
+
+	return format.Source(g.buf.Bytes())
+}
+
+func goName(f schema.Field) string {
+	if f.GoName != "" {
+		return f.GoName
+	}
+	return exported(f.Name)
+}
+
+// exported converts a camelCase schema name to an exported Go identifier,
+// e.g. "aiMetadata" -> "AIMetadata", "responseId" -> "ResponseID"
+func exported(name string) string {
+	var words []string
+	start := 0
+	runes := []rune(name)
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) || runes[i] == '_' || runes[i] == '-' {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+
+	var b strings.Builder
+	for _, w := range words {
+		w = strings.Trim(w, "_-")
+		if w == "" {
+			continue
+		}
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"testing"
+
+	"youdoyou-server/schema"
+)
+
+// TestGeneratedModelsAreCurrent fails when firestore.yaml changed without
+// re-running `go generate ./model`
+func TestGeneratedModelsAreCurrent(t *testing.T) {
+	data, err := os.ReadFile("../../schema/firestore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := schema.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &generator{seen: make(map[string]bool), values: make(map[string]string)}
+	names := make([]string, 0, len(s.Collections))
+	for name := range s.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := g.collection(name, s.Collections[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src, err := g.render("model")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := os.ReadFile("../../model/firestore_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, current) {
+		t.Error("model/firestore_gen.go is out of date; run go generate ./model")
+	}
+}
@@ -2,22 +2,32 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"youdoyou-server/cmd/seed/seeds"
 	"youdoyou-server/config"
+	"youdoyou-server/model"
 	"youdoyou-server/repository"
+	"youdoyou-server/schema"
+	"youdoyou-server/search"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
 )
 
 func main() {
-	ctx := context.Background()
-	cfg := config.LoadConfig()
+	// Parse flags
+	dir := flag.String("dir", "cmd/seed/data", "Directory of YAML/JSON seed files (empty to skip)")
+	only := flag.String("only", "", "Comma-separated seed names to run (default: all)")
+	dryRun := flag.Bool("dry-run", false, "Validate seeds and print what would be written")
+	clean := flag.Bool("clean", false, "Delete every thread before seeding (emulator only)")
+	flag.Parse()
 
 	// Set JST as default timezone
 	jst, err := time.LoadLocation("Asia/Tokyo")
@@ -27,6 +37,67 @@ func main() {
 	}
 	time.Local = jst
 
+	// Collect seeds: compiled Go seeds first, then files (files win on name clash)
+	s, err := schema.Load()
+	if err != nil {
+		log.Fatalf("Failed to load schema: %v", err)
+	}
+
+	available := make(map[string]seeds.SeedData)
+	for name, data := range seeds.Registry {
+		if err := seeds.Validate(data, s); err != nil {
+			log.Fatalf("Seed '%s' is invalid:\n%v", name, err)
+		}
+		available[name] = data
+	}
+	if *dir != "" {
+		fileSeeds, err := seeds.LoadDir(*dir, s, time.Now())
+		if err != nil {
+			log.Fatalf("Failed to load seed files: %v", err)
+		}
+		for name, data := range fileSeeds {
+			available[name] = data
+		}
+	}
+
+	// Determine which seed names to use (--only, or the legacy positional name)
+	var names []string
+	switch {
+	case *only != "":
+		names = strings.Split(*only, ",")
+	case flag.NArg() > 0 && flag.Arg(0) != "all":
+		names = []string{flag.Arg(0)}
+	default:
+		for name := range available {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var seedsToRun []seeds.SeedData
+	for _, name := range names {
+		data, ok := available[strings.TrimSpace(name)]
+		if !ok {
+			log.Fatalf("Seed '%s' not found", name)
+		}
+		seedsToRun = append(seedsToRun, data)
+	}
+
+	if *dryRun {
+		for i, seed := range seedsToRun {
+			fmt.Printf("[%s] thread %s with %d message(s)\n", names[i], seed.Thread.ID, len(seed.Messages))
+		}
+		fmt.Println("Dry run: nothing written")
+		return
+	}
+
+	if *clean && os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		log.Fatalf("--clean is only allowed against the emulator (FIRESTORE_EMULATOR_HOST is not set)")
+	}
+
+	ctx := context.Background()
+	cfg := config.LoadConfig()
+
 	// Initialize Firestore
 	client, err := firestore.NewClient(ctx, cfg.FirestoreProjectID)
 	if err != nil {
@@ -39,24 +110,13 @@ func main() {
 	}()
 
 	repo := repository.NewFirestoreChatRepository(client)
+	searcher := search.NewSearcher(repository.NewFirestoreSearchRepository(client), repo)
 
-	// Determine which seed names to use
-	seedName := "all"
-	if len(os.Args) > 1 {
-		seedName = os.Args[1]
-	}
-
-	var seedsToRun []seeds.SeedData
-	if seedName == "all" {
-		for _, s := range seeds.Registry {
-			seedsToRun = append(seedsToRun, s)
-		}
-	} else {
-		s, ok := seeds.Registry[seedName]
-		if !ok {
-			log.Fatalf("Seed '%s' not found in registry", seedName)
+	if *clean {
+		if err := deleteAllThreads(ctx, client); err != nil {
+			log.Fatalf("Failed to clean threads: %v", err)
 		}
-		seedsToRun = []seeds.SeedData{s}
+		fmt.Println("Deleted all existing threads")
 	}
 
 	for _, seed := range seedsToRun {
@@ -66,7 +126,7 @@ func main() {
 		}
 
 		// --- Idempotency: Delete existing data ---
-		if err := deleteThreadAndMessages(ctx, client, threadID); err != nil {
+		if err := deleteThreadAndMessages(ctx, client, client.Collection("threads").Doc(threadID)); err != nil {
 			log.Fatalf("Failed to clear existing data for thread %s: %v", threadID, err)
 		}
 
@@ -76,39 +136,40 @@ func main() {
 		thread.LastReadAt = ensureTime(thread.LastReadAt)
 		thread.MemorizedUntil = ensureTime(thread.MemorizedUntil)
 
-		_, err = client.Collection("threads").Doc(threadID).Set(ctx, map[string]interface{}{
-			"userId":         thread.UserID,
-			"firstMessage":   thread.FirstMessage,
-			"unreadCount":    thread.UnreadCount,
-			"lastReadAt":     thread.LastReadAt,
-			"replyCount":     thread.ReplyCount,
-			"isPrivate":      thread.IsPrivate,
-			"isArchived":     thread.IsArchived,
-			"sessionMemory":  thread.SessionMemory,
-			"memorizedUntil": thread.MemorizedUntil,
-			"createdAt":      thread.CreatedAt,
-		})
-		if err != nil {
-			log.Fatalf("Failed to create thread: %v", err)
-		}
-
-		for _, msg := range seed.Messages {
-			msg.ThreadID = threadID
+		messages := make([]model.ChatMessage, len(seed.Messages))
+		for i, msg := range seed.Messages {
 			msg.CreatedAt = ensureTime(msg.CreatedAt)
+			messages[i] = msg
+		}
 
-			msgID, err := repo.SaveMessage(ctx, &msg)
-			if err != nil {
-				log.Fatalf("Failed to save message: %v", err)
-			}
-			fmt.Printf("[%s] Saved message: %s\n", threadID, msgID)
+		// RestoreThread writes in bulk and keeps the seed's counters as-is
+		if err := repo.RestoreThread(ctx, &thread, messages); err != nil {
+			log.Fatalf("Failed to seed thread %s: %v", threadID, err)
+		}
+		if err := searcher.IndexThread(ctx, threadID, messages); err != nil {
+			log.Fatalf("Failed to index thread %s: %v", threadID, err)
 		}
 
-		fmt.Printf("Successfully seeded data for thread: %s\n", threadID)
+		fmt.Printf("Successfully seeded data for thread: %s (%d messages)\n", threadID, len(messages))
+	}
+}
+
+func deleteAllThreads(ctx context.Context, client *firestore.Client) error {
+	refs, err := client.Collection("threads").DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return err
 	}
+	for _, ref := range refs {
+		if err := deleteThreadAndMessages(ctx, client, ref); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func deleteThreadAndMessages(ctx context.Context, client *firestore.Client, threadID string) error {
-	threadRef := client.Collection("threads").Doc(threadID)
+func deleteThreadAndMessages(ctx context.Context, client *firestore.Client, threadRef *firestore.DocumentRef) error {
+	bw := client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
 
 	// Delete all messages in the sub-collection
 	msgIter := threadRef.Collection("messages").DocumentRefs(ctx)
@@ -118,16 +179,32 @@ func deleteThreadAndMessages(ctx context.Context, client *firestore.Client, thre
 			break
 		}
 		if err != nil {
+			bw.End()
 			return err
 		}
-		if _, err := docRef.Delete(ctx); err != nil {
+		job, err := bw.Delete(docRef)
+		if err != nil {
+			bw.End()
 			return err
 		}
+		jobs = append(jobs, job)
 	}
 
 	// Delete the thread itself
-	_, err := threadRef.Delete(ctx)
-	return err
+	job, err := bw.Delete(threadRef)
+	if err != nil {
+		bw.End()
+		return err
+	}
+	jobs = append(jobs, job)
+
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func ensureTime(t time.Time) time.Time {
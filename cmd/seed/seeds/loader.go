@@ -0,0 +1,205 @@
+package seeds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"youdoyou-server/schema"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keys allowed next to the schema fields: document IDs, and the export header
+// written by cmd/export so exports can be used as seeds directly.
+var (
+	envelopeKeys = map[string]bool{"thread": true, "messages": true, "version": true, "exportedAt": true}
+	idKeys       = map[string]bool{"id": true}
+)
+
+var relativeTime = regexp.MustCompile(`^([+-])(\d+)([smhdw])$`)
+
+// LoadDir reads every .yaml, .yml and .json file in dir.
+// The seed name is the file name without its extension.
+func LoadDir(dir string, s *schema.Schema, now time.Time) (map[string]SeedData, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed directory: %w", err)
+	}
+
+	result := make(map[string]SeedData)
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+
+		data, err := LoadFile(filepath.Join(dir, e.Name()), s, now)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		result[strings.TrimSuffix(e.Name(), ext)] = data
+	}
+	return result, nil
+}
+
+// LoadFile parses a seed file, resolves relative timestamps and validates it
+func LoadFile(path string, s *schema.Schema, now time.Time) (SeedData, error) {
+	raw, err := os.ReadFile(path) // #nosec G304 -- path comes from the operator
+	if err != nil {
+		return SeedData{}, err
+	}
+
+	// YAML is a superset of JSON, so one decoder handles both
+	var doc map[string]any
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return SeedData{}, fmt.Errorf("failed to parse: %w", err)
+	}
+
+	return decode(doc, s, now)
+}
+
+// Validate checks compiled Go seeds with the same rules as seed files
+func Validate(data SeedData, s *schema.Schema) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	_, err = decode(doc, s, time.Now())
+	return err
+}
+
+func decode(doc map[string]any, s *schema.Schema, now time.Time) (SeedData, error) {
+	threads, ok := s.Collection("threads")
+	if !ok {
+		return SeedData{}, fmt.Errorf("schema has no threads collection")
+	}
+	messages, ok := s.Collection("threads", "messages")
+	if !ok {
+		return SeedData{}, fmt.Errorf("schema has no threads/messages collection")
+	}
+
+	var errs []error
+	for key := range doc {
+		if !envelopeKeys[key] {
+			errs = append(errs, fmt.Errorf("%s: unknown top-level key", key))
+		}
+	}
+
+	thread, ok := doc["thread"].(map[string]any)
+	if !ok {
+		return SeedData{}, fmt.Errorf("thread: required map is missing")
+	}
+	if id, _ := thread["id"].(string); id == "" {
+		errs = append(errs, fmt.Errorf("thread.id: required"))
+	}
+	errs = append(errs, validateDoc(threads, thread, "thread.", now)...)
+
+	msgs, _ := doc["messages"].([]any)
+	for i, m := range msgs {
+		msg, ok := m.(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("messages[%d]: expected map", i))
+			continue
+		}
+		// threadId is assigned by the seeder
+		delete(msg, "threadId")
+		errs = append(errs, validateDoc(messages, msg, fmt.Sprintf("messages[%d].", i), now)...)
+	}
+
+	if len(errs) > 0 {
+		return SeedData{}, errors.Join(errs...)
+	}
+
+	// Round-trip through JSON so the model's json tags define the mapping
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return SeedData{}, err
+	}
+	var data SeedData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return SeedData{}, fmt.Errorf("failed to decode seed: %w", err)
+	}
+	return data, nil
+}
+
+func validateDoc(c *schema.Collection, doc map[string]any, prefix string, now time.Time) []error {
+	var errs []error
+
+	fields := make(map[string]any, len(doc))
+	for key, value := range doc {
+		if idKeys[key] {
+			continue
+		}
+		fields[key] = value
+	}
+
+	for _, f := range c.Fields {
+		if f.Type != "timestamp" {
+			continue
+		}
+		v, ok := fields[f.Name]
+		if !ok {
+			continue
+		}
+		t, err := resolveTime(v, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s%s: %w", prefix, f.Name, err))
+			delete(fields, f.Name)
+			continue
+		}
+		formatted := t.Format(time.RFC3339Nano)
+		doc[f.Name] = formatted
+		fields[f.Name] = formatted
+	}
+
+	for _, err := range c.Validate(fields) {
+		errs = append(errs, fmt.Errorf("%s%w", prefix, err))
+	}
+	return errs
+}
+
+// resolveTime accepts RFC3339 timestamps and offsets relative to now such as
+// "-2h", "-30m", "-3d" or "+1w"
+func resolveTime(v any, now time.Time) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		if m := relativeTime.FindStringSubmatch(t); m != nil {
+			n, err := strconv.Atoi(m[2])
+			if err != nil {
+				return time.Time{}, err
+			}
+			unit := map[string]time.Duration{
+				"s": time.Second,
+				"m": time.Minute,
+				"h": time.Hour,
+				"d": 24 * time.Hour,
+				"w": 7 * 24 * time.Hour,
+			}[m[3]]
+			d := time.Duration(n) * unit
+			if m[1] == "-" {
+				d = -d
+			}
+			return now.Add(d), nil
+		}
+		parsed, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("expected RFC3339 or relative time like \"-2h\", got %q", t)
+		}
+		return parsed, nil
+	default:
+		return time.Time{}, fmt.Errorf("expected timestamp, got %T", v)
+	}
+}
@@ -0,0 +1,85 @@
+package seeds
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/schema"
+)
+
+func loadSchema(t *testing.T) *schema.Schema {
+	t.Helper()
+	s, err := schema.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func writeSeed(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "seed.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDirResolvesRelativeTimes(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	data, err := LoadDir("../data", loadSchema(t), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recent, ok := data["recent"]
+	if !ok {
+		t.Fatalf("seeds = %v, want recent", data)
+	}
+	if !recent.Thread.CreatedAt.Equal(now.Add(-2 * time.Hour)) {
+		t.Errorf("thread createdAt = %s, want 2h before now", recent.Thread.CreatedAt)
+	}
+	if len(recent.Messages) != 3 || !recent.Messages[1].CreatedAt.Equal(now.Add(-119*time.Minute)) {
+		t.Errorf("messages = %+v, want 3 with relative times", recent.Messages)
+	}
+	if recent.Messages[1].Role != model.RoleAssistant {
+		t.Errorf("role = %q, want assistant", recent.Messages[1].Role)
+	}
+}
+
+func TestLoadFileReportsEveryProblem(t *testing.T) {
+	path := writeSeed(t, `
+thread:
+  userId: u1
+  createdAt: yesterday
+  colour: blue
+messages:
+  - role: robot
+    content: hi
+    createdAt: "-1h"
+extra: true
+`)
+
+	_, err := LoadFile(path, loadSchema(t), time.Now())
+	if err == nil {
+		t.Fatal("LoadFile succeeded")
+	}
+	for _, want := range []string{"extra: unknown top-level key", "thread.id: required", "thread.createdAt", "thread.colour", "messages[0].role"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error is missing %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestRegisteredSeedsAreValid(t *testing.T) {
+	s := loadSchema(t)
+	for name, data := range Registry {
+		if err := Validate(data, s); err != nil {
+			t.Errorf("seed %s: %v", name, err)
+		}
+	}
+}
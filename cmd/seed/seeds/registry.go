@@ -6,10 +6,8 @@ import (
 	"youdoyou-server/model"
 )
 
-type SeedData struct {
-	Thread   model.ChatThread
-	Messages []model.ChatMessage
-}
+// SeedData is the thread layout shared with exports (export.Document)
+type SeedData = model.ThreadData
 
 var Registry = make(map[string]SeedData)
 
@@ -7,19 +7,22 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
-	"github.com/firebase/genkit/go/genkit"
-	"github.com/firebase/genkit/go/plugins/googlegenai"
+	firebase "firebase.google.com/go/v4"
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
-	"github.com/jomei/notionapi"
 
+	"youdoyou-server/app"
 	"youdoyou-server/config"
 	"youdoyou-server/handler"
+	"youdoyou-server/middleware"
+	"youdoyou-server/queue"
 	"youdoyou-server/repository"
 	"youdoyou-server/service"
-	"youdoyou-server/tool"
 )
 
+// localQueueBuffer is how many jobs may wait for a worker slot with QUEUE_BACKEND=local
+const localQueueBuffer = 100
+
 func main() {
 	ctx := context.Background()
 	cfg := config.LoadConfig()
@@ -32,29 +35,96 @@ func main() {
 	}
 	defer firestoreClient.Close()
 
-	// Notion
-	notionClient := notionapi.NewClient(notionapi.Token(cfg.NotionToken))
-
-	// Genkit
-	googleAI := &googlegenai.GoogleAI{
-		APIKey: cfg.GoogleGenaiApiKey,
+	// Firebase (Auth)
+	firebaseApp, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: cfg.FirestoreProjectID})
+	if err != nil {
+		log.Fatal(err)
+	}
+	authMiddleware, err := middleware.NewAuthMiddleware(firebaseApp)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	g := genkit.Init(ctx,
-		genkit.WithPlugins(googleAI),
-		genkit.WithDefaultModel("googleai/gemini-3-flash-preview"),
-	)
+	// System hooks: OIDC tokens of Cloud Tasks / Scheduler / Eventarc service accounts
+	hookAuth := func(next http.Handler) http.Handler { return next }
+	switch {
+	case cfg.HookAudience != "":
+		accounts := cfg.HookServiceAccounts
+		if cfg.TasksServiceAcct != "" {
+			accounts = append(accounts, cfg.TasksServiceAcct)
+		}
+		serviceAuth, err := middleware.NewServiceAuthMiddleware(ctx, cfg.HookAudience, accounts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		hookAuth = serviceAuth.Handler
+	case cfg.QueueBackend == "local":
+		log.Println("⚠️ HOOK_AUDIENCE is not set; system hooks accept unauthenticated requests")
+	default:
+		log.Fatal("HOOK_AUDIENCE is required unless QUEUE_BACKEND=local")
+	}
 
 	// --- 2. Dependency Injection (DI) ---
 
-	chatRepo := repository.NewFirestoreChatRepository(firestoreClient)
-	notionRepo := repository.NewNotionRepository(notionClient)
-
-	toolFactory := tool.NewToolFactory(g, chatRepo, nil, notionRepo)
-	tools := toolFactory.CreateAllTools()
+	// The agent, its repositories and the job worker (shared with cmd/retry)
+	agent, err := app.NewAgent(ctx, cfg, firestoreClient, firebaseApp, app.Options{Concurrency: cfg.WorkerConcurrency})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer agent.Close()
+	chatRepo := agent.ChatRepo
+
+	// Agent jobs: handlers enqueue, the worker runs them with a deadline and limited concurrency,
+	// and one run per thread at a time
+	worker := agent.Worker
+	var jobQueue queue.Queue
+	switch cfg.QueueBackend {
+	case "cloudtasks":
+		jobQueue, err = queue.NewCloudTasksQueue(ctx, queue.CloudTasksConfig{
+			ProjectID:      cfg.FirestoreProjectID,
+			Location:       cfg.CloudTasksLocation,
+			Queue:          cfg.CloudTasksQueue,
+			WorkerURL:      cfg.WorkerURL,
+			ServiceAccount: cfg.TasksServiceAcct,
+			Audience:       cfg.HookAudience,
+			Timeout:        cfg.JobTimeout,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "local":
+		jobQueue = queue.NewLocalQueue(worker, localQueueBuffer)
+	default:
+		log.Fatalf("Unknown QUEUE_BACKEND %q (want local or cloudtasks)", cfg.QueueBackend)
+	}
 
-	agentService := service.NewAgentService(chatRepo, nil, notionRepo, g, tools)
-	agentHandler := handler.NewAgentHandler(agentService)
+	agentHandler := handler.NewAgentHandler(chatRepo, jobQueue, agent.Searcher)
+	workerHandler := handler.NewWorkerHandler(worker)
+	retryHandler := handler.NewRetryHandler(agent.RetryService, jobQueue)
+	reminderHandler := handler.NewReminderHandler(service.NewReminderService(agent.TaskRepo, chatRepo))
+	scheduleHandler := handler.NewScheduleHandler(service.NewScheduleService(agent.ScheduleRepo, chatRepo, jobQueue, cfg.ScheduleMisfireGrace))
+	threadHandler := handler.NewThreadHandler(chatRepo)
+	searchHandler := handler.NewSearchHandler(agent.Searcher)
+	messageHandler := handler.NewMessageHandler(chatRepo, agent.AgentService, jobQueue)
+	notificationHandler := handler.NewNotificationHandler(agent.NotificationRepo)
+	channelRepo := repository.NewFirestoreChannelRepository(firestoreClient)
+	channelService := service.NewChannelService(channelRepo, chatRepo, jobQueue)
+	channelHandler := handler.NewChannelHandler(channelService, agent.SlackAdapter, agent.LineAdapter)
+	// Forwarded emails start threads; their attachments go to Cloud Storage
+	var emailService *service.EmailService
+	if cfg.InboundEmailToken != "" && cfg.AttachmentBucket != "" {
+		storageClient, err := firebaseApp.Storage(ctx)
+		if err != nil {
+			log.Fatalf("Failed to create storage client: %v", err)
+		}
+		bucket, err := storageClient.Bucket(cfg.AttachmentBucket)
+		if err != nil {
+			log.Fatalf("Failed to open bucket %s: %v", cfg.AttachmentBucket, err)
+		}
+		emailService = service.NewEmailService(channelRepo, chatRepo, agent.ToolPolicyRepo, repository.NewCloudStorageAttachmentStore(bucket), jobQueue)
+	}
+	emailHandler := handler.NewEmailHandler(emailService, cfg.InboundEmailToken, cfg.InboundEmailAuthservID)
+	mcpHandler := handler.NewMCPHandler(agent.ToolFactory, agent.ToolPolicies, cfg.ToolTimeout)
 
 	// --- 3. HTTP Routing with chi ---
 
@@ -66,13 +136,46 @@ func main() {
 	r.Use(chimiddleware.RealIP)    // プロキシ配下でもクライアントIPを正しく取得
 
 	// B. ルーティングの構築
+	// MCP クライアント用 (Firebase ID トークン必須)
+	r.With(authMiddleware.Handler).Handle("/mcp", mcpHandler)
+
 	r.Route("/v1", func(r chi.Router) {
 
-		// デバッグ/手動実行用 (人間が叩く)
-		r.Post("/agent/chat", agentHandler.HandleAgentChat)
+		// クライアント用 (Firebase ID トークン必須)
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.Handler)
+			r.Get("/threads", threadHandler.HandleListThreads)
+			r.Get("/threads/{threadID}/export", threadHandler.HandleExportThread)
+			r.Put("/threads/{threadID}/branch", messageHandler.HandleSwitchBranch)
+			r.Post("/threads/{threadID}/cancel", messageHandler.HandleCancel)
+			r.Post("/threads/{threadID}/messages/{messageID}/edit", messageHandler.HandleEdit)
+			r.Post("/threads/{threadID}/messages/{messageID}/regenerate", messageHandler.HandleRegenerate)
+			r.Get("/search", searchHandler.HandleSearch)
+			r.Post("/devices", notificationHandler.HandleRegisterDevice)
+			r.Delete("/devices/{token}", notificationHandler.HandleUnregisterDevice)
+			r.Get("/notifications/settings", notificationHandler.HandleGetSettings)
+			r.Put("/notifications/settings", notificationHandler.HandlePutSettings)
+		})
+
+		// システム連携用 (Eventarc / Scheduler / Cloud Tasks の OIDC トークン必須)
+		r.Group(func(r chi.Router) {
+			r.Use(hookAuth)
+			r.Post("/hooks/firestore", agentHandler.HandleFirestoreTrigger)
+			r.Post("/hooks/retry", retryHandler.HandleRetry)
+			r.Post("/hooks/reminders", reminderHandler.HandleReminders)
+			r.Post("/hooks/schedules", scheduleHandler.HandleSchedules)
+			r.Post("/hooks/worker", workerHandler.HandleWorker)
+
+			// 手動実行用 (gcloud auth print-identity-token で取得したトークンで叩く)
+			r.Post("/agent/chat", agentHandler.HandleAgentChat)
+		})
+
+		// チャット連携用 (各プラットフォームの署名で検証)
+		r.Post("/channels/slack/events", channelHandler.HandleSlack)
+		r.Post("/channels/line/webhook", channelHandler.HandleLine)
 
-		// システム連携用 (Eventarc / Scheduler)
-		r.Post("/hooks/firestore", agentHandler.HandleFirestoreTrigger)
+		// メール受信用 (URL のトークンで検証)
+		r.Post("/email/inbound", emailHandler.HandleInbound)
 
 		// ヘルスチェック
 		r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -14,6 +15,61 @@ type Config struct {
 	FirestoreProjectID string `envconfig:"FIRESTORE_PROJECT_ID" default:"youdoyou-intelligence"`
 	NotionToken        string `envconfig:"NOTION_TOKEN" required:"true"`
 	GoogleGenaiApiKey  string `envconfig:"GOOGLE_GENAI_API_KEY" required:"true"`
+
+	// Failed agent runs (failedRuns collection)
+	RetryMaxAttempts int           `envconfig:"RETRY_MAX_ATTEMPTS" default:"5"`
+	RetryBaseDelay   time.Duration `envconfig:"RETRY_BASE_DELAY" default:"1m"`
+
+	// Agent job queue: "local" (in-process goroutines) or "cloudtasks"
+	QueueBackend       string        `envconfig:"QUEUE_BACKEND" default:"local"`
+	JobTimeout         time.Duration `envconfig:"JOB_TIMEOUT" default:"5m"`
+	WorkerConcurrency  int           `envconfig:"WORKER_CONCURRENCY" default:"4"`
+	CloudTasksLocation string        `envconfig:"CLOUD_TASKS_LOCATION" default:"asia-northeast2"`
+	CloudTasksQueue    string        `envconfig:"CLOUD_TASKS_QUEUE" default:"agent-jobs"`
+	WorkerURL          string        `envconfig:"WORKER_URL"` // e.g. https://<service>/v1/hooks/worker
+	TasksServiceAcct   string        `envconfig:"TASKS_SERVICE_ACCOUNT"`
+
+	// OIDC tokens required on /v1/hooks/* and /v1/agent/chat (Cloud Tasks, Scheduler, Eventarc).
+	// Without an audience the hooks are open, which is only allowed with QUEUE_BACKEND=local.
+	HookAudience        string   `envconfig:"HOOK_AUDIENCE"` // e.g. https://<service>
+	HookServiceAccounts []string `envconfig:"HOOK_SERVICE_ACCOUNTS"`
+
+	// Tool calls within one agent turn
+	ToolConcurrency int           `envconfig:"TOOL_CONCURRENCY" default:"4"`
+	ToolTimeout     time.Duration `envconfig:"TOOL_TIMEOUT" default:"30s"`
+
+	// Tool permissions; toolPolicies documents can only narrow these
+	ToolGroups              []string `envconfig:"TOOL_GROUPS" default:"calendar,notion,search,tasks,schedules,mcp"`
+	ToolReadOnlyUsers       []string `envconfig:"TOOL_READ_ONLY_USERS"`
+	ToolPrivateThreadWrites bool     `envconfig:"TOOL_PRIVATE_THREAD_WRITES" default:"false"`
+
+	// External MCP servers whose tools are offered to the agent (JSON file, see tool.MCPConfig)
+	MCPConfigPath string `envconfig:"MCP_CONFIG"`
+
+	// Tool routing: "model" (keywords, then a cheap model call), "rules" (keywords only) or "off"
+	ToolRouting string `envconfig:"TOOL_ROUTING" default:"model"`
+
+	// Scheduled runs (schedules collection): how late a run may start and still count as on time
+	ScheduleMisfireGrace time.Duration `envconfig:"SCHEDULE_MISFIRE_GRACE" default:"5m"`
+
+	// Push notifications for assistant replies: "fcm" or "off"
+	PushBackend string `envconfig:"PUSH_BACKEND" default:"fcm"`
+
+	// Chat channels; each is enabled when its secrets are set
+	SlackSigningSecret     string `envconfig:"SLACK_SIGNING_SECRET"`
+	SlackBotToken          string `envconfig:"SLACK_BOT_TOKEN"`
+	LineChannelSecret      string `envconfig:"LINE_CHANNEL_SECRET"`
+	LineChannelAccessToken string `envconfig:"LINE_CHANNEL_ACCESS_TOKEN"`
+
+	// Inbound email; enabled when both are set
+	InboundEmailToken string `envconfig:"INBOUND_EMAIL_TOKEN"`
+	AttachmentBucket  string `envconfig:"ATTACHMENT_BUCKET"`
+	// Server whose Authentication-Results header is trusted for raw MIME
+	// deliveries (SendGrid's SPF / dkim fields are read without it)
+	InboundEmailAuthservID string `envconfig:"INBOUND_EMAIL_AUTHSERV_ID"`
+
+	// Per-thread run lock: "firestore" (lease document) or "memory" (single instance only)
+	ThreadLockBackend string `envconfig:"THREAD_LOCK_BACKEND" default:"firestore"`
 }
 
 var (
@@ -0,0 +1,125 @@
+package email
+
+import (
+	"encoding/json"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// Auth is the receiving mail server's SPF / DKIM verdict on an email. The
+// From header can be forged; only a passing check of its domain vouches for it.
+type Auth struct {
+	// SPF is the SPF result ("pass", "fail", "softfail", ...), lowercase
+	SPF string
+	// SPFDomain is the envelope sender domain SPF was checked for
+	SPFDomain string
+	// DKIMDomains are the signing domains of the passing DKIM signatures
+	DKIMDomains []string
+}
+
+// Verifies reports whether the verdict vouches for from: a passing DKIM
+// signature or a passing SPF check of from's domain (or its parent domain),
+// like DMARC's relaxed alignment
+func (a *Auth) Verifies(from *mail.Address) bool {
+	if a == nil || from == nil {
+		return false
+	}
+	_, domain, ok := strings.Cut(strings.ToLower(from.Address), "@")
+	if !ok {
+		return false
+	}
+	if a.SPF == "pass" && aligned(domain, a.SPFDomain) {
+		return true
+	}
+	for _, d := range a.DKIMDomains {
+		if aligned(domain, d) {
+			return true
+		}
+	}
+	return false
+}
+
+func aligned(fromDomain, authDomain string) bool {
+	authDomain = strings.ToLower(strings.TrimSuffix(authDomain, "."))
+	return authDomain != "" && (fromDomain == authDomain || strings.HasSuffix(fromDomain, "."+authDomain))
+}
+
+// SendGridAuth reads the SPF, dkim and envelope fields of SendGrid Inbound
+// Parse, e.g. "pass", "{@example.com : pass, @other.com : fail}" and
+// {"from":"bounce@example.com","to":[...]}
+func SendGridAuth(spf, dkim, envelope string) *Auth {
+	a := &Auth{SPF: strings.ToLower(strings.TrimSpace(spf))}
+	var env struct {
+		From string `json:"from"`
+	}
+	if json.Unmarshal([]byte(envelope), &env) == nil {
+		a.SPFDomain = domainOf(env.From)
+	}
+	for _, entry := range strings.Split(strings.Trim(strings.TrimSpace(dkim), "{}"), ",") {
+		domain, result, ok := strings.Cut(entry, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(result), "pass") {
+			a.DKIMDomains = append(a.DKIMDomains, strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		}
+	}
+	return a
+}
+
+// headerComment matches a (comment) in a header value
+var headerComment = regexp.MustCompile(`\([^()]*\)`)
+
+// AuthenticationResults reads the first Authentication-Results header
+// (RFC 8601) added by the server authservID. Receiving servers prepend theirs,
+// so a forged header lower in the message is never read before the real one.
+// It returns nil when the server added none.
+func (e *Email) AuthenticationResults(authservID string) *Auth {
+	for _, value := range e.authResults {
+		fields := strings.Split(headerComment.ReplaceAllString(value, ""), ";")
+		// authserv-id may be followed by a version number
+		id, _, _ := strings.Cut(strings.TrimSpace(fields[0]), " ")
+		if !strings.EqualFold(id, authservID) {
+			continue
+		}
+
+		a := &Auth{}
+		for _, field := range fields[1:] {
+			props := strings.Fields(field)
+			if len(props) == 0 {
+				continue
+			}
+			method, result, _ := strings.Cut(strings.ToLower(props[0]), "=")
+			switch method {
+			case "spf":
+				a.SPF = result
+				for _, p := range props[1:] {
+					if v, ok := strings.CutPrefix(strings.ToLower(p), "smtp.mailfrom="); ok {
+						a.SPFDomain = domainOf(v)
+					}
+				}
+			case "dkim":
+				if result != "pass" {
+					continue
+				}
+				for _, p := range props[1:] {
+					if v, ok := strings.CutPrefix(strings.ToLower(p), "header.d="); ok {
+						a.DKIMDomains = append(a.DKIMDomains, v)
+					} else if v, ok := strings.CutPrefix(strings.ToLower(p), "header.i="); ok {
+						a.DKIMDomains = append(a.DKIMDomains, domainOf(v))
+					}
+				}
+			}
+		}
+		return a
+	}
+	return nil
+}
+
+// domainOf returns the domain of an address, or the value itself if it is
+// already a bare domain
+func domainOf(address string) string {
+	address = strings.Trim(strings.TrimSpace(address), "<>")
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+	return address
+}
@@ -0,0 +1,70 @@
+package email
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestAuthVerifies(t *testing.T) {
+	from := &mail.Address{Address: "Taro@Mail.Example.com"}
+	cases := []struct {
+		name string
+		auth *Auth
+		want bool
+	}{
+		{"no verdict", nil, false},
+		{"dkim of from domain", &Auth{DKIMDomains: []string{"mail.example.com"}}, true},
+		{"dkim of parent domain", &Auth{DKIMDomains: []string{"example.com"}}, true},
+		{"dkim of other domain", &Auth{DKIMDomains: []string{"attacker.test"}}, false},
+		{"dkim of lookalike domain", &Auth{DKIMDomains: []string{"ail.example.com"}}, false},
+		{"spf pass aligned", &Auth{SPF: "pass", SPFDomain: "example.com"}, true},
+		{"spf pass unaligned", &Auth{SPF: "pass", SPFDomain: "attacker.test"}, false},
+		{"spf softfail", &Auth{SPF: "softfail", SPFDomain: "example.com"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.auth.Verifies(from); got != c.want {
+				t.Errorf("Verifies = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSendGridAuth(t *testing.T) {
+	auth := SendGridAuth("Pass", "{@example.com : pass, @other.test : fail}", `{"from":"bounce@example.com","to":["in@parse.test"]}`)
+
+	if auth.SPF != "pass" || auth.SPFDomain != "example.com" {
+		t.Errorf("SPF = %q for %q", auth.SPF, auth.SPFDomain)
+	}
+	if len(auth.DKIMDomains) != 1 || auth.DKIMDomains[0] != "example.com" {
+		t.Errorf("DKIMDomains = %v, want only the passing signature", auth.DKIMDomains)
+	}
+}
+
+func TestAuthenticationResultsReadsTrustedServer(t *testing.T) {
+	raw := "Authentication-Results: mx.inbound.test;\r\n" +
+		" dkim=pass (2048-bit key) header.d=example.com header.s=s1;\r\n" +
+		" spf=fail (sender IP is 192.0.2.1) smtp.mailfrom=bounce@example.com\r\n" +
+		"Authentication-Results: mx.inbound.test; dkim=pass header.d=attacker.test\r\n" +
+		"From: taro@example.com\r\n" +
+		"Subject: hi\r\n\r\nbody\r\n"
+	e, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.AuthenticationResults("mx.other.test") != nil {
+		t.Error("a header from another server must not be read")
+	}
+	auth := e.AuthenticationResults("mx.inbound.test")
+	if auth == nil {
+		t.Fatal("no verdict from mx.inbound.test")
+	}
+	if auth.SPF != "fail" || auth.SPFDomain != "example.com" {
+		t.Errorf("SPF = %q for %q", auth.SPF, auth.SPFDomain)
+	}
+	if len(auth.DKIMDomains) != 1 || auth.DKIMDomains[0] != "example.com" {
+		t.Errorf("DKIMDomains = %v, want the topmost header's only", auth.DKIMDomains)
+	}
+}
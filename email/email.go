@@ -0,0 +1,206 @@
+// Package email parses forwarded emails (RFC 5322 / MIME) into text the
+// agent can read. Headers and bodies in legacy charsets such as ISO-2022-JP
+// and Shift_JIS are decoded to UTF-8.
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// maxPartDepth bounds how deeply nested multipart bodies are followed
+const maxPartDepth = 8
+
+// Email is a parsed email
+type Email struct {
+	// MessageID is the Message-ID header without angle brackets, if any
+	MessageID string
+	// From is nil when the header is missing or unparsable
+	From    *mail.Address
+	Subject string
+	// Date is zero when the header is missing or unparsable
+	Date time.Time
+	// Text is the plain text body, or the HTML body as text if there is none
+	Text        string
+	Attachments []Attachment
+	// Auth is the receiving server's SPF / DKIM verdict. Parse leaves it nil;
+	// the caller sets it from what the inbound service reports.
+	Auth *Auth
+
+	// authResults are the Authentication-Results headers, topmost first
+	authResults []string
+}
+
+// Attachment is a file attached to an email, or an inline part that is not
+// body text (e.g. an embedded image)
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// Parse reads a raw email
+func Parse(r io.Reader) (*Email, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email: %w", err)
+	}
+
+	e := &Email{
+		MessageID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>"),
+		Subject:   decodeHeader(msg.Header.Get("Subject")),
+		// net/mail keeps repeated headers in message order
+		authResults: msg.Header["Authentication-Results"],
+	}
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	if from, err := parser.Parse(msg.Header.Get("From")); err == nil {
+		e.From = from
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		e.Date = date
+	}
+
+	var body parts
+	if err := body.walk(textproto.MIMEHeader(msg.Header), msg.Body, 0); err != nil {
+		return nil, err
+	}
+	e.Text = body.text()
+	e.Attachments = body.attachments
+	return e, nil
+}
+
+// parts collects the body texts and attachments of a message
+type parts struct {
+	plain       []string
+	html        []string
+	attachments []Attachment
+}
+
+func (p *parts) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045: no (or a broken) Content-Type means US-ASCII plain text
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	disposition, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	name := dispParams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	name = decodeHeader(name)
+
+	if strings.HasPrefix(mediaType, "multipart/") && depth < maxPartDepth {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s part: %w", mediaType, err)
+			}
+			if err := p.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("failed to decode %s part: %w", mediaType, err)
+	}
+
+	isAttachment := disposition == "attachment" || name != ""
+	switch {
+	case !isAttachment && mediaType == "text/plain":
+		p.plain = append(p.plain, decodeText(data, params["charset"]))
+	case !isAttachment && mediaType == "text/html":
+		p.html = append(p.html, decodeText(data, params["charset"]))
+	default:
+		if name == "" {
+			name = fmt.Sprintf("attachment-%d%s", len(p.attachments)+1, extension(mediaType))
+		}
+		p.attachments = append(p.attachments, Attachment{Name: name, ContentType: mediaType, Data: data})
+	}
+	return nil
+}
+
+// text prefers the plain text parts; HTML-only mail is converted to text
+func (p *parts) text() string {
+	texts := p.plain
+	if len(texts) == 0 {
+		for _, h := range p.html {
+			texts = append(texts, htmlToText(h))
+		}
+	}
+	text := strings.Join(texts, "\n\n")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.TrimSpace(text)
+}
+
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// The decoder skips line breaks
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// decodeText converts a body in charset to UTF-8. Unknown charsets are kept as-is.
+func decodeText(data []byte, charset string) string {
+	r, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		return string(data)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii":
+		return input, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// decodeHeader decodes RFC 2047 encoded-words (=?ISO-2022-JP?B?...?=).
+// A header that cannot be decoded is returned as-is.
+func decodeHeader(s string) string {
+	decoded, err := wordDecoder.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+func extension(mediaType string) string {
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime/quotedprintable"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// encodedWord renders s as an RFC 2047 ISO-2022-JP B encoded-word
+func encodedWord(t *testing.T, s string) string {
+	return "=?ISO-2022-JP?B?" + base64.StdEncoding.EncodeToString(encode(t, japanese.ISO2022JP, s)) + "?="
+}
+
+func quotedPrintable(t *testing.T, data []byte) string {
+	t.Helper()
+	var b bytes.Buffer
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestParseISO2022JP(t *testing.T) {
+	raw := "Message-ID: <abc@example.com>\r\n" +
+		"From: " + encodedWord(t, "山田太郎") + " <taro@example.com>\r\n" +
+		"Subject: " + encodedWord(t, "会議の議事録") + "\r\n" +
+		"Date: Mon, 2 Mar 2026 10:00:00 +0900\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=ISO-2022-JP\r\n" +
+		"Content-Transfer-Encoding: 7bit\r\n\r\n" +
+		string(encode(t, japanese.ISO2022JP, "お疲れさまです。\r\n明日の会議は10時からです。\r\n"))
+
+	e, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Subject != "会議の議事録" {
+		t.Errorf("Subject = %q", e.Subject)
+	}
+	if e.From == nil || e.From.Name != "山田太郎" || e.From.Address != "taro@example.com" {
+		t.Errorf("From = %+v", e.From)
+	}
+	if e.MessageID != "abc@example.com" {
+		t.Errorf("MessageID = %q", e.MessageID)
+	}
+	if want := "お疲れさまです。\n明日の会議は10時からです。"; e.Text != want {
+		t.Errorf("Text = %q, want %q", e.Text, want)
+	}
+}
+
+func TestParseShiftJISBodies(t *testing.T) {
+	sjis := encode(t, japanese.ShiftJIS, "請求書を添付します。ご確認ください。")
+	for name, part := range map[string]string{
+		"quoted-printable": "Content-Transfer-Encoding: quoted-printable\r\n\r\n" + quotedPrintable(t, sjis),
+		"base64":           "Content-Transfer-Encoding: base64\r\n\r\n" + base64.StdEncoding.EncodeToString(sjis),
+	} {
+		t.Run(name, func(t *testing.T) {
+			raw := "From: taro@example.com\r\n" +
+				"Subject: invoice\r\n" +
+				"MIME-Version: 1.0\r\n" +
+				"Content-Type: multipart/mixed; boundary=b1\r\n\r\n" +
+				"--b1\r\n" +
+				"Content-Type: text/plain; charset=Shift_JIS\r\n" +
+				part + "\r\n" +
+				"--b1\r\n" +
+				"Content-Type: application/pdf\r\n" +
+				"Content-Disposition: attachment; filename=\"invoice.pdf\"\r\n" +
+				"Content-Transfer-Encoding: base64\r\n\r\n" +
+				base64.StdEncoding.EncodeToString([]byte("%PDF-1.4")) + "\r\n" +
+				"--b1--\r\n"
+
+			e, err := Parse(strings.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if e.Text != "請求書を添付します。ご確認ください。" {
+				t.Errorf("Text = %q", e.Text)
+			}
+			if len(e.Attachments) != 1 || e.Attachments[0].Name != "invoice.pdf" || string(e.Attachments[0].Data) != "%PDF-1.4" {
+				t.Errorf("Attachments = %+v", e.Attachments)
+			}
+		})
+	}
+}
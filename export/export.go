@@ -0,0 +1,95 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FormatVersion is bumped whenever the JSON layout changes incompatibly
+const FormatVersion = 1
+
+// Document is the versioned JSON export of a thread.
+// It embeds model.ThreadData like seeds do, so an export can be used as a seed as-is.
+type Document struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	model.ThreadData
+}
+
+// ImportOptions controls how a Document is restored
+type ImportOptions struct {
+	// ThreadID overrides the thread ID stored in the document
+	ThreadID string
+	// Overwrite allows restoring over an existing thread
+	Overwrite bool
+}
+
+// Export loads a thread with all of its messages
+func Export(ctx context.Context, chatRepo repository.ChatRepository, threadID string) (*Document, error) {
+	thread, err := chatRepo.GetThread(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := chatRepo.GetMessages(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{
+		Version:    FormatVersion,
+		ExportedAt: time.Now(),
+		ThreadData: model.ThreadData{
+			Thread:   *thread,
+			Messages: messages,
+		},
+	}, nil
+}
+
+// Decode reads a JSON export and checks its version
+func Decode(r io.Reader) (*Document, error) {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode export: %w", err)
+	}
+	if doc.Version == 0 || doc.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported export version: %d", doc.Version)
+	}
+	return &doc, nil
+}
+
+// Import restores a document into the chat repository. With Overwrite, an
+// existing thread is replaced, including messages missing from the document.
+func Import(ctx context.Context, chatRepo repository.ChatRepository, doc *Document, opts ImportOptions) (string, error) {
+	thread := doc.Thread
+	if opts.ThreadID != "" {
+		thread.ID = opts.ThreadID
+	}
+	if thread.ID == "" {
+		return "", fmt.Errorf("thread ID is required")
+	}
+
+	if !opts.Overwrite {
+		_, err := chatRepo.GetThread(ctx, thread.ID)
+		if err == nil {
+			return "", fmt.Errorf("thread %s already exists", thread.ID)
+		}
+		if status.Code(err) != codes.NotFound {
+			return "", fmt.Errorf("failed to check thread %s: %w", thread.ID, err)
+		}
+	}
+
+	if err := chatRepo.RestoreThread(ctx, &thread, doc.Messages); err != nil {
+		return "", err
+	}
+	return thread.ID, nil
+}
@@ -0,0 +1,119 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/test"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// exportChatRepository keeps restored threads in memory
+type exportChatRepository struct {
+	test.MockChatRepository
+	threads  map[string]model.ChatThread
+	messages map[string][]model.ChatMessage
+}
+
+func (r *exportChatRepository) GetThread(ctx context.Context, threadID string) (*model.ChatThread, error) {
+	thread, ok := r.threads[threadID]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "thread not found")
+	}
+	return &thread, nil
+}
+
+func (r *exportChatRepository) GetMessages(ctx context.Context, threadID string) ([]model.ChatMessage, error) {
+	return r.messages[threadID], nil
+}
+
+func (r *exportChatRepository) RestoreThread(ctx context.Context, thread *model.ChatThread, messages []model.ChatMessage) error {
+	r.threads[thread.ID] = *thread
+	r.messages[thread.ID] = messages
+	return nil
+}
+
+func newExportTest() *exportChatRepository {
+	created := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	return &exportChatRepository{
+		threads: map[string]model.ChatThread{
+			"t1": {ID: "t1", UserID: "u1", Title: "週末の予定", Tags: []string{"plans"}, CreatedAt: created},
+		},
+		messages: map[string][]model.ChatMessage{
+			"t1": {
+				{ID: "m1", ThreadID: "t1", Role: model.RoleUser, Content: "土曜の予定は？", CreatedAt: created},
+				{ID: "m2", ThreadID: "t1", Role: model.RoleAssistant, Content: "10時から買い物です。", CreatedAt: created.Add(time.Minute)},
+			},
+		},
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	repo := newExportTest()
+
+	doc, err := Export(ctx, repo, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(doc); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := Import(ctx, repo, decoded, ImportOptions{ThreadID: "t2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "t2" || repo.threads["t2"].Title != "週末の予定" || len(repo.messages["t2"]) != 2 {
+		t.Errorf("imported %s = %+v with %d messages, want a copy of t1", id, repo.threads["t2"], len(repo.messages["t2"]))
+	}
+}
+
+func TestImportRefusesExistingThread(t *testing.T) {
+	ctx := context.Background()
+	repo := newExportTest()
+	doc, err := Export(ctx, repo, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Import(ctx, repo, doc, ImportOptions{}); err == nil {
+		t.Error("Import over an existing thread succeeded without Overwrite")
+	}
+	if _, err := Import(ctx, repo, doc, ImportOptions{Overwrite: true}); err != nil {
+		t.Errorf("Import with Overwrite = %v", err)
+	}
+}
+
+func TestDecodeRejectsUnknownVersion(t *testing.T) {
+	for _, body := range []string{`{"thread":{"id":"t1"}}`, `{"version":99}`, `{`} {
+		if _, err := Decode(strings.NewReader(body)); err == nil {
+			t.Errorf("Decode(%s) succeeded", body)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	doc, err := Export(context.Background(), newExportTest(), "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := Markdown(doc)
+	for _, want := range []string{"# 週末の予定", "- Tags: plans", "### 👤 User — 2026-03-02 10:00", "10時から買い物です。"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown is missing %q:\n%s", want, md)
+		}
+	}
+}
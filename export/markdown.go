@@ -0,0 +1,66 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+
+	"youdoyou-server/model"
+)
+
+const timeLayout = "2006-01-02 15:04"
+
+// Markdown renders a document as a human-readable transcript
+func Markdown(doc *Document) string {
+	var b strings.Builder
+	thread := doc.Thread
+
+	title := thread.Title
+	if title == "" {
+		title = thread.FirstMessage
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+
+	fmt.Fprintf(&b, "- Thread ID: `%s`\n", thread.ID)
+	fmt.Fprintf(&b, "- Created: %s\n", thread.CreatedAt.Format(timeLayout))
+	if len(thread.Tags) > 0 {
+		fmt.Fprintf(&b, "- Tags: %s\n", strings.Join(thread.Tags, ", "))
+	}
+	if thread.IsPrivate {
+		b.WriteString("- Private: yes\n")
+	}
+	fmt.Fprintf(&b, "- Exported: %s\n", doc.ExportedAt.Format(timeLayout))
+
+	if thread.SessionMemory != "" {
+		b.WriteString("\n## Session Memory\n\n")
+		b.WriteString(strings.TrimSpace(thread.SessionMemory))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n## Messages\n")
+	for _, msg := range doc.Messages {
+		speaker := "👤 User"
+		if msg.Role == model.RoleAssistant {
+			speaker = "🤖 Assistant"
+		}
+		fmt.Fprintf(&b, "\n### %s — %s", speaker, msg.CreatedAt.Format(timeLayout))
+		if msg.BranchID != "" {
+			fmt.Fprintf(&b, " (branch `%s`)", msg.BranchID)
+		}
+		b.WriteString("\n\n")
+		b.WriteString(strings.TrimSpace(msg.Content))
+		b.WriteString("\n")
+
+		if len(msg.Attachments) > 0 {
+			b.WriteString("\nAttachments:\n")
+			for _, a := range msg.Attachments {
+				fmt.Fprintf(&b, "- [%s](%s) (%s, %d bytes)\n", a.Name, a.URL, a.MimeType, a.Size)
+			}
+		}
+
+		if msg.AIMetadata != nil {
+			fmt.Fprintf(&b, "\n<sub>model: %s, tokens: %.0f</sub>\n", msg.AIMetadata.Model, msg.AIMetadata.Usage.TotalTokens)
+		}
+	}
+
+	return b.String()
+}
@@ -7,22 +7,31 @@ import (
 	"net/http"
 	"strings"
 
-	"youdoyou-server/service"
+	"youdoyou-server/model"
+	"youdoyou-server/queue"
+	"youdoyou-server/repository"
+	"youdoyou-server/search"
 
 	"github.com/googleapis/google-cloudevents-go/cloud/firestoredata"
 	"google.golang.org/protobuf/proto"
 )
 
 type AgentHandler struct {
-	agentService *service.AgentService
+	chatRepo repository.ChatRepository
+	jobQueue queue.Queue
+	searcher *search.Searcher
 }
 
-func NewAgentHandler(agentService *service.AgentService) *AgentHandler {
-	return &AgentHandler{agentService: agentService}
+func NewAgentHandler(chatRepo repository.ChatRepository, jobQueue queue.Queue, searcher *search.Searcher) *AgentHandler {
+	return &AgentHandler{
+		chatRepo: chatRepo,
+		jobQueue: jobQueue,
+		searcher: searcher,
+	}
 }
 
 // ==========================================
-// 1. Generic Agent Chat (Scheduler / Manual)
+// 1. Generic Agent Chat (Manual)
 // URL: POST /v1/agent/chat
 // ==========================================
 func (h *AgentHandler) HandleAgentChat(w http.ResponseWriter, r *http.Request) {
@@ -31,37 +40,36 @@ func (h *AgentHandler) HandleAgentChat(w http.ResponseWriter, r *http.Request) {
 	// 定義した AgentChatRequest を使用
 	var req AgentChatRequest
 
-	// Bodyがあればデコード (Schedulerは空ボディで来る可能性があるためエラーは許容)
+	// Bodyがあればデコード (空ボディは下で threadId 未指定として弾く)
 	if r.Body != nil {
 		_ = json.NewDecoder(r.Body).Decode(&req)
 	}
 
-	var err error
-
+	// スレッドのないジョブは実行できないため、登録せずに弾く (定期実行は /v1/hooks/schedules を使う)
 	if req.ThreadID == "" {
-		log.Printf("⏰ Agent Chat Triggered (Initiate Mode)")
+		http.Error(w, "threadId is required", http.StatusBadRequest)
+		return
 	}
 
-	err = h.agentService.Chat(ctx, req.ThreadID)
-	if err != nil {
-		log.Printf("❌ Agent run failed: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// エージェントの実行はジョブキューに任せ、すぐに 202 を返す
+	job := queue.Job{ThreadID: req.ThreadID, MessageID: req.MessageID}
+	if err := h.jobQueue.Enqueue(ctx, job); err != nil {
+		log.Printf("❌ Failed to enqueue agent job: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	// 成功レスポンス
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(AgentChatResponse{Status: "ok"})
-	if err != nil {
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(AgentChatResponse{Status: "queued", ThreadID: req.ThreadID}); err != nil {
 		log.Printf("Failed to encode response: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
 	}
 }
 
 // ==========================================
 // 2. Firestore Trigger (Eventarc)
 // URL: POST /hooks/firestore
+// 作成イベント (google.cloud.firestore.document.v1.created) のみ購読する
 // ==========================================
 func (h *AgentHandler) HandleFirestoreTrigger(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -91,11 +99,24 @@ func (h *AgentHandler) HandleFirestoreTrigger(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// 全てのメッセージ (user / assistant) を検索インデックスに登録
+	msg := messageFromEvent(&eventData, threadID)
+	if h.searcher != nil {
+		if err := h.searcher.IndexMessage(ctx, msg); err != nil {
+			log.Printf("Warning: Failed to index message %s: %v", msg.ID, err)
+		}
+	}
+
+	// クライアントが直接書いたメッセージをスレッドのカウンタに加える (サーバー保存分は保存時に加算済み)
+	if err := h.chatRepo.CountMessage(ctx, threadID, msg.ID); err != nil {
+		log.Printf("Warning: Failed to count message %s: %v", msg.ID, err)
+	}
+
 	// Check if the message is from a user (only process user messages)
 	fields := eventData.GetValue().GetFields()
 	if roleField, ok := fields["role"]; ok {
 		role := roleField.GetStringValue()
-		if role != "user" {
+		if role != model.RoleUser {
 			// assistantメッセージなら処理せず正常終了
 			log.Printf("Skipping non-user message (role=%s)", role)
 			w.WriteHeader(http.StatusOK)
@@ -103,13 +124,24 @@ func (h *AgentHandler) HandleFirestoreTrigger(w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	if err := h.agentService.Chat(ctx, threadID); err != nil {
-		log.Printf("❌ Firestore trigger failed: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Slack / LINE / メールは Webhook がジョブ登録済み。インポートしたメッセージには返信しない
+	if source := fields["source"].GetStringValue(); source != "" {
+		log.Printf("Skipping %s message %s (not sent from the app)", source, msg.ID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// 最新メッセージではなく、トリガーされたメッセージそのものに返信する (編集・分岐対応)
+	// 長い LLM 呼び出しでリクエストを保持しないよう、ジョブとして登録して 202 を返す。
+	// 実行失敗は failedRuns + /v1/hooks/retry で再試行する
+	if err := h.jobQueue.Enqueue(ctx, queue.Job{ThreadID: threadID, MessageID: msg.ID}); err != nil {
+		// 登録できなかった場合のみ Eventarc に再配信させる
+		log.Printf("❌ Failed to enqueue agent job: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(http.StatusAccepted)
 }
 
 // ==========================================
@@ -125,3 +157,22 @@ func extractThreadIDFromPath(path string) string {
 	}
 	return ""
 }
+
+func messageFromEvent(eventData *firestoredata.DocumentEventData, threadID string) *model.ChatMessage {
+	doc := eventData.GetValue()
+	fields := doc.GetFields()
+
+	parts := strings.Split(doc.GetName(), "/")
+	msg := &model.ChatMessage{
+		ID:       parts[len(parts)-1],
+		ThreadID: threadID,
+		Role:     fields["role"].GetStringValue(),
+		Content:  fields["content"].GetStringValue(),
+	}
+	if ts := fields["createdAt"].GetTimestampValue(); ts != nil {
+		msg.CreatedAt = ts.AsTime()
+	} else if ct := doc.GetCreateTime(); ct != nil {
+		msg.CreatedAt = ct.AsTime()
+	}
+	return msg
+}
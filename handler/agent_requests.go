@@ -1,15 +1,18 @@
 package handler
 
 // AgentChatRequest は、Schedulerや手動実行時のリクエストボディ定義です。
-// Schedulerからの実行などでBodyが空の場合は、ThreadIDが空文字になります。
+// ThreadIDは必須です (空の場合は 400 を返します)。
+// MessageIDを指定すると、最新メッセージではなくそのメッセージに返信します。
 type AgentChatRequest struct {
-	ThreadID string `json:"threadId"`
+	ThreadID  string `json:"threadId"`
+	MessageID string `json:"messageId,omitempty"`
 }
 
 // レスポンス用の構造体は、単純なJSONを返すだけなら定義しなくても
 // w.Write([]byte(`{"status":"ok"}`)) で十分ですが、
 // 拡張性を考えるなら定義しておいてもOKです。
 type AgentChatResponse struct {
-	Status string `json:"status"`
-	Error  string `json:"error,omitempty"`
+	Status   string `json:"status"`
+	ThreadID string `json:"threadId,omitempty"`
+	Error    string `json:"error,omitempty"`
 }
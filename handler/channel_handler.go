@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"youdoyou-server/channel"
+	"youdoyou-server/service"
+)
+
+// maxWebhookBody はチャネル Webhook のリクエストボディの上限
+const maxWebhookBody = 1 << 20
+
+type ChannelHandler struct {
+	channelService *service.ChannelService
+	slack          channel.Adapter
+	line           channel.Adapter
+}
+
+// NewChannelHandler は設定されたチャネルのアダプタを受け取る (未設定は nil)
+func NewChannelHandler(channelService *service.ChannelService, slack channel.Adapter, line channel.Adapter) *ChannelHandler {
+	return &ChannelHandler{
+		channelService: channelService,
+		slack:          slack,
+		line:           line,
+	}
+}
+
+// ==========================================
+// Slack Events API
+// URL: POST /v1/channels/slack/events
+// ==========================================
+func (h *ChannelHandler) HandleSlack(w http.ResponseWriter, r *http.Request) {
+	h.handle(w, r, "slack", h.slack)
+}
+
+// ==========================================
+// LINE Messaging API Webhook
+// URL: POST /v1/channels/line/webhook
+// ==========================================
+func (h *ChannelHandler) HandleLine(w http.ResponseWriter, r *http.Request) {
+	h.handle(w, r, "line", h.line)
+}
+
+func (h *ChannelHandler) handle(w http.ResponseWriter, r *http.Request, name string, adapter channel.Adapter) {
+	ctx := r.Context()
+
+	if adapter == nil {
+		http.Error(w, name+" is not configured", http.StatusNotFound)
+		return
+	}
+
+	// 署名はボディのバイト列そのものに対して検証する
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	hook, err := adapter.Parse(r.Header, body)
+	if errors.Is(err, channel.ErrBadSignature) {
+		log.Printf("❌ Rejected %s webhook: %v", name, err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to parse %s webhook: %v", name, err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	// Slack の URL 検証
+	if hook.Challenge != "" {
+		w.Header().Set("Content-Type", "text/plain")
+		if _, err := w.Write([]byte(hook.Challenge)); err != nil {
+			log.Printf("Failed to write response: %v", err)
+		}
+		return
+	}
+
+	// 保存とジョブ登録だけ行い、すぐに 200 を返す (返信は ChannelChatRepository が投稿する)
+	for _, msg := range hook.Messages {
+		err := h.channelService.Receive(ctx, msg)
+		if errors.Is(err, service.ErrUnknownSender) {
+			// 再送されても結果は同じなので捨てる
+			log.Printf("Dropped %s message in %s: %v", name, msg.ConversationKey, err)
+			continue
+		}
+		if err != nil {
+			// プラットフォームに再送させる
+			log.Printf("❌ Failed to receive %s message in %s: %v", name, msg.ConversationKey, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"youdoyou-server/email"
+	"youdoyou-server/service"
+)
+
+// maxEmailBody は受信メール (添付込み) の上限
+const maxEmailBody = 25 << 20
+
+// rawEmailFields は multipart/form-data で生の MIME を載せるフィールド名
+// (SendGrid Inbound Parse の "Send Raw" は email、Mailgun の MIME 形式は body-mime)
+var rawEmailFields = []string{"email", "body-mime"}
+
+type EmailHandler struct {
+	emailService *service.EmailService
+	token        string
+	authservID   string
+}
+
+// NewEmailHandler は受信 URL のトークンと、Authentication-Results を信用する
+// 受信サーバー名を受け取る (emailService が nil なら未設定)
+func NewEmailHandler(emailService *service.EmailService, token string, authservID string) *EmailHandler {
+	return &EmailHandler{
+		emailService: emailService,
+		token:        token,
+		authservID:   authservID,
+	}
+}
+
+// ==========================================
+// 受信メール Webhook
+// URL: POST /v1/email/inbound?token=...
+// 生の MIME (message/rfc822) か、それを載せた multipart/form-data を受け付ける
+// From は SPF / DKIM の判定 (SendGrid の SPF・dkim フィールド、なければ
+// 受信サーバーの Authentication-Results) で確認できたものだけ信用する
+// ==========================================
+func (h *EmailHandler) HandleInbound(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.emailService == nil {
+		http.Error(w, "email is not configured", http.StatusNotFound)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(h.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxEmailBody)
+	raw, auth, err := readRawEmail(r)
+	if err != nil {
+		log.Printf("❌ Failed to read inbound email: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	parsed, err := email.Parse(bytes.NewReader(raw))
+	if err != nil {
+		log.Printf("❌ Failed to parse inbound email: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	parsed.Auth = auth
+	if parsed.Auth == nil && h.authservID != "" {
+		parsed.Auth = parsed.AuthenticationResults(h.authservID)
+	}
+
+	threadID, err := h.emailService.Receive(ctx, parsed)
+	if errors.Is(err, service.ErrUnknownSender) || errors.Is(err, service.ErrUnverifiedSender) {
+		// 再送されても結果は同じなので 200 で捨てる
+		log.Printf("Dropped inbound email %q: %v", parsed.Subject, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err != nil {
+		// 受信サービスに再送させる
+		log.Printf("❌ Failed to receive inbound email %q: %v", parsed.Subject, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, EmailInboundResponse{Status: "ok", ThreadID: threadID})
+}
+
+// readRawEmail はリクエストから生の MIME メッセージと、受信サービスが
+// フォームで送ってきた SPF / DKIM の判定 (なければ nil) を取り出す
+func readRawEmail(r *http.Request) ([]byte, *email.Auth, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		raw, err := io.ReadAll(r.Body)
+		return raw, nil, err
+	}
+
+	if err := r.ParseMultipartForm(maxEmailBody); err != nil {
+		return nil, nil, err
+	}
+	var auth *email.Auth
+	if r.FormValue("SPF") != "" || r.FormValue("dkim") != "" {
+		auth = email.SendGridAuth(r.FormValue("SPF"), r.FormValue("dkim"), r.FormValue("envelope"))
+	}
+	for _, field := range rawEmailFields {
+		if value := r.FormValue(field); value != "" {
+			return []byte(value), auth, nil
+		}
+		if files := r.MultipartForm.File[field]; len(files) > 0 {
+			f, err := files[0].Open()
+			if err != nil {
+				return nil, nil, err
+			}
+			defer f.Close()
+			raw, err := io.ReadAll(f)
+			return raw, auth, err
+		}
+	}
+	return nil, nil, errors.New("no raw MIME field (email or body-mime) in form")
+}
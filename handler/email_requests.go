@@ -0,0 +1,7 @@
+package handler
+
+// EmailInboundResponse は、受信メールから作成したスレッドです。
+type EmailInboundResponse struct {
+	Status   string `json:"status"`
+	ThreadID string `json:"threadId"`
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"youdoyou-server/middleware"
+	"youdoyou-server/service"
+	"youdoyou-server/tool"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// mcpDeadlineMargin は Tool の期限後に応答を返すための猶予
+const mcpDeadlineMargin = 10 * time.Second
+
+type MCPHandler struct {
+	server      *server.StreamableHTTPServer
+	toolTimeout time.Duration
+}
+
+// NewMCPHandler は ToolFactory の Tool を MCP (Streamable HTTP) で公開します。
+// 呼び出し元は Firebase ID トークンで認証され、ツールポリシーが適用される
+func NewMCPHandler(toolFactory *tool.ToolFactory, toolPolicies *service.ToolPolicyService, toolTimeout time.Duration) *MCPHandler {
+	caller := func(ctx context.Context) (tool.RunContext, error) {
+		token, ok := middleware.GetUserFromContext(ctx)
+		if !ok {
+			return tool.RunContext{}, errors.New("unauthorized")
+		}
+		perms, err := toolPolicies.ResolveUser(ctx, token.UID)
+		if err != nil {
+			log.Printf("Warning: Failed to resolve tool policy for user %s, running read-only: %v", token.UID, err)
+		}
+		return tool.RunContext{UserID: token.UID, Timezone: tool.DefaultTimezone, Permissions: perms}, nil
+	}
+
+	mcpServer := tool.NewMCPServer(toolFactory, caller, toolTimeout)
+	return &MCPHandler{
+		// セッションを持たないため、どのインスタンスに振り分けられても処理できる
+		server:      server.NewStreamableHTTPServer(mcpServer, server.WithStateLess(true)),
+		toolTimeout: toolTimeout,
+	}
+}
+
+// ==========================================
+// MCP Server (MCP Client)
+// URL: /mcp
+// ==========================================
+func (h *MCPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// サーバー全体の WriteTimeout より長い Tool の実行に合わせて延長する
+	if h.toolTimeout > 0 {
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Now().Add(h.toolTimeout + mcpDeadlineMargin)); err != nil {
+			log.Printf("Warning: Failed to extend write deadline: %v", err)
+		}
+	}
+
+	h.server.ServeHTTP(w, r)
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"youdoyou-server/middleware"
+	"youdoyou-server/queue"
+	"youdoyou-server/repository"
+	"youdoyou-server/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type MessageHandler struct {
+	chatRepo     repository.ChatRepository
+	agentService *service.AgentService
+	jobQueue     queue.Queue
+}
+
+func NewMessageHandler(chatRepo repository.ChatRepository, agentService *service.AgentService, jobQueue queue.Queue) *MessageHandler {
+	return &MessageHandler{
+		chatRepo:     chatRepo,
+		agentService: agentService,
+		jobQueue:     jobQueue,
+	}
+}
+
+// ==========================================
+// Regenerate (Client)
+// URL: POST /v1/threads/{threadID}/messages/{messageID}/regenerate
+// ==========================================
+func (h *MessageHandler) HandleRegenerate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	threadID := chi.URLParam(r, "threadID")
+	if !h.authorize(ctx, w, threadID) {
+		return
+	}
+
+	// 以前の返信は元の分岐に残し、新しい分岐で返信し直す。
+	// 返信はジョブとして生成し、保存された時点でその分岐に切り替わる
+	branchID, err := h.agentService.Regenerate(ctx, h.jobQueue, threadID, chi.URLParam(r, "messageID"))
+	if errors.Is(err, service.ErrMessageNotFound) {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Regenerate failed for thread %s: %v", threadID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(BranchResponse{BranchID: branchID}); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// ==========================================
+// Edit User Message (Client)
+// URL: POST /v1/threads/{threadID}/messages/{messageID}/edit
+// ==========================================
+func (h *MessageHandler) HandleEdit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	threadID := chi.URLParam(r, "threadID")
+	if !h.authorize(ctx, w, threadID) {
+		return
+	}
+
+	var req EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Content) == "" {
+		http.Error(w, "content is required", http.StatusBadRequest)
+		return
+	}
+
+	// 編集後のメッセージは新しい分岐に保存される。返信は Firestore トリガー経由で生成される
+	edited, err := h.agentService.EditMessage(ctx, threadID, chi.URLParam(r, "messageID"), req.Content)
+	if errors.Is(err, service.ErrMessageNotFound) {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Edit failed for thread %s: %v", threadID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, BranchResponse{BranchID: edited.BranchID, MessageID: edited.ID})
+}
+
+// ==========================================
+// Switch Branch (Client)
+// URL: PUT /v1/threads/{threadID}/branch
+// ==========================================
+func (h *MessageHandler) HandleSwitchBranch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	threadID := chi.URLParam(r, "threadID")
+	if !h.authorize(ctx, w, threadID) {
+		return
+	}
+
+	var req SwitchBranchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// 存在しない分岐への切替は拒否する (空文字はルート分岐)
+	if req.BranchID != "" {
+		messages, err := h.chatRepo.GetMessages(ctx, threadID)
+		if err != nil {
+			log.Printf("❌ Failed to get messages for thread %s: %v", threadID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		found := false
+		for _, m := range messages {
+			if m.BranchID == req.BranchID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			http.Error(w, "branch not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	if err := h.chatRepo.SetActiveBranch(ctx, threadID, req.BranchID); err != nil {
+		log.Printf("❌ Failed to switch branch for thread %s: %v", threadID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, BranchResponse{BranchID: req.BranchID})
+}
+
+// ==========================================
+// Cancel In-flight Run (Client)
+// URL: POST /v1/threads/{threadID}/cancel
+// ==========================================
+func (h *MessageHandler) HandleCancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	threadID := chi.URLParam(r, "threadID")
+	if !h.authorize(ctx, w, threadID) {
+		return
+	}
+
+	// このインスタンスの実行は即座に、他インスタンスの実行は次のターンで停止する
+	stopped, err := h.agentService.Cancel(ctx, threadID)
+	if err != nil {
+		log.Printf("❌ Cancel failed for thread %s: %v", threadID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("🛑 Cancel requested for thread %s (%d local run(s) stopped)", threadID, stopped)
+
+	writeJSON(w, CancelResponse{Status: "cancelling"})
+}
+
+// ==========================================
+// Helper Functions
+// ==========================================
+
+// authorize はスレッドの所有者かどうかを確認し、違う場合はエラーレスポンスを書き込みます。
+// 他人のスレッドは存在しないものとして扱う
+func (h *MessageHandler) authorize(ctx context.Context, w http.ResponseWriter, threadID string) bool {
+	token, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+
+	thread, err := h.chatRepo.GetThread(ctx, threadID)
+	if err != nil || thread.UserID != token.UID {
+		http.Error(w, "thread not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
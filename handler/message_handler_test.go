@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"youdoyou-server/middleware"
+	"youdoyou-server/model"
+	"youdoyou-server/service"
+	"youdoyou-server/test"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// messageChatRepository serves u1's thread t1 holding the user message m1
+type messageChatRepository struct {
+	test.MockChatRepository
+}
+
+func (r *messageChatRepository) GetThread(ctx context.Context, threadID string) (*model.ChatThread, error) {
+	if threadID != "t1" {
+		return nil, status.Error(codes.NotFound, "thread not found")
+	}
+	return &model.ChatThread{ID: "t1", UserID: "u1"}, nil
+}
+
+func (r *messageChatRepository) GetMessage(ctx context.Context, threadID string, messageID string) (*model.ChatMessage, error) {
+	if messageID != "m1" {
+		return nil, status.Error(codes.NotFound, "message not found")
+	}
+	return &model.ChatMessage{ID: "m1", ThreadID: threadID, Role: model.RoleUser}, nil
+}
+
+func regenerate(t *testing.T, uid string, threadID string, messageID string) (*httptest.ResponseRecorder, *test.FakeQueue) {
+	t.Helper()
+	chatRepo := &messageChatRepository{}
+	jobQueue := &test.FakeQueue{}
+	h := NewMessageHandler(chatRepo, service.NewAgentService(chatRepo, nil, nil, nil, nil, nil, nil, nil, nil), jobQueue)
+
+	router := chi.NewRouter()
+	router.Post("/threads/{threadID}/messages/{messageID}/regenerate", h.HandleRegenerate)
+	req := httptest.NewRequest(http.MethodPost, "/threads/"+threadID+"/messages/"+messageID+"/regenerate", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &auth.Token{UID: uid}))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec, jobQueue
+}
+
+func TestHandleRegenerateQueuesRun(t *testing.T) {
+	rec, jobQueue := regenerate(t, "u1", "t1", "m1")
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+	if len(jobQueue.Jobs) != 1 || jobQueue.Jobs[0].MessageID != "m1" || jobQueue.Jobs[0].BranchID == "" {
+		t.Errorf("jobs = %+v, want one run answering m1 on a new branch", jobQueue.Jobs)
+	}
+}
+
+func TestHandleRegenerateNotFound(t *testing.T) {
+	cases := map[string][3]string{
+		"unknown message": {"u1", "t1", "missing"},
+		"foreign thread":  {"u2", "t1", "m1"},
+		"unknown thread":  {"u1", "t2", "m1"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			rec, jobQueue := regenerate(t, c[0], c[1], c[2])
+
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want 404: %s", rec.Code, rec.Body)
+			}
+			if len(jobQueue.Jobs) != 0 {
+				t.Errorf("queued %+v, want nothing", jobQueue.Jobs)
+			}
+		})
+	}
+}
+
+func TestHandleCancel(t *testing.T) {
+	chatRepo := &messageChatRepository{}
+	h := NewMessageHandler(chatRepo, service.NewAgentService(chatRepo, nil, nil, nil, nil, nil, nil, nil, nil), &test.FakeQueue{})
+	router := chi.NewRouter()
+	router.Post("/threads/{threadID}/cancel", h.HandleCancel)
+
+	for uid, want := range map[string]int{"u1": http.StatusOK, "u2": http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodPost, "/threads/t1/cancel", nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &auth.Token{UID: uid}))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d: %s", uid, rec.Code, want, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package handler
+
+// EditMessageRequest は、ユーザーメッセージ編集APIのリクエストボディです。
+type EditMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// SwitchBranchRequest は、表示する分岐を切り替えるリクエストボディです。
+// BranchIDが空文字の場合は元の (ルート) 分岐に戻します。
+type SwitchBranchRequest struct {
+	BranchID string `json:"branchId"`
+}
+
+// BranchResponse は、編集・再生成・分岐切替の結果です。
+type BranchResponse struct {
+	BranchID  string `json:"branchId"`
+	MessageID string `json:"messageId,omitempty"`
+}
+
+// CancelResponse は、実行中のエージェントへのキャンセル要求の結果です。
+type CancelResponse struct {
+	Status string `json:"status"`
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"youdoyou-server/middleware"
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type NotificationHandler struct {
+	notificationRepo repository.NotificationRepository
+}
+
+func NewNotificationHandler(notificationRepo repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{notificationRepo: notificationRepo}
+}
+
+// ==========================================
+// Register Device (Client)
+// URL: POST /v1/devices
+// ==========================================
+func (h *NotificationHandler) HandleRegisterDevice(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RegisterDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Token) == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+	switch req.Platform {
+	case model.DevicePlatformIos, model.DevicePlatformAndroid, model.DevicePlatformWeb:
+	default:
+		http.Error(w, "platform must be ios, android or web", http.StatusBadRequest)
+		return
+	}
+
+	// アプリ起動のたびに登録し直してよい (同じトークンは上書きされる)
+	device := &model.DeviceToken{
+		UserID:    token.UID,
+		Token:     strings.TrimSpace(req.Token),
+		Platform:  req.Platform,
+		UpdatedAt: time.Now(),
+	}
+	if err := h.notificationRepo.SaveDeviceToken(ctx, device); err != nil {
+		log.Printf("❌ Failed to register device for user %s: %v", token.UID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, DeviceResponse{Status: "registered"})
+}
+
+// ==========================================
+// Unregister Device (Client, e.g. on sign-out)
+// URL: DELETE /v1/devices/{token}
+// ==========================================
+func (h *NotificationHandler) HandleUnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// 他のユーザーのトークンは削除しない
+	if err := h.notificationRepo.DeleteDeviceToken(ctx, token.UID, chi.URLParam(r, "token")); err != nil {
+		log.Printf("❌ Failed to unregister device for user %s: %v", token.UID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, DeviceResponse{Status: "unregistered"})
+}
+
+// ==========================================
+// Get Notification Settings (Client)
+// URL: GET /v1/notifications/settings
+// ==========================================
+func (h *NotificationHandler) HandleGetSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.notificationRepo.GetNotificationSettings(ctx, token.UID)
+	if err != nil {
+		log.Printf("❌ Failed to get notification settings for user %s: %v", token.UID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, newNotificationSettingsResponse(settings))
+}
+
+// ==========================================
+// Update Notification Settings (Client)
+// URL: PUT /v1/notifications/settings
+// ==========================================
+func (h *NotificationHandler) HandlePutSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req NotificationSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings := &model.NotificationSettings{
+		ID:           token.UID,
+		Muted:        req.Muted,
+		MutedUntil:   req.MutedUntil,
+		MutedThreads: req.MutedThreads,
+		HidePreviews: req.HidePreviews,
+		UpdatedAt:    time.Now(),
+	}
+	if err := h.notificationRepo.SaveNotificationSettings(ctx, settings); err != nil {
+		log.Printf("❌ Failed to save notification settings for user %s: %v", token.UID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, newNotificationSettingsResponse(settings))
+}
+
+func newNotificationSettingsResponse(settings *model.NotificationSettings) NotificationSettingsResponse {
+	resp := NotificationSettingsResponse{
+		Muted:        settings.Muted,
+		MutedUntil:   settings.MutedUntil,
+		MutedThreads: settings.MutedThreads,
+		HidePreviews: settings.HidePreviews,
+	}
+	if resp.MutedThreads == nil {
+		resp.MutedThreads = []string{}
+	}
+	return resp
+}
@@ -0,0 +1,32 @@
+package handler
+
+import "time"
+
+// RegisterDeviceRequest は、プッシュ通知を受け取る端末の登録リクエストボディです。
+// Platform は ios / android / web のいずれかです。
+type RegisterDeviceRequest struct {
+	Token    string `json:"token"`
+	Platform string `json:"platform"`
+}
+
+// NotificationSettingsRequest は、通知設定の更新リクエストボディです。
+// 指定しなかった項目は既定値 (通知あり・プレビューあり) に戻ります。
+type NotificationSettingsRequest struct {
+	Muted        bool      `json:"muted"`
+	MutedUntil   time.Time `json:"mutedUntil,omitzero"`
+	MutedThreads []string  `json:"mutedThreads"`
+	HidePreviews bool      `json:"hidePreviews"`
+}
+
+// NotificationSettingsResponse は、ユーザーの通知設定です。
+type NotificationSettingsResponse struct {
+	Muted        bool      `json:"muted"`
+	MutedUntil   time.Time `json:"mutedUntil,omitzero"`
+	MutedThreads []string  `json:"mutedThreads"`
+	HidePreviews bool      `json:"hidePreviews"`
+}
+
+// DeviceResponse は、端末の登録・削除の結果です。
+type DeviceResponse struct {
+	Status string `json:"status"`
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"youdoyou-server/service"
+)
+
+// defaultReminderLimit は 1 回の呼び出しで送るリマインダー件数の既定値
+const defaultReminderLimit = 50
+
+type ReminderHandler struct {
+	reminderService *service.ReminderService
+}
+
+func NewReminderHandler(reminderService *service.ReminderService) *ReminderHandler {
+	return &ReminderHandler{reminderService: reminderService}
+}
+
+// ==========================================
+// Send Due Reminders (Cloud Scheduler)
+// URL: POST /v1/hooks/reminders?limit=50
+// ==========================================
+func (h *ReminderHandler) HandleReminders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := defaultReminderLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	// 送信に失敗したタスクは次回の呼び出しで再送される
+	summary, err := h.reminderService.SendDue(ctx, time.Now(), limit)
+	if err != nil {
+		log.Printf("❌ Sending reminders failed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("⏰ Sent reminders: %+v", summary)
+
+	writeJSON(w, ReminderResponse{Status: "ok", ReminderSummary: summary})
+}
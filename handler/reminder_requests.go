@@ -0,0 +1,9 @@
+package handler
+
+import "youdoyou-server/service"
+
+// ReminderResponse は、期限を迎えたリマインダーの送信結果です。
+type ReminderResponse struct {
+	Status string `json:"status"`
+	service.ReminderSummary
+}
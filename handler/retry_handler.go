@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"youdoyou-server/queue"
+	"youdoyou-server/service"
+)
+
+// defaultRetryLimit は 1 回の呼び出しで再試行する件数の既定値
+const defaultRetryLimit = 3
+
+type RetryHandler struct {
+	retryService *service.RetryService
+	jobQueue     queue.Queue
+}
+
+func NewRetryHandler(retryService *service.RetryService, jobQueue queue.Queue) *RetryHandler {
+	return &RetryHandler{
+		retryService: retryService,
+		jobQueue:     jobQueue,
+	}
+}
+
+// ==========================================
+// Retry Failed Runs (Cloud Scheduler)
+// URL: POST /v1/hooks/retry?limit=3
+// ==========================================
+func (h *RetryHandler) HandleRetry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := defaultRetryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	// 再実行はジョブキューに任せ、すぐに 202 を返す
+	summary, err := h.retryService.RetryDue(ctx, h.jobQueue, time.Now(), limit)
+	if err != nil {
+		log.Printf("❌ Retry failed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("🔁 Queued failed runs: %+v", summary)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(RetryResponse{Status: "queued", RetrySummary: summary}); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
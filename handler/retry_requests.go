@@ -0,0 +1,9 @@
+package handler
+
+import "youdoyou-server/service"
+
+// RetryResponse は、失敗した実行の再試行ジョブの登録結果です。
+type RetryResponse struct {
+	Status string `json:"status"`
+	service.RetrySummary
+}
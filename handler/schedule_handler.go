@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"youdoyou-server/service"
+)
+
+// defaultScheduleLimit は 1 回の呼び出しで起動するスケジュール件数の既定値
+const defaultScheduleLimit = 50
+
+type ScheduleHandler struct {
+	scheduleService *service.ScheduleService
+}
+
+func NewScheduleHandler(scheduleService *service.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{scheduleService: scheduleService}
+}
+
+// ==========================================
+// Dispatch Due Schedules (Cloud Scheduler, every minute)
+// URL: POST /v1/hooks/schedules?limit=50
+// ==========================================
+func (h *ScheduleHandler) HandleSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := defaultScheduleLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	// 実行はジョブキューに積むだけ。取りこぼした回の扱いは ScheduleService.DispatchDue を参照
+	summary, err := h.scheduleService.DispatchDue(ctx, time.Now(), limit)
+	if err != nil {
+		log.Printf("❌ Dispatching schedules failed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("🔁 Dispatched schedules: %+v", summary)
+
+	writeJSON(w, ScheduleResponse{Status: "ok", ScheduleSummary: summary})
+}
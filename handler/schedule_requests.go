@@ -0,0 +1,9 @@
+package handler
+
+import "youdoyou-server/service"
+
+// ScheduleResponse は、期限を迎えたスケジュールの起動結果です。
+type ScheduleResponse struct {
+	Status string `json:"status"`
+	service.ScheduleSummary
+}
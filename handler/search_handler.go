@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"youdoyou-server/middleware"
+	"youdoyou-server/search"
+)
+
+const defaultSearchLimit = 20
+
+type SearchHandler struct {
+	searcher *search.Searcher
+}
+
+func NewSearchHandler(searcher *search.Searcher) *SearchHandler {
+	return &SearchHandler{searcher: searcher}
+}
+
+// ==========================================
+// Full-text Search (Client)
+// URL: GET /v1/search?q=xxx&limit=20
+// ==========================================
+func (h *SearchHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		http.Error(w, "missing query parameter: q", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.searcher.Search(ctx, query, token.UID, limit)
+	if err != nil {
+		log.Printf("❌ Search failed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(SearchResponse{Results: results}); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
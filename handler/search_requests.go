@@ -0,0 +1,7 @@
+package handler
+
+import "youdoyou-server/search"
+
+type SearchResponse struct {
+	Results []search.Result `json:"results"`
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"youdoyou-server/export"
+	"youdoyou-server/middleware"
+	"youdoyou-server/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ThreadHandler struct {
+	chatRepo repository.ChatRepository
+}
+
+func NewThreadHandler(chatRepo repository.ChatRepository) *ThreadHandler {
+	return &ThreadHandler{chatRepo: chatRepo}
+}
+
+// ==========================================
+// Thread List (Client)
+// URL: GET /v1/threads?tag=xxx&archived=true
+// ==========================================
+func (h *ThreadHandler) HandleListThreads(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tag := r.URL.Query().Get("tag")
+	includeArchived := r.URL.Query().Get("archived") == "true"
+
+	threads, err := h.chatRepo.ListThreads(ctx, token.UID, tag)
+	if err != nil {
+		log.Printf("❌ Failed to list threads: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListThreadsResponse{Threads: []ThreadResponse{}}
+	for _, t := range threads {
+		// アーカイブ済みはデフォルトで一覧から除外
+		if t.IsArchived && !includeArchived {
+			continue
+		}
+		resp.Threads = append(resp.Threads, newThreadResponse(t))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// ==========================================
+// Thread Export (Client)
+// URL: GET /v1/threads/{threadID}/export?format=json|markdown
+// ==========================================
+func (h *ThreadHandler) HandleExportThread(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	token, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	threadID := chi.URLParam(r, "threadID")
+	doc, err := export.Export(ctx, h.chatRepo, threadID)
+	if err != nil {
+		log.Printf("❌ Failed to export thread %s: %v", threadID, err)
+		http.Error(w, "thread not found", http.StatusNotFound)
+		return
+	}
+
+	// 他人のスレッドは存在しないものとして扱う
+	if doc.Thread.UserID != token.UID {
+		http.Error(w, "thread not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+threadID+`.json"`)
+		if err := json.NewEncoder(w).Encode(doc); err != nil {
+			log.Printf("Failed to encode response: %v", err)
+		}
+	case "markdown", "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+threadID+`.md"`)
+		if _, err := w.Write([]byte(export.Markdown(doc))); err != nil {
+			log.Printf("Failed to write response: %v", err)
+		}
+	default:
+		http.Error(w, "invalid format", http.StatusBadRequest)
+	}
+}
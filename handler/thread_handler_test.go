@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"youdoyou-server/middleware"
+	"youdoyou-server/model"
+	"youdoyou-server/test"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// listChatRepository holds threads of u1 and u2
+type listChatRepository struct {
+	test.MockChatRepository
+}
+
+func (r *listChatRepository) ListThreads(ctx context.Context, userID string, tag string) ([]model.ChatThread, error) {
+	all := []model.ChatThread{
+		{ID: "t1", UserID: "u1", Title: "仕事", Tags: []string{"work"}},
+		{ID: "t2", UserID: "u1", Title: "旅行", Tags: []string{"travel"}},
+		{ID: "t3", UserID: "u1", Tags: []string{"work"}, IsArchived: true},
+		{ID: "t4", UserID: "u2", Tags: []string{"work"}},
+	}
+	var result []model.ChatThread
+	for _, t := range all {
+		if t.UserID == userID && (tag == "" || slices.Contains(t.Tags, tag)) {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func listThreads(t *testing.T, query string) []string {
+	t.Helper()
+	h := NewThreadHandler(&listChatRepository{})
+	req := httptest.NewRequest(http.MethodGet, "/threads"+query, nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &auth.Token{UID: "u1"}))
+	rec := httptest.NewRecorder()
+	h.HandleListThreads(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resp ListThreadsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, thread := range resp.Threads {
+		ids = append(ids, thread.ID)
+	}
+	return ids
+}
+
+func TestHandleListThreadsByTag(t *testing.T) {
+	cases := map[string][]string{
+		"":                        {"t1", "t2"},
+		"?tag=work":               {"t1"},
+		"?tag=work&archived=true": {"t1", "t3"},
+		"?tag=none":               nil,
+	}
+	for query, want := range cases {
+		if got := listThreads(t, query); !slices.Equal(got, want) {
+			t.Errorf("%q: threads = %v, want %v", query, got, want)
+		}
+	}
+}
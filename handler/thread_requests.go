@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"time"
+
+	"youdoyou-server/model"
+)
+
+// ThreadResponse は、スレッド一覧APIで返すスレッドの表現です。
+type ThreadResponse struct {
+	ID           string    `json:"id"`
+	FirstMessage string    `json:"firstMessage"`
+	Title        string    `json:"title"`
+	Tags         []string  `json:"tags"`
+	UnreadCount  int       `json:"unreadCount"`
+	ReplyCount   int       `json:"replyCount"`
+	IsPrivate    bool      `json:"isPrivate"`
+	IsArchived   bool      `json:"isArchived"`
+	ActiveBranch string    `json:"activeBranchId"`
+	LastReadAt   time.Time `json:"lastReadAt"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type ListThreadsResponse struct {
+	Threads []ThreadResponse `json:"threads"`
+}
+
+func newThreadResponse(t model.ChatThread) ThreadResponse {
+	tags := t.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return ThreadResponse{
+		ID:           t.ID,
+		FirstMessage: t.FirstMessage,
+		Title:        t.Title,
+		Tags:         tags,
+		UnreadCount:  t.UnreadCount,
+		ReplyCount:   t.ReplyCount,
+		IsPrivate:    t.IsPrivate,
+		IsArchived:   t.IsArchived,
+		ActiveBranch: t.ActiveBranchID,
+		LastReadAt:   t.LastReadAt,
+		CreatedAt:    t.CreatedAt,
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"youdoyou-server/queue"
+)
+
+// workerDeadlineMargin はジョブの期限後に結果を記録・応答するための猶予
+const workerDeadlineMargin = 30 * time.Second
+
+type WorkerHandler struct {
+	worker *queue.Worker
+}
+
+func NewWorkerHandler(worker *queue.Worker) *WorkerHandler {
+	return &WorkerHandler{worker: worker}
+}
+
+// ==========================================
+// Agent Job Worker (Cloud Tasks)
+// URL: POST /v1/hooks/worker
+// ==========================================
+func (h *WorkerHandler) HandleWorker(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var job queue.Job
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil || job.ThreadID == "" {
+		// 不正なタスクは再試行しても成功しないため 400 で破棄させる
+		http.Error(w, "invalid job", http.StatusBadRequest)
+		return
+	}
+
+	// サーバー全体の WriteTimeout より長いジョブの期限に合わせて延長する
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(h.worker.Timeout() + workerDeadlineMargin)); err != nil {
+		log.Printf("Warning: Failed to extend write deadline: %v", err)
+	}
+
+	err := h.worker.TryRun(ctx, job)
+	if errors.Is(err, queue.ErrBusy) {
+		// 同時実行数の上限。Cloud Tasks にバックオフ付きで再送させる
+		http.Error(w, "worker busy", http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		// 失敗は failedRuns に記録済みのため、Cloud Tasks には再送させない
+		log.Printf("❌ Job for thread %s failed: %v", job.ThreadID, err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"youdoyou-server/queue"
+)
+
+func postJob(h *WorkerHandler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/hooks/worker", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.HandleWorker(rec, req)
+	return rec
+}
+
+func TestHandleWorkerRunsJob(t *testing.T) {
+	var ran queue.Job
+	h := NewWorkerHandler(queue.NewWorker(func(ctx context.Context, job queue.Job) error {
+		ran = job
+		return nil
+	}, 1, time.Minute))
+
+	rec := postJob(h, `{"threadId":"t1","messageId":"m1"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ran.ThreadID != "t1" || ran.MessageID != "m1" {
+		t.Errorf("job = %+v, want t1/m1", ran)
+	}
+}
+
+func TestHandleWorkerRejectsInvalidJob(t *testing.T) {
+	h := NewWorkerHandler(queue.NewWorker(func(ctx context.Context, job queue.Job) error {
+		t.Error("invalid job ran")
+		return nil
+	}, 1, time.Minute))
+
+	for _, body := range []string{`{`, `{"messageId":"m1"}`} {
+		if rec := postJob(h, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func TestHandleWorkerBusy(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	worker := queue.NewWorker(func(ctx context.Context, job queue.Job) error {
+		if job.ThreadID == "t1" {
+			close(started)
+			<-release
+		}
+		return nil
+	}, 1, time.Minute)
+	h := NewWorkerHandler(worker)
+
+	done := make(chan error)
+	go func() { done <- worker.Run(context.Background(), queue.Job{ThreadID: "t1"}) }()
+	<-started
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	// Cloud Tasks retries 429 with backoff
+	if rec := postJob(h, `{"threadId":"t2"}`); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", rec.Code)
+	}
+}
@@ -0,0 +1,136 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreLocker keeps leases in threadLocks/{threadID} so runs are
+// serialized across instances. A lease expires after ttl, so a crashed run
+// cannot block its thread forever.
+type FirestoreLocker struct {
+	client *firestore.Client
+	ttl    time.Duration
+}
+
+func NewFirestoreLocker(client *firestore.Client, ttl time.Duration) *FirestoreLocker {
+	return &FirestoreLocker{client: client, ttl: ttl}
+}
+
+func (l *FirestoreLocker) Acquire(ctx context.Context, threadID string, owner string, messageID string) (bool, error) {
+	ref := l.client.Collection("threadLocks").Doc(threadID)
+	var acquired bool
+
+	err := l.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		lease, err := getLease(tx, ref)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if lease != nil && lease.Owner != owner && lease.ExpiresAt.After(now) {
+			acquired = false
+			return tx.Update(ref, []firestore.Update{
+				{Path: "followUp", Value: true},
+				{Path: "followUpMessageId", Value: laterMessage(lease.FollowUpMessageID, messageID)},
+			})
+		}
+
+		// New lease, or takeover of an expired one. Follow-ups requested from
+		// the crashed holder are kept so this run answers them too.
+		if lease == nil {
+			lease = &model.ThreadLock{}
+		}
+		acquired = true
+		lease.Owner = owner
+		lease.ExpiresAt = now.Add(l.ttl)
+		return tx.Set(ref, lease)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire thread lock: %w", err)
+	}
+	return acquired, nil
+}
+
+func (l *FirestoreLocker) TryAcquire(ctx context.Context, threadID string, owner string) (bool, error) {
+	ref := l.client.Collection("threadLocks").Doc(threadID)
+	var acquired bool
+
+	err := l.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		lease, err := getLease(tx, ref)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if lease != nil && lease.Owner != owner && lease.ExpiresAt.After(now) {
+			acquired = false
+			return nil
+		}
+		if lease == nil {
+			lease = &model.ThreadLock{}
+		}
+		acquired = true
+		lease.Owner = owner
+		lease.ExpiresAt = now.Add(l.ttl)
+		return tx.Set(ref, lease)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire thread lock: %w", err)
+	}
+	return acquired, nil
+}
+
+func (l *FirestoreLocker) Release(ctx context.Context, threadID string, owner string) (string, bool, error) {
+	ref := l.client.Collection("threadLocks").Doc(threadID)
+	var messageID string
+	var followUp bool
+
+	err := l.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		messageID, followUp = "", false
+
+		lease, err := getLease(tx, ref)
+		if err != nil {
+			return err
+		}
+		if lease == nil || lease.Owner != owner {
+			// Expired and taken over; the new holder owns the follow-ups
+			return nil
+		}
+
+		if lease.FollowUp {
+			messageID, followUp = lease.FollowUpMessageID, true
+			return tx.Set(ref, &model.ThreadLock{
+				Owner:     owner,
+				ExpiresAt: time.Now().Add(l.ttl),
+			})
+		}
+		return tx.Delete(ref)
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to release thread lock: %w", err)
+	}
+	return messageID, followUp, nil
+}
+
+func getLease(tx *firestore.Transaction, ref *firestore.DocumentRef) (*model.ThreadLock, error) {
+	doc, err := tx.Get(ref)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lease model.ThreadLock
+	if err := doc.DataTo(&lease); err != nil {
+		return nil, fmt.Errorf("failed to parse thread lock: %w", err)
+	}
+	return &lease, nil
+}
@@ -0,0 +1,30 @@
+// Package lock serializes agent runs per thread. A run holds a lease on its
+// thread; runs that arrive meanwhile are folded into a single follow-up run
+// of the holder instead of running concurrently.
+package lock
+
+import (
+	"context"
+)
+
+type Locker interface {
+	// Acquire takes the thread's lease for owner. If another run holds it, the
+	// request is recorded as a follow-up for the holder and Acquire returns false.
+	Acquire(ctx context.Context, threadID string, owner string, messageID string) (bool, error)
+	// TryAcquire takes the thread's lease for owner if it is free. Unlike
+	// Acquire it records nothing when another run holds it.
+	TryAcquire(ctx context.Context, threadID string, owner string) (bool, error)
+	// Release ends owner's lease. If follow-ups were requested while it was
+	// held, the lease is kept and ok is true: the caller must run again for
+	// messageID (empty = latest message) and then call Release again.
+	Release(ctx context.Context, threadID string, owner string) (messageID string, ok bool, err error)
+}
+
+// laterMessage returns the later of two message IDs. UUID v7 IDs sort by
+// creation time; an empty ID (answer the latest message) sorts first.
+func laterMessage(a, b string) string {
+	if b > a {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,65 @@
+package lock
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryLocker keeps leases in process memory. It only serializes runs on a
+// single instance.
+type MemoryLocker struct {
+	mu     sync.Mutex
+	leases map[string]*memoryLease
+}
+
+type memoryLease struct {
+	owner             string
+	followUp          bool
+	followUpMessageID string
+}
+
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{leases: make(map[string]*memoryLease)}
+}
+
+func (l *MemoryLocker) Acquire(ctx context.Context, threadID string, owner string, messageID string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lease, ok := l.leases[threadID]; ok && lease.owner != owner {
+		lease.followUp = true
+		lease.followUpMessageID = laterMessage(lease.followUpMessageID, messageID)
+		return false, nil
+	}
+	l.leases[threadID] = &memoryLease{owner: owner}
+	return true, nil
+}
+
+func (l *MemoryLocker) TryAcquire(ctx context.Context, threadID string, owner string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lease, ok := l.leases[threadID]; ok && lease.owner != owner {
+		return false, nil
+	}
+	l.leases[threadID] = &memoryLease{owner: owner}
+	return true, nil
+}
+
+func (l *MemoryLocker) Release(ctx context.Context, threadID string, owner string) (string, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lease, ok := l.leases[threadID]
+	if !ok || lease.owner != owner {
+		return "", false, nil
+	}
+	if lease.followUp {
+		messageID := lease.followUpMessageID
+		lease.followUp = false
+		lease.followUpMessageID = ""
+		return messageID, true, nil
+	}
+	delete(l.leases, threadID)
+	return "", false, nil
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/idtoken"
+)
+
+// ServiceAuthMiddleware accepts requests from Google services (Cloud Tasks,
+// Cloud Scheduler, Eventarc) carrying an OIDC token that was issued for the
+// audience to one of the allowed service accounts
+type ServiceAuthMiddleware struct {
+	validator *idtoken.Validator
+	audience  string
+	accounts  []string
+}
+
+// NewServiceAuthMiddleware creates a new ServiceAuthMiddleware instance
+func NewServiceAuthMiddleware(ctx context.Context, audience string, accounts []string) (*ServiceAuthMiddleware, error) {
+	validator, err := idtoken.NewValidator(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OIDC token validator: %w", err)
+	}
+	return &ServiceAuthMiddleware{
+		validator: validator,
+		audience:  audience,
+		accounts:  accounts,
+	}, nil
+}
+
+// Handler は chi のミドルウェア形式 (func(http.Handler) http.Handler) です
+func (m *ServiceAuthMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || idToken == "" {
+			http.Error(w, "missing authorization header", http.StatusUnauthorized)
+			return
+		}
+
+		payload, err := m.validator.Validate(r.Context(), idToken, m.audience)
+		if err != nil {
+			log.Printf("Error verifying OIDC token: %v", err)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		// Google が確認したサービスアカウントのメールアドレスだけを信用する
+		email, _ := payload.Claims["email"].(string)
+		verified, _ := payload.Claims["email_verified"].(bool)
+		if !verified || !slices.Contains(m.accounts, email) {
+			log.Printf("Rejected OIDC token of %q", email)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,61 @@
+package migration
+
+import (
+	"context"
+
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+
+	"cloud.google.com/go/firestore"
+)
+
+// backfillThreadIDAndCounters sets threadId and counted on messages written
+// before they were stored, then recomputes replyCount / unreadCount on threads
+// (every message counts; see threads.replyCount in the schema).
+func backfillThreadIDAndCounters(client *firestore.Client) Migration {
+	counters := repository.NewFirestoreCounterRepository(client)
+
+	return Migration{
+		Version: 1,
+		Name:    "backfill_thread_id_and_counters",
+		Steps: []Step{
+			{
+				Collection:      "messages",
+				CollectionGroup: true,
+				Apply: func(ctx context.Context, doc *firestore.DocumentSnapshot) ([]firestore.Update, error) {
+					threadID := doc.Ref.Parent.Parent.ID
+					var updates []firestore.Update
+					if current, err := doc.DataAt("threadId"); err != nil || current != threadID {
+						updates = append(updates, firestore.Update{Path: "threadId", Value: threadID})
+					}
+					// The recount below includes every existing message
+					if counted, err := doc.DataAt("counted"); err != nil || counted != true {
+						updates = append(updates, firestore.Update{Path: "counted", Value: true})
+					}
+					return updates, nil
+				},
+			},
+			{
+				Collection: "threads",
+				Apply: func(ctx context.Context, doc *firestore.DocumentSnapshot) ([]firestore.Update, error) {
+					var thread model.ChatThread
+					if err := doc.DataTo(&thread); err != nil {
+						return nil, err
+					}
+
+					actual, err := counters.Recount(ctx, doc.Ref.ID, thread.LastReadAt)
+					if err != nil {
+						return nil, err
+					}
+					if actual.ReplyCount == thread.ReplyCount && actual.UnreadCount == thread.UnreadCount {
+						return nil, nil
+					}
+					return []firestore.Update{
+						{Path: "replyCount", Value: actual.ReplyCount},
+						{Path: "unreadCount", Value: actual.UnreadCount},
+					}, nil
+				},
+			},
+		},
+	}
+}
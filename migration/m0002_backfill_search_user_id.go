@@ -0,0 +1,47 @@
+package migration
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+)
+
+// backfillSearchUserID copies the thread owner onto search index entries
+// written before queries were restricted to the caller's entries. Entries of
+// deleted threads are left as they are; search skips them.
+func backfillSearchUserID(client *firestore.Client) Migration {
+	return Migration{
+		Version: 2,
+		Name:    "backfill_search_user_id",
+		Steps: []Step{
+			{
+				Collection: "searchIndex",
+				Apply: func(ctx context.Context, doc *firestore.DocumentSnapshot) ([]firestore.Update, error) {
+					if userID, err := doc.DataAt("userId"); err == nil && userID != "" {
+						return nil, nil
+					}
+					threadID, err := doc.DataAt("threadId")
+					if err != nil {
+						return nil, nil
+					}
+					id, ok := threadID.(string)
+					if !ok || id == "" {
+						return nil, nil
+					}
+					thread, err := client.Collection("threads").Doc(id).Get(ctx)
+					if err != nil {
+						if thread != nil && !thread.Exists() {
+							return nil, nil
+						}
+						return nil, err
+					}
+					owner, err := thread.DataAt("userId")
+					if err != nil {
+						return nil, nil
+					}
+					return []firestore.Update{{Path: "userId", Value: owner}}, nil
+				},
+			},
+		},
+	}
+}
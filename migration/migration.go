@@ -0,0 +1,242 @@
+// Package migration runs ordered, idempotent data migrations over Firestore
+// documents. Applied versions and resume cursors are recorded in the
+// _migrations collection.
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	stateCollection  = "_migrations"
+	defaultBatchSize = 200
+
+	StatusRunning = "running"
+	StatusDone    = "done"
+)
+
+// Step rewrites the documents of one collection.
+// Apply returns the updates for a document, or none if it is already migrated;
+// returning nothing for migrated documents is what makes re-runs safe.
+type Step struct {
+	// Collection is a top-level collection ("threads") or, with CollectionGroup,
+	// every subcollection with that name ("messages")
+	Collection      string
+	CollectionGroup bool
+	Apply           func(ctx context.Context, doc *firestore.DocumentSnapshot) ([]firestore.Update, error)
+}
+
+type Migration struct {
+	Version int
+	Name    string
+	Steps   []Step
+}
+
+// State is the record stored in _migrations/{version}
+type State struct {
+	Name       string    `firestore:"name"`
+	Status     string    `firestore:"status"`
+	Step       int       `firestore:"step"`
+	Cursor     string    `firestore:"cursor"` // relative path of the last processed document
+	Updated    int       `firestore:"updated"`
+	StartedAt  time.Time `firestore:"startedAt"`
+	FinishedAt time.Time `firestore:"finishedAt"`
+}
+
+type Runner struct {
+	client     *firestore.Client
+	migrations []Migration
+	DryRun     bool
+	BatchSize  int
+}
+
+func NewRunner(client *firestore.Client, migrations []Migration) *Runner {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Runner{
+		client:     client,
+		migrations: sorted,
+		BatchSize:  defaultBatchSize,
+	}
+}
+
+// Status returns the recorded state of every known migration (nil if never run)
+func (r *Runner) Status(ctx context.Context) (map[int]*State, error) {
+	result := make(map[int]*State)
+	for _, m := range r.migrations {
+		state, err := r.loadState(ctx, m.Version)
+		if err != nil {
+			return nil, err
+		}
+		result[m.Version] = state
+	}
+	return result, nil
+}
+
+// Run applies pending migrations up to and including target (0 = all)
+func (r *Runner) Run(ctx context.Context, target int) error {
+	for _, m := range r.migrations {
+		if target > 0 && m.Version > target {
+			break
+		}
+
+		state, err := r.loadState(ctx, m.Version)
+		if err != nil {
+			return err
+		}
+		if state != nil && state.Status == StatusDone {
+			continue
+		}
+		if state == nil {
+			state = &State{Name: m.Name, Status: StatusRunning, StartedAt: time.Now()}
+		} else {
+			log.Printf("Resuming migration %04d_%s at step %d after %q", m.Version, m.Name, state.Step, state.Cursor)
+		}
+
+		if err := r.runMigration(ctx, m, state); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) runMigration(ctx context.Context, m Migration, state *State) error {
+	log.Printf("Running migration %04d_%s", m.Version, m.Name)
+
+	for ; state.Step < len(m.Steps); state.Step++ {
+		step := m.Steps[state.Step]
+		for {
+			done, err := r.runPage(ctx, step, state)
+			if err != nil {
+				return err
+			}
+			if !r.DryRun {
+				if err := r.saveState(ctx, m.Version, state); err != nil {
+					return err
+				}
+			}
+			if done {
+				break
+			}
+		}
+		state.Cursor = ""
+	}
+
+	if r.DryRun {
+		log.Printf("Migration %04d_%s: %d document(s) would be updated (dry run)", m.Version, m.Name, state.Updated)
+		return nil
+	}
+
+	state.Status = StatusDone
+	state.FinishedAt = time.Now()
+	if err := r.saveState(ctx, m.Version, state); err != nil {
+		return err
+	}
+	log.Printf("Migration %04d_%s done: %d document(s) updated", m.Version, m.Name, state.Updated)
+	return nil
+}
+
+// runPage migrates one page of documents after the cursor and advances it
+func (r *Runner) runPage(ctx context.Context, step Step, state *State) (bool, error) {
+	var query firestore.Query
+	if step.CollectionGroup {
+		query = r.client.CollectionGroup(step.Collection).Query
+	} else {
+		query = r.client.Collection(step.Collection).Query
+	}
+	query = query.OrderBy(firestore.DocumentID, firestore.Asc).Limit(r.BatchSize)
+	if state.Cursor != "" {
+		query = query.StartAfter(r.client.Doc(state.Cursor))
+	}
+
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return false, fmt.Errorf("failed to query %s: %w", step.Collection, err)
+	}
+	if len(docs) == 0 {
+		return true, nil
+	}
+
+	var bw *firestore.BulkWriter
+	var jobs []*firestore.BulkWriterJob
+	if !r.DryRun {
+		bw = r.client.BulkWriter(ctx)
+	}
+
+	for _, doc := range docs {
+		updates, err := step.Apply(ctx, doc)
+		if err != nil {
+			if bw != nil {
+				bw.End()
+			}
+			return false, fmt.Errorf("%s: %w", relativePath(doc.Ref), err)
+		}
+		if len(updates) == 0 {
+			continue
+		}
+
+		state.Updated++
+		if r.DryRun {
+			log.Printf("  would update %s", relativePath(doc.Ref))
+			continue
+		}
+		job, err := bw.Update(doc.Ref, updates)
+		if err != nil {
+			bw.End()
+			return false, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	if bw != nil {
+		bw.End()
+		for _, job := range jobs {
+			if _, err := job.Results(); err != nil {
+				return false, fmt.Errorf("failed to write updates: %w", err)
+			}
+		}
+	}
+
+	state.Cursor = relativePath(docs[len(docs)-1].Ref)
+	return len(docs) < r.BatchSize, nil
+}
+
+// relativePath strips "projects/{p}/databases/{d}/documents/" so the cursor
+// can be passed back to Client.Doc
+func relativePath(ref *firestore.DocumentRef) string {
+	if _, after, ok := strings.Cut(ref.Path, "/documents/"); ok {
+		return after
+	}
+	return ref.Path
+}
+
+func (r *Runner) loadState(ctx context.Context, version int) (*State, error) {
+	doc, err := r.client.Collection(stateCollection).Doc(strconv.Itoa(version)).Get(ctx)
+	if err != nil {
+		if doc != nil && !doc.Exists() {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load migration state: %w", err)
+	}
+	var state State
+	if err := doc.DataTo(&state); err != nil {
+		return nil, fmt.Errorf("failed to parse migration state: %w", err)
+	}
+	return &state, nil
+}
+
+func (r *Runner) saveState(ctx context.Context, version int, state *State) error {
+	_, err := r.client.Collection(stateCollection).Doc(strconv.Itoa(version)).Set(ctx, state)
+	if err != nil {
+		return fmt.Errorf("failed to save migration state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// These tests run against the Firestore emulator (make emulators) and are
+// skipped when FIRESTORE_EMULATOR_HOST is not set. They use their own project
+// ID and clear it, so data seeded for development is left alone.
+
+const testProjectID = "demo-youdoyou-migration"
+
+func newTestClient(t *testing.T) *firestore.Client {
+	t.Helper()
+	host := os.Getenv("FIRESTORE_EMULATOR_HOST")
+	if host == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+
+	// Start from an empty database
+	url := fmt.Sprintf("http://%s/emulator/v1/projects/%s/databases/(default)/documents", host, testProjectID)
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to clear emulator: %v", err)
+	}
+	resp.Body.Close()
+
+	client, err := firestore.NewClient(context.Background(), testProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// seedThread writes a thread with stale counters and messages written before
+// threadId / counted were stored
+func seedThread(t *testing.T, client *firestore.Client, threadID string, lastReadAt time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	threadRef := client.Collection("threads").Doc(threadID)
+	if _, err := threadRef.Set(ctx, map[string]any{
+		"userId":      "user-1",
+		"replyCount":  0,
+		"unreadCount": 0,
+		"lastReadAt":  lastReadAt,
+		"createdAt":   lastReadAt.Add(-time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	messages := []map[string]any{
+		{"role": "user", "content": "hi", "createdAt": lastReadAt.Add(-time.Minute)},
+		{"role": "assistant", "content": "read", "createdAt": lastReadAt.Add(-time.Second)},
+		{"role": "assistant", "content": "unread", "createdAt": lastReadAt.Add(time.Minute)},
+	}
+	for i, msg := range messages {
+		if _, err := threadRef.Collection("messages").Doc("m"+strconv.Itoa(i)).Set(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBackfillThreadIDAndCountersIsIdempotent(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	seedThread(t, client, "t1", time.Now().Add(-time.Hour))
+
+	runner := NewRunner(client, []Migration{backfillThreadIDAndCounters(client)})
+	if err := runner.Run(ctx, 0); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+
+	thread, err := client.Collection("threads").Doc("t1").Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := thread.Data()["replyCount"]; got != int64(3) {
+		t.Errorf("replyCount = %v, want 3", got)
+	}
+	if got := thread.Data()["unreadCount"]; got != int64(1) {
+		t.Errorf("unreadCount = %v, want 1", got)
+	}
+	msg, err := client.Collection("threads").Doc("t1").Collection("messages").Doc("m0").Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Data()["threadId"]; got != "t1" {
+		t.Errorf("threadId = %v, want t1", got)
+	}
+	if got := msg.Data()["counted"]; got != true {
+		t.Errorf("counted = %v, want true", got)
+	}
+
+	// A second run over migrated data must not change anything
+	if _, err := client.Collection(stateCollection).Doc("1").Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.Run(ctx, 0); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	state, err := runner.loadState(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil || state.Status != StatusDone || state.Updated != 0 {
+		t.Errorf("second run state = %+v, want done with 0 updates", state)
+	}
+}
+
+func TestDryRunWritesNothing(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	seedThread(t, client, "t1", time.Now().Add(-time.Hour))
+
+	runner := NewRunner(client, []Migration{backfillThreadIDAndCounters(client)})
+	runner.DryRun = true
+	if err := runner.Run(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	thread, err := client.Collection("threads").Doc("t1").Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := thread.Data()["replyCount"]; got != int64(0) {
+		t.Errorf("replyCount = %v, want untouched 0", got)
+	}
+	msg, err := client.Collection("threads").Doc("t1").Collection("messages").Doc("m0").Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := msg.Data()["threadId"]; ok {
+		t.Error("dry run wrote threadId")
+	}
+	state, err := runner.loadState(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != nil {
+		t.Errorf("dry run saved state %+v", state)
+	}
+}
+
+func TestRunResumesFromCursor(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := client.Collection("items").Doc(id).Set(ctx, map[string]any{"n": 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var applied []string
+	m := Migration{
+		Version: 1,
+		Name:    "touch_items",
+		Steps: []Step{{
+			Collection: "items",
+			Apply: func(ctx context.Context, doc *firestore.DocumentSnapshot) ([]firestore.Update, error) {
+				applied = append(applied, doc.Ref.ID)
+				return []firestore.Update{{Path: "n", Value: 1}}, nil
+			},
+		}},
+	}
+
+	// An earlier run stopped after "c"
+	if _, err := client.Collection(stateCollection).Doc("1").Set(ctx, &State{
+		Name:      m.Name,
+		Status:    StatusRunning,
+		Cursor:    "items/c",
+		Updated:   3,
+		StartedAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := NewRunner(client, []Migration{m})
+	runner.BatchSize = 1 // one page per document, so every page advances the cursor
+	if err := runner.Run(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(applied) != "[d e]" {
+		t.Errorf("applied = %v, want [d e]", applied)
+	}
+	state, err := runner.loadState(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != StatusDone || state.Updated != 5 {
+		t.Errorf("state = %+v, want done with 5 updates", state)
+	}
+	for id, want := range map[string]int64{"a": 0, "c": 0, "d": 1, "e": 1} {
+		doc, err := client.Collection("items").Doc(id).Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := doc.Data()["n"]; got != want {
+			t.Errorf("items/%s n = %v, want %d", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package migration
+
+import (
+	"cloud.google.com/go/firestore"
+)
+
+// All returns every migration in version order. Add new migrations at the end;
+// never renumber or edit one that has been applied in production.
+func All(client *firestore.Client) []Migration {
+	return []Migration{
+		backfillThreadIDAndCounters(client),
+		backfillSearchUserID(client),
+	}
+}
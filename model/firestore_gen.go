@@ -0,0 +1,326 @@
+// Code generated by cmd/schemagen from schema/firestore.yaml; DO NOT EDIT.
+
+package model
+
+import "time"
+
+// Enum values declared in the schema
+const (
+	ChannelSlack            = "slack"
+	ChannelLine             = "line"
+	ChannelEmail            = "email"
+	DevicePlatformIos       = "ios"
+	DevicePlatformAndroid   = "android"
+	DevicePlatformWeb       = "web"
+	ErrorCodeTimeout        = "timeout"
+	ErrorCodeRateLimited    = "rate_limited"
+	ErrorCodeModelError     = "model_error"
+	ErrorCodeStorageError   = "storage_error"
+	ErrorCodeCancelled      = "cancelled"
+	ErrorCodeInternal       = "internal"
+	FailedRunStatusPending  = "pending"
+	FailedRunStatusResolved = "resolved"
+	FailedRunStatusDead     = "dead"
+	CatchUpOnce             = "once"
+	CatchUpSkip             = "skip"
+	RoleUser                = "user"
+	RoleAssistant           = "assistant"
+	TaskPriorityLow         = "low"
+	TaskPriorityNormal      = "normal"
+	TaskPriorityHigh        = "high"
+	MessageStatusPending    = "pending"
+	MessageStatusProcessing = "processing"
+	MessageStatusCompleted  = "completed"
+	MessageStatusError      = "error"
+	MessageSourceSlack      = "slack"
+	MessageSourceLine       = "line"
+	MessageSourceEmail      = "email"
+	MessageSourceImport     = "import"
+	AttachmentTypeImage     = "image"
+	AttachmentTypeText      = "text"
+	AttachmentTypeDocument  = "document"
+	AttachmentTypeAudio     = "audio"
+	AttachmentTypeVideo     = "video"
+	RoutingMethodRules      = "rules"
+	RoutingMethodModel      = "model"
+	RoutingMethodFallback   = "fallback"
+	RoutingMethodOff        = "off"
+)
+
+// ChannelAccount is a document in channelAccounts. Links a Slack / LINE user or an email sender address to a youdoyou user. Document ID is <channel>_<external user ID> (email_<lowercase address> for email). Created by an admin; messages and mail from unlinked senders are dropped.
+type ChannelAccount struct {
+	ID        string    `json:"id,omitempty" firestore:"-"`
+	UserID    string    `json:"userId" firestore:"userId"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+}
+
+// ChannelThread is a document in channelThreads. Maps a sender in a Slack / LINE conversation to the thread they continue, a received Slack / LINE event to the thread it was saved in, or a received email to the thread created for it. Document ID is <channel>_<conversation key>. Written by the channel and email webhooks.
+type ChannelThread struct {
+	ID      string `json:"id,omitempty" firestore:"-"`
+	Channel string `json:"channel" firestore:"channel"`
+	// Slack team:channel:thread_ts or LINE user / group / room ID, followed by :<userId> of the sender (each sender has their own thread), event:<Slack event_id / LINE webhookEventId>, or email Message-ID
+	ConversationKey string    `json:"conversationKey" firestore:"conversationKey"`
+	ThreadID        string    `json:"threadId" firestore:"threadId"`
+	CreatedAt       time.Time `json:"createdAt" firestore:"createdAt"`
+}
+
+// DeviceToken is a document in deviceTokens. FCM registration tokens of users' devices. Document ID is the SHA-256 of the token. Registered by POST /v1/devices; removed when FCM rejects the token.
+type DeviceToken struct {
+	ID       string `json:"id,omitempty" firestore:"-"`
+	UserID   string `json:"userId" firestore:"userId"`
+	Token    string `json:"token" firestore:"token"`
+	Platform string `json:"platform" firestore:"platform"`
+	// Last time the device registered the token
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// FailedRun is a document in failedRuns. Dead-letter queue of failed agent runs, keyed by the triggering message ID (or thread ID). Written by the server only.
+type FailedRun struct {
+	ID       string `json:"id,omitempty" firestore:"-"`
+	ThreadID string `json:"threadId" firestore:"threadId"`
+	// User message the run answered. Empty when the run answered the latest message.
+	MessageID string `json:"messageId,omitempty" firestore:"messageId,omitempty"`
+	// Last error message
+	Error     string `json:"error" firestore:"error"`
+	ErrorCode string `json:"errorCode" firestore:"errorCode"`
+	// Number of failed runs, including the original one
+	Attempts int `json:"attempts" firestore:"attempts"`
+	// pending: waiting for a retry, resolved: a retry succeeded, dead: not retryable or out of attempts
+	Status string `json:"status" firestore:"status"`
+	// Earliest time of the next retry (exponential backoff)
+	NextAttemptAt time.Time `json:"nextAttemptAt" firestore:"nextAttemptAt"`
+	CreatedAt     time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// NotificationSettings is a document in notificationSettings. Per-user push notification settings. Document ID is the user ID. A missing document means every push is sent with a preview.
+type NotificationSettings struct {
+	ID string `json:"id,omitempty" firestore:"-"`
+	// Sends no pushes at all
+	Muted bool `json:"muted" firestore:"muted"`
+	// Sends no pushes until this time
+	MutedUntil time.Time `json:"mutedUntil,omitempty" firestore:"mutedUntil,omitempty"`
+	// Threads that send no pushes
+	MutedThreads []string `json:"mutedThreads,omitempty" firestore:"mutedThreads,omitempty"`
+	// Pushes never contain message text. Private threads never show it either way.
+	HidePreviews bool      `json:"hidePreviews" firestore:"hidePreviews"`
+	UpdatedAt    time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Schedule is a document in schedules. Prompts the agent runs on a cron schedule. Created by the agent's schedule tools; runs are dispatched by /v1/hooks/schedules.
+type Schedule struct {
+	ID     string `json:"id,omitempty" firestore:"-"`
+	UserID string `json:"userId" firestore:"userId"`
+	Title  string `json:"title" firestore:"title"`
+	// Standard 5-field cron expression (or a descriptor like @daily), evaluated in timezone
+	Cron string `json:"cron" firestore:"cron"`
+	// IANA timezone the cron expression is evaluated in
+	Timezone string `json:"timezone" firestore:"timezone"`
+	// Instruction the agent answers on each run, as if the user had sent it
+	Prompt string `json:"prompt" firestore:"prompt"`
+	// Thread replies are posted to. Empty means a new thread is created by the next run and kept.
+	ThreadID string `json:"threadId,omitempty" firestore:"threadId,omitempty"`
+	// Start a new thread on every run instead of posting to threadId
+	NewThread bool `json:"newThread" firestore:"newThread"`
+	// What a run that is later than the misfire grace does: once runs it late, skip drops it. Missed runs are never replayed one by one.
+	CatchUp string `json:"catchUp" firestore:"catchUp"`
+	Enabled bool   `json:"enabled" firestore:"enabled"`
+	// Next scheduled time; the dispatcher runs the schedule once this has passed
+	NextRunAt time.Time `json:"nextRunAt" firestore:"nextRunAt"`
+	// Scheduled time of the last dispatched run
+	LastRunAt time.Time `json:"lastRunAt,omitempty" firestore:"lastRunAt,omitempty"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// SearchEntry is a document in searchIndex. Full-text index of messages. A long message is split into overlapping chunks, keyed by the message ID for the first and <message ID>_<n> for the rest. Written by the server only.
+type SearchEntry struct {
+	ID string `json:"id,omitempty" firestore:"-"`
+	// Indexed message. Entries written before chunking lack it; their document ID is the message ID.
+	MessageID string `json:"messageId" firestore:"messageId"`
+	// Owner of the parent thread. Queries only read the caller's entries.
+	UserID string `json:"userId" firestore:"userId"`
+	// Parent thread. Ownership is checked again against the live thread at query time.
+	ThreadID string `json:"threadId" firestore:"threadId"`
+	Role     string `json:"role" firestore:"role"`
+	// Copy of the chunk's content, used for snippets
+	Content string `json:"content" firestore:"content"`
+	// Lowercased words and Japanese unigrams/bigrams
+	Tokens    []string  `json:"tokens" firestore:"tokens"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+}
+
+// Task is a document in tasks. Native todo / reminder items. Created by the agent's task tools; reminders are posted by /v1/hooks/reminders.
+type Task struct {
+	ID     string `json:"id,omitempty" firestore:"-"`
+	UserID string `json:"userId" firestore:"userId"`
+	// Thread reminders are posted to. Empty means a new thread is created for the first reminder.
+	ThreadID string `json:"threadId,omitempty" firestore:"threadId,omitempty"`
+	Title    string `json:"title" firestore:"title"`
+	Notes    string `json:"notes,omitempty" firestore:"notes,omitempty"`
+	// Due date of the current occurrence; the reminder is posted at this time. Empty means no reminder.
+	DueAt time.Time `json:"dueAt,omitempty" firestore:"dueAt,omitempty"`
+	// iCalendar recurrence rule without DTSTART, e.g. FREQ=WEEKLY;BYDAY=MO. Anchored at seriesStart.
+	RRule string `json:"rrule,omitempty" firestore:"rrule,omitempty"`
+	// dueAt of the first occurrence of a recurring task
+	SeriesStart time.Time `json:"seriesStart,omitempty" firestore:"seriesStart,omitempty"`
+	// IANA timezone the recurrence is evaluated in
+	Timezone string `json:"timezone,omitempty" firestore:"timezone,omitempty"`
+	Priority string `json:"priority" firestore:"priority"`
+	// True when done. Completing a recurring task moves dueAt to the next occurrence instead, until the series ends.
+	Completed   bool      `json:"completed" firestore:"completed"`
+	CompletedAt time.Time `json:"completedAt,omitempty" firestore:"completedAt,omitempty"`
+	// dueAt of the occurrence whose reminder was posted, so it is posted once
+	RemindedAt time.Time `json:"remindedAt,omitempty" firestore:"remindedAt,omitempty"`
+	CreatedAt  time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// ThreadLock is a document in threadLocks. Lease that serializes agent runs per thread, keyed by thread ID. Written by the server only.
+type ThreadLock struct {
+	ID string `json:"id,omitempty" firestore:"-"`
+	// ID of the run holding the lease
+	Owner string `json:"owner" firestore:"owner"`
+	// The lease can be taken over after this time (crashed instance)
+	ExpiresAt time.Time `json:"expiresAt" firestore:"expiresAt"`
+	// A run arrived while the lease was held; the holder runs once more before releasing
+	FollowUp bool `json:"followUp" firestore:"followUp"`
+	// Latest message the coalesced runs were asked to answer (empty = latest message)
+	FollowUpMessageID string `json:"followUpMessageId,omitempty" firestore:"followUpMessageId,omitempty"`
+}
+
+// ChatThread is a document in threads. Main timeline posts (like Slack messages in a channel)
+type ChatThread struct {
+	ID string `json:"id,omitempty" firestore:"-"`
+	// Owner of the thread
+	UserID string `json:"userId" firestore:"userId"`
+	// The first message content. Acts as the thread's identity.
+	FirstMessage string `json:"firstMessage" firestore:"firstMessage"`
+	// Short generated title. Empty until the first assistant reply.
+	Title string `json:"title" firestore:"title"`
+	// Generated topic tags
+	Tags []string `json:"tags" firestore:"tags"`
+	// Number of unread assistant messages (incremented by the server on save)
+	UnreadCount int `json:"unreadCount" firestore:"unreadCount"`
+	// Timestamp of the last read message
+	LastReadAt time.Time `json:"lastReadAt" firestore:"lastReadAt"`
+	// Number of documents in the messages subcollection, of every role and every writer. Messages the server saves are counted in the same transaction; messages clients write are counted by the Firestore trigger. Recount, migrations and cmd/check use the same definition.
+	ReplyCount int `json:"replyCount" firestore:"replyCount"`
+	// If true, excluded from weekly reports
+	IsPrivate bool `json:"isPrivate" firestore:"isPrivate"`
+	// If true, hidden from thread list
+	IsArchived bool `json:"isArchived" firestore:"isArchived"`
+	// JSON string containing structured summary, decisions, and entities
+	SessionMemory string `json:"sessionMemory" firestore:"sessionMemory"`
+	// The createdAt of the last message included in the sessionMemory
+	MemorizedUntil time.Time `json:"memorizedUntil" firestore:"memorizedUntil"`
+	// Branch shown to the user and sent to the agent. Empty means the original (root) branch.
+	ActiveBranchID string `json:"activeBranchId,omitempty" firestore:"activeBranchId,omitempty"`
+	// Set by the cancel endpoint. Agent runs that started before this time stop at their next turn.
+	CancelRequestedAt time.Time `json:"cancelRequestedAt,omitempty" firestore:"cancelRequestedAt,omitempty"`
+	// IANA timezone of the user, e.g. Asia/Tokyo. Tools use it for dates; empty means Asia/Tokyo.
+	Timezone string `json:"timezone,omitempty" firestore:"timezone,omitempty"`
+	// Slack / LINE conversation this thread continues. Assistant messages are posted back to it.
+	Channel *ChannelRef `json:"channel,omitempty" firestore:"channel,omitempty"`
+	// Original post timestamp (UUID v7 should also encode this)
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+}
+
+// ChannelRef is the channel field of ChatThread.
+type ChannelRef struct {
+	Channel string `json:"channel" firestore:"channel"`
+	// Where replies are posted: Slack channel ID, or LINE user / group / room ID
+	Target string `json:"target" firestore:"target"`
+	// Slack thread_ts replies are posted under. Empty for LINE.
+	ThreadKey string `json:"threadKey" firestore:"threadKey"`
+}
+
+// ChatMessage is a document in threads/{id}/messages. Messages in a thread
+type ChatMessage struct {
+	ID string `json:"id,omitempty" firestore:"-"`
+	// Parent thread ID. Denormalized so collection group queries can find the thread.
+	ThreadID string `json:"threadId,omitempty" firestore:"threadId,omitempty"`
+	// Message this one follows. Empty means the previous message in the same branch.
+	ParentID string `json:"parentId,omitempty" firestore:"parentId,omitempty"`
+	// Branch created by an edit or regenerate. Empty means the original (root) branch.
+	BranchID string `json:"branchId,omitempty" firestore:"branchId,omitempty"`
+	// Message sender role
+	Role string `json:"role" firestore:"role"`
+	// Reply message content (supports markdown)
+	Content string `json:"content" firestore:"content"`
+	// Processing status of a user message, updated by the agent service. Empty on assistant messages.
+	Status string `json:"status,omitempty" firestore:"status,omitempty"`
+	// Why processing failed. Set on the failed user message and on the assistant error reply.
+	ErrorCode string `json:"errorCode,omitempty" firestore:"errorCode,omitempty"`
+	// True if running the agent again may succeed
+	Retryable bool `json:"retryable,omitempty" firestore:"retryable,omitempty"`
+	// Set once the message is included in the thread's replyCount / unreadCount, so a redelivered trigger does not count it twice
+	Counted bool `json:"counted,omitempty" firestore:"counted,omitempty"`
+	// Attached files in the reply
+	Attachments []Attachment `json:"attachments,omitempty" firestore:"attachments,omitempty"`
+	// Tools the agent ran to produce this reply
+	ToolCalls []ToolCall `json:"toolCalls,omitempty" firestore:"toolCalls,omitempty"`
+	// Where a user message came from. Empty means the app. Channel and email messages are queued by their webhook and import marks restored messages (exports, seeds), so the Firestore trigger skips them.
+	Source string `json:"source,omitempty" firestore:"source,omitempty"`
+	// Task this assistant message reminds about. Set on reminder messages.
+	TaskID string `json:"taskId,omitempty" firestore:"taskId,omitempty"`
+	// Schedule that started the run this assistant message answers. Set on scheduled replies.
+	ScheduleID string `json:"scheduleId,omitempty" firestore:"scheduleId,omitempty"`
+	// Tool groups the router exposed for this reply, recorded for debugging. Set on assistant messages.
+	Routing *ToolRouting `json:"routing,omitempty" firestore:"routing,omitempty"`
+	// AI response metadata
+	AIMetadata *AIMetadata `json:"aiMetadata,omitempty" firestore:"aiMetadata,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt" firestore:"createdAt"`
+}
+
+// Attachment is the attachments field of ChatMessage.
+type Attachment struct {
+	Type     string `json:"type" firestore:"type"`
+	URL      string `json:"url" firestore:"url"`
+	MimeType string `json:"mimeType" firestore:"mimeType"`
+	Name     string `json:"name" firestore:"name"`
+	Size     int64  `json:"size" firestore:"size"`
+}
+
+// ToolCall is the toolCalls field of ChatMessage.
+type ToolCall struct {
+	Name string `json:"name" firestore:"name"`
+	// Tool input as sent by the model
+	Parameters map[string]interface{} `json:"parameters" firestore:"parameters"`
+	Result     string                 `json:"result" firestore:"result"`
+}
+
+// ToolRouting is the routing field of ChatMessage.
+type ToolRouting struct {
+	Groups []string `json:"groups" firestore:"groups"`
+	// How the groups were chosen
+	Method string `json:"method" firestore:"method"`
+	Reason string `json:"reason" firestore:"reason"`
+}
+
+// AIMetadata is the aiMetadata field of ChatMessage.
+type AIMetadata struct {
+	Model        string  `json:"model" firestore:"model"`
+	Usage        AIUsage `json:"usage" firestore:"usage"`
+	FinishReason string  `json:"finishReason" firestore:"finishReason"`
+	ResponseID   string  `json:"responseId" firestore:"responseId"`
+}
+
+// AIUsage is the usage field of AIMetadata.
+type AIUsage struct {
+	PromptTokens     float64 `json:"promptTokens" firestore:"promptTokens"`
+	CompletionTokens float64 `json:"completionTokens" firestore:"completionTokens"`
+	TotalTokens      float64 `json:"totalTokens" firestore:"totalTokens"`
+}
+
+// ToolPolicy is a document in toolPolicies. Restricts the tools the agent may use, keyed by user_<userId> or thread_<threadId> (threads started by email get a read-only one). Policies only narrow the defaults in the server config; the most restrictive one wins.
+type ToolPolicy struct {
+	ID string `json:"id,omitempty" firestore:"-"`
+	// Tool groups the agent may use (calendar, notion, search). Empty keeps the inherited groups.
+	Groups []string `json:"groups,omitempty" firestore:"groups,omitempty"`
+	// Tool names the agent may not call, e.g. createNotionPage
+	DenyTools []string `json:"denyTools,omitempty" firestore:"denyTools,omitempty"`
+	// Deny tools that write to external services
+	ReadOnly  bool      `json:"readOnly" firestore:"readOnly"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
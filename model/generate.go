@@ -0,0 +1,3 @@
+package model
+
+//go:generate go run ../cmd/schemagen -schema ../schema/firestore.yaml -out firestore_gen.go
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/messaging"
+)
+
+// FCMSender sends pushes with Firebase Cloud Messaging
+type FCMSender struct {
+	client *messaging.Client
+}
+
+func NewFCMSender(ctx context.Context, app *firebase.App) (*FCMSender, error) {
+	client, err := app.Messaging(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FCM client: %w", err)
+	}
+	return &FCMSender{client: client}, nil
+}
+
+func (s *FCMSender) Send(ctx context.Context, token string, n Notification) error {
+	_, err := s.client.Send(ctx, &messaging.Message{
+		Token:        token,
+		Notification: &messaging.Notification{Title: n.Title, Body: n.Body},
+		Data:         n.Data,
+		APNS: &messaging.APNSConfig{
+			Payload: &messaging.APNSPayload{Aps: &messaging.Aps{Sound: "default"}},
+		},
+	})
+	if messaging.IsUnregistered(err) || messaging.IsSenderIDMismatch(err) {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send push: %w", err)
+	}
+	return nil
+}
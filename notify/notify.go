@@ -0,0 +1,24 @@
+// Package notify sends push notifications to users' devices. Senders sit
+// behind an interface so the service can run with FCM or a fake.
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidToken is returned when a device token will never work again
+// (the app was uninstalled or the token expired); callers should forget it
+var ErrInvalidToken = errors.New("device token is no longer valid")
+
+// Notification is one push. Data is passed to the app untouched, e.g. so a
+// tap opens the thread.
+type Notification struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+type Sender interface {
+	Send(ctx context.Context, token string, n Notification) error
+}
@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	cloudtasks "google.golang.org/api/cloudtasks/v2"
+	"google.golang.org/api/googleapi"
+)
+
+// CloudTasksConfig describes the queue and the worker endpoint tasks are sent to
+type CloudTasksConfig struct {
+	ProjectID      string
+	Location       string
+	Queue          string
+	WorkerURL      string
+	ServiceAccount string // used for the OIDC token on the worker request
+	Audience       string // of the OIDC token; the worker URL if empty
+	Timeout        time.Duration
+}
+
+// CloudTasksQueue enqueues jobs as HTTP tasks that POST to the worker endpoint
+type CloudTasksQueue struct {
+	service *cloudtasks.Service
+	cfg     CloudTasksConfig
+}
+
+var taskNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+func NewCloudTasksQueue(ctx context.Context, cfg CloudTasksConfig) (*CloudTasksQueue, error) {
+	if cfg.Queue == "" || cfg.WorkerURL == "" {
+		return nil, fmt.Errorf("cloud tasks queue and worker URL are required")
+	}
+	service, err := cloudtasks.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Tasks client: %w", err)
+	}
+	return &CloudTasksQueue{service: service, cfg: cfg}, nil
+}
+
+func (q *CloudTasksQueue) Enqueue(ctx context.Context, job Job) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	parent := fmt.Sprintf("projects/%s/locations/%s/queues/%s", q.cfg.ProjectID, q.cfg.Location, q.cfg.Queue)
+	task := &cloudtasks.Task{
+		HttpRequest: &cloudtasks.HttpRequest{
+			HttpMethod: http.MethodPost,
+			Url:        q.cfg.WorkerURL,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Body:       base64.StdEncoding.EncodeToString(body),
+		},
+	}
+	if q.cfg.ServiceAccount != "" {
+		task.HttpRequest.OidcToken = &cloudtasks.OidcToken{ServiceAccountEmail: q.cfg.ServiceAccount, Audience: q.cfg.Audience}
+	}
+	if q.cfg.Timeout > 0 {
+		// Leave headroom for the worker to record the outcome after the job's deadline
+		task.DispatchDeadline = fmt.Sprintf("%ds", int((q.cfg.Timeout + 30*time.Second).Seconds()))
+	}
+	switch {
+	case job.Retry:
+		// Retries answer a message that already had a task; RetryDue queues each attempt once
+	case job.MessageID != "":
+		// Named tasks are deduplicated, so a redelivered trigger doesn't answer twice.
+		// A regenerate answers the same message again on its own branch.
+		name := job.ThreadID + "_" + job.MessageID
+		if job.BranchID != "" {
+			name += "_" + job.BranchID
+		}
+		task.Name = parent + "/tasks/" + taskNameUnsafe.ReplaceAllString(name, "-")
+	case job.ScheduleID != "":
+		// One task per scheduled time of a schedule
+		task.Name = parent + "/tasks/" + taskNameUnsafe.ReplaceAllString(fmt.Sprintf("%s_%s_%d", job.ThreadID, job.ScheduleID, job.RunAt.Unix()), "-")
+	}
+
+	_, err = q.service.Projects.Locations.Queues.Tasks.Create(parent, &cloudtasks.CreateTaskRequest{Task: task}).Context(ctx).Do()
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create task: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package queue
+
+import (
+	"context"
+	"log"
+)
+
+// InlineQueue runs each job before Enqueue returns, for one-shot commands
+// that have to wait for their jobs. Failures are logged, like LocalQueue's.
+type InlineQueue struct {
+	worker *Worker
+}
+
+func NewInlineQueue(worker *Worker) *InlineQueue {
+	return &InlineQueue{worker: worker}
+}
+
+func (q *InlineQueue) Enqueue(ctx context.Context, job Job) error {
+	if err := q.worker.Run(ctx, job); err != nil {
+		log.Printf("❌ Job for thread %s failed: %v", job.ThreadID, err)
+	}
+	return nil
+}
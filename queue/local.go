@@ -0,0 +1,37 @@
+package queue
+
+import (
+	"context"
+	"log"
+)
+
+// LocalQueue runs jobs on in-process goroutines. Jobs are lost if the process
+// exits, so it is meant for local development and single-instance setups.
+type LocalQueue struct {
+	jobs chan Job
+}
+
+// NewLocalQueue starts one goroutine per worker slot. buffer is the number of
+// jobs that may wait for a free slot.
+func NewLocalQueue(worker *Worker, buffer int) *LocalQueue {
+	q := &LocalQueue{jobs: make(chan Job, buffer)}
+	for i := 0; i < cap(worker.slots); i++ {
+		go func() {
+			for job := range q.jobs {
+				if err := worker.Run(context.Background(), job); err != nil {
+					log.Printf("❌ Job for thread %s failed: %v", job.ThreadID, err)
+				}
+			}
+		}()
+	}
+	return q
+}
+
+func (q *LocalQueue) Enqueue(ctx context.Context, job Job) error {
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
@@ -0,0 +1,92 @@
+// Package queue runs agent jobs outside the HTTP request that triggered them,
+// either on Cloud Tasks or on an in-process goroutine pool.
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrBusy is returned by Worker.TryRun when every slot is taken
+	ErrBusy = errors.New("worker is busy")
+	// ErrQueueFull is returned by LocalQueue.Enqueue when its buffer is full
+	ErrQueueFull = errors.New("job queue is full")
+)
+
+// Job asks the agent to answer a thread
+type Job struct {
+	ThreadID string `json:"threadId"`
+	// MessageID is the message to answer. Empty means the latest message.
+	MessageID string `json:"messageId,omitempty"`
+	// BranchID puts the reply on a new branch (regenerate)
+	BranchID string `json:"branchId,omitempty"`
+	// Retry marks a re-run of a failed run
+	Retry bool `json:"retry,omitempty"`
+
+	// ScheduleID marks a scheduled run: the agent answers Prompt instead of a
+	// user message. RunAt is the scheduled time being run.
+	ScheduleID string    `json:"scheduleId,omitempty"`
+	Prompt     string    `json:"prompt,omitempty"`
+	RunAt      time.Time `json:"runAt,omitzero"`
+}
+
+// Handler runs one job
+type Handler func(ctx context.Context, job Job) error
+
+type Queue interface {
+	Enqueue(ctx context.Context, job Job) error
+}
+
+// Worker runs jobs with limited concurrency, each with its own deadline
+type Worker struct {
+	handler Handler
+	slots   chan struct{}
+	timeout time.Duration
+}
+
+func NewWorker(handler Handler, concurrency int, timeout time.Duration) *Worker {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Worker{
+		handler: handler,
+		slots:   make(chan struct{}, concurrency),
+		timeout: timeout,
+	}
+}
+
+// Timeout is the deadline given to each job
+func (w *Worker) Timeout() time.Duration {
+	return w.timeout
+}
+
+// Run waits for a free slot and runs the job
+func (w *Worker) Run(ctx context.Context, job Job) error {
+	select {
+	case w.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return w.run(ctx, job)
+}
+
+// TryRun runs the job if a slot is free and returns ErrBusy otherwise
+func (w *Worker) TryRun(ctx context.Context, job Job) error {
+	select {
+	case w.slots <- struct{}{}:
+	default:
+		return ErrBusy
+	}
+	return w.run(ctx, job)
+}
+
+func (w *Worker) run(ctx context.Context, job Job) error {
+	defer func() { <-w.slots }()
+
+	// The job outlives the request that delivered it, but not its own deadline
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), w.timeout)
+	defer cancel()
+	return w.handler(ctx, job)
+}
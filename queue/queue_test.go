@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorkerTryRunWhenBusy(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	w := NewWorker(func(ctx context.Context, job Job) error {
+		close(started)
+		<-release
+		return nil
+	}, 1, time.Minute)
+
+	done := make(chan error)
+	go func() { done <- w.Run(context.Background(), Job{ThreadID: "t1"}) }()
+	<-started
+
+	if err := w.TryRun(context.Background(), Job{ThreadID: "t2"}); !errors.Is(err, ErrBusy) {
+		t.Errorf("TryRun = %v, want ErrBusy", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkerJobOutlivesRequest(t *testing.T) {
+	var jobErr error
+	w := NewWorker(func(ctx context.Context, job Job) error {
+		jobErr = ctx.Err()
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("job has no deadline")
+		}
+		return nil
+	}, 1, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.TryRun(ctx, Job{ThreadID: "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	if jobErr != nil {
+		t.Errorf("job context ended with the request: %v", jobErr)
+	}
+}
+
+func TestLocalQueueRunsJobs(t *testing.T) {
+	jobs := make(chan Job, 1)
+	w := NewWorker(func(ctx context.Context, job Job) error {
+		jobs <- job
+		return nil
+	}, 1, time.Minute)
+	q := NewLocalQueue(w, 1)
+
+	if err := q.Enqueue(context.Background(), Job{ThreadID: "t1", MessageID: "m1"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case job := <-jobs:
+		if job.ThreadID != "t1" || job.MessageID != "m1" {
+			t.Errorf("job = %+v, want t1/m1", job)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not run")
+	}
+}
+
+func TestLocalQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	w := NewWorker(func(ctx context.Context, job Job) error {
+		<-release
+		return nil
+	}, 1, time.Minute)
+	// No goroutine drains the buffer while the only slot is held
+	w.slots <- struct{}{}
+	q := NewLocalQueue(w, 1)
+
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = q.Enqueue(context.Background(), Job{ThreadID: "t1"})
+	}
+	if !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Enqueue = %v, want ErrQueueFull", err)
+	}
+}
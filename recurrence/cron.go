@@ -0,0 +1,103 @@
+package recurrence
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxMissedRuns bounds the walk over missed cron times in LastCron
+const maxMissedRuns = 100000
+
+// ValidateCron checks that expr is a standard 5-field cron expression or a
+// descriptor such as @daily. The timezone is kept separately, so CRON_TZ= and
+// TZ= prefixes are rejected.
+func ValidateCron(expr string) error {
+	_, err := parseCron(expr)
+	return err
+}
+
+// NextCron returns the first time after `after` that expr fires in loc
+func NextCron(expr string, after time.Time, loc *time.Location) (time.Time, error) {
+	sched, err := parseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	next := sched.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never fires", expr)
+	}
+	return next, nil
+}
+
+// LastCron returns the latest time expr fires in loc that is not after now,
+// starting from from, a time it fires at. It returns from when no later time
+// has passed.
+func LastCron(expr string, from time.Time, now time.Time, loc *time.Location) (time.Time, error) {
+	sched, err := parseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	last := from
+	for i := 0; i < maxMissedRuns; i++ {
+		next := sched.Next(last.In(loc))
+		if next.IsZero() || next.After(now) {
+			return last, nil
+		}
+		last = next
+	}
+	return last, nil
+}
+
+// ShortestCronGap returns the shortest time between consecutive times expr
+// fires in loc during window after `after`. It stops at the first gap shorter
+// than floor, so frequent expressions are not walked to the end.
+func ShortestCronGap(expr string, after time.Time, window time.Duration, floor time.Duration, loc *time.Location) (time.Duration, error) {
+	sched, err := parseCron(expr)
+	if err != nil {
+		return 0, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	end := after.Add(window)
+	prev := sched.Next(after.In(loc))
+	if prev.IsZero() {
+		return 0, fmt.Errorf("cron expression %q never fires", expr)
+	}
+	shortest := window
+	for !prev.After(end) {
+		next := sched.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if gap := next.Sub(prev); gap < shortest {
+			shortest = gap
+			if shortest < floor {
+				break
+			}
+		}
+		prev = next
+	}
+	return shortest, nil
+}
+
+func parseCron(expr string) (cron.Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		return nil, fmt.Errorf("invalid cron expression %q: set the timezone separately", expr)
+	}
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	return sched, nil
+}
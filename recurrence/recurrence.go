@@ -0,0 +1,77 @@
+// Package recurrence evaluates iCalendar recurrence rules (RRULE) for tasks
+// and reminders, and cron expressions for schedules.
+package recurrence
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"youdoyou-server/model"
+
+	"github.com/teambition/rrule-go"
+)
+
+// Validate checks that rule is an RRULE without DTSTART, e.g. FREQ=WEEKLY;BYDAY=MO
+func Validate(rule string) error {
+	_, err := parse(rule, time.Now())
+	return err
+}
+
+// Next returns the first occurrence after `after` of rule anchored at start,
+// evaluated in loc. ok is false when the series has ended.
+func Next(rule string, start time.Time, after time.Time, loc *time.Location) (time.Time, bool, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	r, err := parse(rule, start.In(loc))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	next := r.After(after, false)
+	if next.IsZero() {
+		return time.Time{}, false, nil
+	}
+	return next, true, nil
+}
+
+func parse(rule string, start time.Time) (*rrule.RRule, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	opt, err := rrule.StrToROption(rule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule %q: %w", rule, err)
+	}
+	opt.Dtstart = start
+	r, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule %q: %w", rule, err)
+	}
+	return r, nil
+}
+
+// AdvanceTask moves a recurring task to its next occurrence after `after` and
+// clears the reminder of the previous one. It returns false, leaving the task
+// unchanged, when the task does not recur or its series has ended.
+func AdvanceTask(task *model.Task, after time.Time) (bool, error) {
+	if task.RRule == "" || task.DueAt.IsZero() {
+		return false, nil
+	}
+
+	start := task.SeriesStart
+	if start.IsZero() {
+		start = task.DueAt
+	}
+	loc, err := time.LoadLocation(task.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	next, ok, err := Next(task.RRule, start, after, loc)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	task.SeriesStart = start
+	task.DueAt = next
+	task.RemindedAt = time.Time{}
+	return true, nil
+}
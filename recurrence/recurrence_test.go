@@ -0,0 +1,64 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+)
+
+func TestNextInTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Monday 8:00 JST is Sunday 23:00 UTC
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, tokyo)
+
+	next, ok, err := Next("RRULE:FREQ=WEEKLY;BYDAY=MO", start, start, tokyo)
+	if err != nil || !ok {
+		t.Fatalf("Next = %v, %v", ok, err)
+	}
+	if want := start.AddDate(0, 0, 7); !next.Equal(want) {
+		t.Errorf("Next = %s, want %s", next, want)
+	}
+
+	// Evaluated in UTC the same rule means Monday 23:00 UTC, a Tuesday in Tokyo
+	next, _, _ = Next("FREQ=WEEKLY;BYDAY=MO", start, start, time.UTC)
+	if next.In(tokyo).Weekday() != time.Tuesday {
+		t.Errorf("Next in UTC = %s, want Tuesday in Tokyo", next)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("FREQ=DAILY;INTERVAL=2"); err != nil {
+		t.Errorf("Validate = %v", err)
+	}
+	if err := Validate("FREQ=SOMETIMES"); err == nil {
+		t.Error("Validate accepted an invalid rule")
+	}
+}
+
+func TestAdvanceTask(t *testing.T) {
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	task := &model.Task{RRule: "FREQ=DAILY;COUNT=2", DueAt: due, RemindedAt: due, Timezone: "UTC"}
+
+	advanced, err := AdvanceTask(task, due)
+	if err != nil || !advanced {
+		t.Fatalf("AdvanceTask = %v, %v", advanced, err)
+	}
+	if !task.DueAt.Equal(due.AddDate(0, 0, 1)) || !task.SeriesStart.Equal(due) || !task.RemindedAt.IsZero() {
+		t.Errorf("task = %+v, want the next day with the reminder cleared", task)
+	}
+
+	// COUNT=2: the series ends after the second occurrence
+	advanced, err = AdvanceTask(task, task.DueAt)
+	if err != nil || advanced {
+		t.Errorf("AdvanceTask at the end of the series = %v, %v", advanced, err)
+	}
+
+	oneOff := &model.Task{DueAt: due}
+	if advanced, _ := AdvanceTask(oneOff, due); advanced {
+		t.Error("AdvanceTask advanced a task without a rule")
+	}
+}
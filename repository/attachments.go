@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+)
+
+type CloudStorageAttachmentStore struct {
+	bucket *storage.BucketHandle
+}
+
+func NewCloudStorageAttachmentStore(bucket *storage.BucketHandle) AttachmentStore {
+	return &CloudStorageAttachmentStore{bucket: bucket}
+}
+
+func (s *CloudStorageAttachmentStore) PutAttachment(ctx context.Context, path string, contentType string, data []byte) (string, error) {
+	w := s.bucket.Object(path).NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return "", fmt.Errorf("failed to write attachment %s: %w", path, err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to store attachment %s: %w", path, err)
+	}
+	return "gs://" + s.bucket.BucketName() + "/" + path, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"sort"
+
+	"youdoyou-server/model"
+)
+
+// Messages form a tree: every message follows its parent. ParentID is set
+// explicitly when a branch forks (edit / regenerate); otherwise the parent is
+// the previous message in the same branch, so threads written before
+// branching existed are a single root branch.
+
+// ResolveParents returns the effective parent ID of every message
+func ResolveParents(messages []model.ChatMessage) map[string]string {
+	sorted := sortedByCreatedAt(messages)
+	parents := make(map[string]string, len(sorted))
+	lastInBranch := make(map[string]string)
+	for _, msg := range sorted {
+		parent := msg.ParentID
+		if parent == "" {
+			parent = lastInBranch[msg.BranchID]
+		}
+		parents[msg.ID] = parent
+		lastInBranch[msg.BranchID] = msg.ID
+	}
+	return parents
+}
+
+// PathTo returns the conversation ending at messageID, oldest first.
+// It returns nil if the message is not found.
+func PathTo(messages []model.ChatMessage, messageID string) []model.ChatMessage {
+	byID := make(map[string]model.ChatMessage, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+	if _, ok := byID[messageID]; !ok {
+		return nil
+	}
+
+	parents := ResolveParents(messages)
+	var path []model.ChatMessage
+	seen := make(map[string]bool)
+	for id := messageID; id != "" && !seen[id]; id = parents[id] {
+		msg, ok := byID[id]
+		if !ok {
+			break
+		}
+		seen[id] = true
+		path = append(path, msg)
+	}
+
+	// Reverse into chronological order
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// BranchHead returns the latest message of the branch, falling back to the
+// latest root message when the branch has none
+func BranchHead(messages []model.ChatMessage, branchID string) (model.ChatMessage, bool) {
+	sorted := sortedByCreatedAt(messages)
+	for _, want := range []string{branchID, ""} {
+		for i := len(sorted) - 1; i >= 0; i-- {
+			if sorted[i].BranchID == want {
+				return sorted[i], true
+			}
+		}
+	}
+	return model.ChatMessage{}, false
+}
+
+// ActivePath returns the conversation along the given branch, oldest first
+func ActivePath(messages []model.ChatMessage, branchID string) []model.ChatMessage {
+	head, ok := BranchHead(messages, branchID)
+	if !ok {
+		return nil
+	}
+	return PathTo(messages, head.ID)
+}
+
+func sortedByCreatedAt(messages []model.ChatMessage) []model.ChatMessage {
+	sorted := append([]model.ChatMessage(nil), messages...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			// UUID v7 IDs sort by creation time
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+	return sorted
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ChannelDocID is the channelThreads / channelAccounts document ID of a key
+// on a channel ("/" is not allowed in document IDs)
+func ChannelDocID(channel string, key string) string {
+	return channel + "_" + strings.ReplaceAll(key, "/", "-")
+}
+
+type FirestoreChannelRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreChannelRepository(client *firestore.Client) ChannelRepository {
+	return &FirestoreChannelRepository{client: client}
+}
+
+// GetChannelThread returns nil without error when the conversation has no thread yet
+func (r *FirestoreChannelRepository) GetChannelThread(ctx context.Context, channel string, conversationKey string) (*model.ChannelThread, error) {
+	doc, err := r.client.Collection("channelThreads").Doc(ChannelDocID(channel, conversationKey)).Get(ctx)
+	if err != nil {
+		if doc != nil && !doc.Exists() {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get channel thread: %w", err)
+	}
+	return parseChannelThread(doc)
+}
+
+// LinkChannelThread maps link's conversation to link.ThreadID unless it is
+// already mapped, in which case the existing link is returned with false.
+// Messages arriving together on a new conversation thus share one thread.
+func (r *FirestoreChannelRepository) LinkChannelThread(ctx context.Context, link *model.ChannelThread) (*model.ChannelThread, bool, error) {
+	if err := validateDocument(link, "channelThreads"); err != nil {
+		return nil, false, err
+	}
+
+	ref := r.client.Collection("channelThreads").Doc(ChannelDocID(link.Channel, link.ConversationKey))
+	_, err := ref.Create(ctx, link)
+	if status.Code(err) == codes.AlreadyExists {
+		doc, err := ref.Get(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get channel thread: %w", err)
+		}
+		existing, err := parseChannelThread(doc)
+		return existing, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to link channel thread: %w", err)
+	}
+	link.ID = ref.ID
+	return link, true, nil
+}
+
+// UnlinkChannelThread removes the conversation's link, if any
+func (r *FirestoreChannelRepository) UnlinkChannelThread(ctx context.Context, channel string, conversationKey string) error {
+	if _, err := r.client.Collection("channelThreads").Doc(ChannelDocID(channel, conversationKey)).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to unlink channel thread: %w", err)
+	}
+	return nil
+}
+
+// GetChannelAccount returns nil without error when the external user is not linked
+func (r *FirestoreChannelRepository) GetChannelAccount(ctx context.Context, channel string, externalUserID string) (*model.ChannelAccount, error) {
+	doc, err := r.client.Collection("channelAccounts").Doc(ChannelDocID(channel, externalUserID)).Get(ctx)
+	if err != nil {
+		if doc != nil && !doc.Exists() {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get channel account: %w", err)
+	}
+	var account model.ChannelAccount
+	if err := doc.DataTo(&account); err != nil {
+		return nil, fmt.Errorf("failed to parse channel account: %w", err)
+	}
+	account.ID = doc.Ref.ID
+	return &account, nil
+}
+
+func parseChannelThread(doc *firestore.DocumentSnapshot) (*model.ChannelThread, error) {
+	var link model.ChannelThread
+	if err := doc.DataTo(&link); err != nil {
+		return nil, fmt.Errorf("failed to parse channel thread: %w", err)
+	}
+	link.ID = doc.Ref.ID
+	return &link, nil
+}
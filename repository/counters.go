@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// ThreadCounters holds the denormalized counters stored on a thread document
+type ThreadCounters struct {
+	ReplyCount  int
+	UnreadCount int
+}
+
+// CounterDrift describes a thread whose stored counters differ from its messages
+type CounterDrift struct {
+	ThreadID string
+	Stored   ThreadCounters
+	Actual   ThreadCounters
+}
+
+// FirestoreCounterRepository recomputes thread counters from the messages subcollection
+type FirestoreCounterRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreCounterRepository(client *firestore.Client) *FirestoreCounterRepository {
+	return &FirestoreCounterRepository{client: client}
+}
+
+// Recount counts the messages of a thread. replyCount is every message,
+// whatever its role and whoever wrote it (see threads.replyCount in the
+// schema); assistant messages newer than lastReadAt are unread.
+func (r *FirestoreCounterRepository) Recount(ctx context.Context, threadID string, lastReadAt time.Time) (ThreadCounters, error) {
+	iter := r.messages(threadID).Documents(ctx)
+	defer iter.Stop()
+	return countMessages(iter, lastReadAt)
+}
+
+func (r *FirestoreCounterRepository) messages(threadID string) firestore.Query {
+	return r.client.Collection("threads").Doc(threadID).Collection("messages").Select("role", "createdAt")
+}
+
+func countMessages(iter *firestore.DocumentIterator, lastReadAt time.Time) (ThreadCounters, error) {
+	var counters ThreadCounters
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return counters, fmt.Errorf("failed to iterate messages: %w", err)
+		}
+		var msg model.ChatMessage
+		if err := doc.DataTo(&msg); err != nil {
+			return counters, fmt.Errorf("failed to parse message data: %w", err)
+		}
+
+		counters.ReplyCount++
+		if msg.Role == model.RoleAssistant && msg.CreatedAt.After(lastReadAt) {
+			counters.UnreadCount++
+		}
+	}
+	return counters, nil
+}
+
+// FindDrift returns the threads whose stored counters do not match their messages.
+// If threadIDs is empty, every thread is checked.
+func (r *FirestoreCounterRepository) FindDrift(ctx context.Context, threadIDs []string) ([]CounterDrift, error) {
+	var docs []*firestore.DocumentSnapshot
+	if len(threadIDs) == 0 {
+		all, err := r.client.Collection("threads").Documents(ctx).GetAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list threads: %w", err)
+		}
+		docs = all
+	} else {
+		for _, id := range threadIDs {
+			doc, err := r.client.Collection("threads").Doc(id).Get(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get thread %s: %w", id, err)
+			}
+			docs = append(docs, doc)
+		}
+	}
+
+	var drifts []CounterDrift
+	for _, doc := range docs {
+		var thread model.ChatThread
+		if err := doc.DataTo(&thread); err != nil {
+			return nil, fmt.Errorf("failed to parse thread %s: %w", doc.Ref.ID, err)
+		}
+
+		actual, err := r.Recount(ctx, doc.Ref.ID, thread.LastReadAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recount thread %s: %w", doc.Ref.ID, err)
+		}
+
+		stored := ThreadCounters{ReplyCount: thread.ReplyCount, UnreadCount: thread.UnreadCount}
+		if stored != actual {
+			drifts = append(drifts, CounterDrift{ThreadID: doc.Ref.ID, Stored: stored, Actual: actual})
+		}
+	}
+
+	return drifts, nil
+}
+
+// Repair recounts a thread's messages and overwrites its stored counters in
+// one transaction, so a message saved meanwhile is neither lost nor counted
+// twice. It returns the counters now stored.
+func (r *FirestoreCounterRepository) Repair(ctx context.Context, threadID string) (ThreadCounters, error) {
+	threadRef := r.client.Collection("threads").Doc(threadID)
+	var counters ThreadCounters
+
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(threadRef)
+		if err != nil {
+			return fmt.Errorf("failed to get thread: %w", err)
+		}
+		var thread model.ChatThread
+		if err := doc.DataTo(&thread); err != nil {
+			return fmt.Errorf("failed to parse thread: %w", err)
+		}
+
+		iter := tx.Documents(r.messages(threadID))
+		defer iter.Stop()
+		counters, err = countMessages(iter, thread.LastReadAt)
+		if err != nil {
+			return err
+		}
+		if counters == (ThreadCounters{ReplyCount: thread.ReplyCount, UnreadCount: thread.UnreadCount}) {
+			return nil
+		}
+		return tx.Update(threadRef, []firestore.Update{
+			{Path: "replyCount", Value: counters.ReplyCount},
+			{Path: "unreadCount", Value: counters.UnreadCount},
+		})
+	})
+	return counters, err
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+)
+
+// These tests run against the Firestore emulator (make emulators) and are
+// skipped when FIRESTORE_EMULATOR_HOST is not set. They use their own project
+// ID and clear it, so data seeded for development is left alone.
+
+const testProjectID = "demo-youdoyou-repository"
+
+func newTestClient(t *testing.T) *firestore.Client {
+	t.Helper()
+	host := os.Getenv("FIRESTORE_EMULATOR_HOST")
+	if host == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+
+	// Start from an empty database
+	url := fmt.Sprintf("http://%s/emulator/v1/projects/%s/databases/(default)/documents", host, testProjectID)
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to clear emulator: %v", err)
+	}
+	resp.Body.Close()
+
+	client, err := firestore.NewClient(context.Background(), testProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRepairFixesDriftedCounters(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	lastReadAt := time.Now().Add(-time.Hour)
+	threadRef := client.Collection("threads").Doc("t1")
+	if _, err := threadRef.Set(ctx, map[string]any{"userId": "u1", "replyCount": 7, "unreadCount": 5, "lastReadAt": lastReadAt}); err != nil {
+		t.Fatal(err)
+	}
+	for i, msg := range []map[string]any{
+		{"role": "user", "createdAt": lastReadAt.Add(-time.Minute)},
+		{"role": "assistant", "createdAt": lastReadAt.Add(-time.Second)},
+		{"role": "assistant", "createdAt": lastReadAt.Add(time.Minute)},
+	} {
+		if _, err := threadRef.Collection("messages").Doc(fmt.Sprintf("m%d", i)).Set(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counters := NewFirestoreCounterRepository(client)
+	repaired, err := counters.Repair(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ThreadCounters{ReplyCount: 3, UnreadCount: 1}
+	if repaired != want {
+		t.Errorf("Repair = %+v, want %+v", repaired, want)
+	}
+	drifts, err := counters.FindDrift(ctx, []string{"t1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("drift after repair: %+v", drifts)
+	}
+}
+
+func TestRepairKeepsMessagesSavedMeanwhile(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	if _, err := client.Collection("threads").Doc("t1").Set(ctx, map[string]any{"userId": "u1", "lastReadAt": time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	chatRepo := NewFirestoreChatRepository(client)
+	counters := NewFirestoreCounterRepository(client)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := chatRepo.SaveMessage(ctx, &model.ChatMessage{ThreadID: "t1", Role: model.RoleAssistant, Content: fmt.Sprint(i), CreatedAt: time.Now()})
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := counters.Repair(ctx, "t1")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	drifts, err := counters.FindDrift(ctx, []string{"t1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("counters drifted while repairing: %+v", drifts)
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+)
+
+type FirestoreFailedRunRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreFailedRunRepository(client *firestore.Client) FailedRunRepository {
+	return &FirestoreFailedRunRepository{client: client}
+}
+
+// GetFailedRun returns nil without error when the run has never failed
+func (r *FirestoreFailedRunRepository) GetFailedRun(ctx context.Context, id string) (*model.FailedRun, error) {
+	doc, err := r.client.Collection("failedRuns").Doc(id).Get(ctx)
+	if err != nil {
+		if doc != nil && !doc.Exists() {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get failed run: %w", err)
+	}
+	var run model.FailedRun
+	if err := doc.DataTo(&run); err != nil {
+		return nil, fmt.Errorf("failed to parse failed run: %w", err)
+	}
+	run.ID = doc.Ref.ID
+	return &run, nil
+}
+
+func (r *FirestoreFailedRunRepository) SaveFailedRun(ctx context.Context, run *model.FailedRun) error {
+	if err := validateDocument(run, "failedRuns"); err != nil {
+		return err
+	}
+	if _, err := r.client.Collection("failedRuns").Doc(run.ID).Set(ctx, run); err != nil {
+		return fmt.Errorf("failed to save failed run: %w", err)
+	}
+	return nil
+}
+
+// ListDueFailedRuns returns pending runs whose backoff has elapsed, oldest due first.
+// The pending set is small, so the due filter runs in memory instead of
+// needing a composite index.
+func (r *FirestoreFailedRunRepository) ListDueFailedRuns(ctx context.Context, now time.Time, limit int) ([]model.FailedRun, error) {
+	docs, err := r.client.Collection("failedRuns").
+		Where("status", "==", model.FailedRunStatusPending).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed runs: %w", err)
+	}
+
+	var runs []model.FailedRun
+	for _, doc := range docs {
+		var run model.FailedRun
+		if err := doc.DataTo(&run); err != nil {
+			return nil, fmt.Errorf("failed to parse failed run: %w", err)
+		}
+		run.ID = doc.Ref.ID
+		if run.NextAttemptAt.After(now) {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].NextAttemptAt.Before(runs[j].NextAttemptAt) })
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
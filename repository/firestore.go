@@ -3,11 +3,15 @@ package repository
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 
 	"youdoyou-server/model"
 
 	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type FirestoreChatRepository struct {
@@ -25,6 +29,15 @@ func (r *FirestoreChatRepository) GetUnmemorizedMessages(ctx context.Context, th
 		return nil, fmt.Errorf("failed to get thread: %w", err)
 	}
 
+	if thread.ActiveBranchID != "" {
+		// Branched threads need the whole tree to find the active path
+		all, err := r.GetMessages(ctx, threadID)
+		if err != nil {
+			return nil, err
+		}
+		return afterMemorized(ActivePath(all, thread.ActiveBranchID), thread.MemorizedUntil), nil
+	}
+
 	// Query for messages created after memorizedUntil
 	query := r.client.Collection("threads").Doc(threadID).
 		Collection("messages").
@@ -37,6 +50,97 @@ func (r *FirestoreChatRepository) GetUnmemorizedMessages(ctx context.Context, th
 	}
 
 	var messages []model.ChatMessage
+	for _, doc := range docs {
+		var msg model.ChatMessage
+		if err := doc.DataTo(&msg); err != nil {
+			return nil, fmt.Errorf("failed to parse message data: %w", err)
+		}
+		msg.ID = doc.Ref.ID
+		// Root branch only: versions replaced by an edit or regenerate live in other branches
+		if msg.BranchID != "" {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// GetMessagesUpTo returns the unmemorized messages on the path ending at
+// messageID. The target message is always included.
+func (r *FirestoreChatRepository) GetMessagesUpTo(ctx context.Context, threadID string, messageID string) ([]model.ChatMessage, error) {
+	thread, err := r.GetThread(ctx, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread: %w", err)
+	}
+	target, err := r.GetMessage(ctx, threadID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only messages between memorizedUntil and the target can be on the returned
+	// path: a message's ancestors are always older than it
+	docs, err := r.client.Collection("threads").Doc(threadID).
+		Collection("messages").
+		Where("createdAt", ">", thread.MemorizedUntil).
+		Where("createdAt", "<=", target.CreatedAt).
+		OrderBy("createdAt", firestore.Asc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+
+	messages := make([]model.ChatMessage, 0, len(docs)+1)
+	found := false
+	for _, doc := range docs {
+		var msg model.ChatMessage
+		if err := doc.DataTo(&msg); err != nil {
+			return nil, fmt.Errorf("failed to parse message data: %w", err)
+		}
+		msg.ID = doc.Ref.ID
+		found = found || msg.ID == messageID
+		messages = append(messages, msg)
+	}
+	if !found {
+		// The target itself is already memorized
+		messages = append(messages, *target)
+	}
+	return PathTo(messages, messageID), nil
+}
+
+func afterMemorized(messages []model.ChatMessage, memorizedUntil time.Time) []model.ChatMessage {
+	var result []model.ChatMessage
+	for _, msg := range messages {
+		if msg.CreatedAt.After(memorizedUntil) {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+func (r *FirestoreChatRepository) GetMessage(ctx context.Context, threadID string, messageID string) (*model.ChatMessage, error) {
+	doc, err := r.client.Collection("threads").Doc(threadID).Collection("messages").Doc(messageID).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	var msg model.ChatMessage
+	if err := doc.DataTo(&msg); err != nil {
+		return nil, fmt.Errorf("failed to parse message data: %w", err)
+	}
+	msg.ID = doc.Ref.ID
+	return &msg, nil
+}
+
+func (r *FirestoreChatRepository) GetMessages(ctx context.Context, threadID string) ([]model.ChatMessage, error) {
+	docs, err := r.client.Collection("threads").Doc(threadID).
+		Collection("messages").
+		OrderBy("createdAt", firestore.Asc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+
+	messages := make([]model.ChatMessage, 0, len(docs))
 	for _, doc := range docs {
 		var msg model.ChatMessage
 		if err := doc.DataTo(&msg); err != nil {
@@ -63,6 +167,10 @@ func (r *FirestoreChatRepository) GetThread(ctx context.Context, threadID string
 }
 
 func (r *FirestoreChatRepository) SaveMessage(ctx context.Context, message *model.ChatMessage) (string, error) {
+	if err := validateDocument(message, "threads", "messages"); err != nil {
+		return "", err
+	}
+
 	// Generate UUID v7
 	id, err := uuid.NewV7()
 	if err != nil {
@@ -70,11 +178,29 @@ func (r *FirestoreChatRepository) SaveMessage(ctx context.Context, message *mode
 	}
 	idStr := id.String()
 
-	// Use Doc(id).Set instead of Add
-	_, err = r.client.Collection("threads").Doc(message.ThreadID).
-		Collection("messages").Doc(idStr).
-		Set(ctx, message)
+	threadRef := r.client.Collection("threads").Doc(message.ThreadID)
+	msgRef := threadRef.Collection("messages").Doc(idStr)
 
+	// Keep the parent thread's counters in step with the messages subcollection
+	updates := []firestore.Update{
+		{Path: "replyCount", Value: firestore.Increment(1)},
+	}
+	if message.Role == model.RoleAssistant {
+		updates = append(updates, firestore.Update{Path: "unreadCount", Value: firestore.Increment(1)})
+	}
+
+	// Use Doc(id).Set instead of Add, in the same transaction as the counter update
+	message.Counted = true
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Set(msgRef, message); err != nil {
+			return err
+		}
+		return tx.Update(threadRef, updates)
+	})
+	if status.Code(err) == codes.NotFound {
+		// Update fails on a missing thread, and the message is not written either
+		return "", fmt.Errorf("thread %s does not exist: %w", message.ThreadID, err)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -82,7 +208,47 @@ func (r *FirestoreChatRepository) SaveMessage(ctx context.Context, message *mode
 	return idStr, nil
 }
 
+// CountMessage adds a message that a client wrote directly to its thread's
+// counters. Messages that are already counted (everything SaveMessage and
+// RestoreThread write, or a redelivered trigger) are left alone.
+func (r *FirestoreChatRepository) CountMessage(ctx context.Context, threadID string, messageID string) error {
+	threadRef := r.client.Collection("threads").Doc(threadID)
+	msgRef := threadRef.Collection("messages").Doc(messageID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(msgRef)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get message: %w", err)
+		}
+		var msg model.ChatMessage
+		if err := doc.DataTo(&msg); err != nil {
+			return fmt.Errorf("failed to parse message data: %w", err)
+		}
+		if msg.Counted {
+			return nil
+		}
+
+		updates := []firestore.Update{
+			{Path: "replyCount", Value: firestore.Increment(1)},
+		}
+		if msg.Role == model.RoleAssistant {
+			updates = append(updates, firestore.Update{Path: "unreadCount", Value: firestore.Increment(1)})
+		}
+		if err := tx.Update(msgRef, []firestore.Update{{Path: "counted", Value: true}}); err != nil {
+			return err
+		}
+		return tx.Update(threadRef, updates)
+	})
+}
+
 func (r *FirestoreChatRepository) CreateThread(ctx context.Context, thread *model.ChatThread) error {
+	if err := validateDocument(thread, "threads"); err != nil {
+		return err
+	}
+
 	// Generate UUID v7 for thread ID if not set
 	if thread.ID == "" {
 		id, err := uuid.NewV7()
@@ -92,9 +258,11 @@ func (r *FirestoreChatRepository) CreateThread(ctx context.Context, thread *mode
 		thread.ID = id.String()
 	}
 
-	_, err := r.client.Collection("threads").Doc(thread.ID).Set(ctx, map[string]interface{}{
+	data := map[string]interface{}{
 		"userId":         thread.UserID,
 		"firstMessage":   thread.FirstMessage,
+		"title":          thread.Title,
+		"tags":           thread.Tags,
 		"unreadCount":    thread.UnreadCount,
 		"lastReadAt":     thread.LastReadAt,
 		"replyCount":     thread.ReplyCount,
@@ -103,6 +271,181 @@ func (r *FirestoreChatRepository) CreateThread(ctx context.Context, thread *mode
 		"sessionMemory":  thread.SessionMemory,
 		"memorizedUntil": thread.MemorizedUntil,
 		"createdAt":      thread.CreatedAt,
+	}
+	if thread.Timezone != "" {
+		data["timezone"] = thread.Timezone
+	}
+	if thread.Channel != nil {
+		data["channel"] = thread.Channel
+	}
+	_, err := r.client.Collection("threads").Doc(thread.ID).Set(ctx, data)
+	return err
+}
+
+func (r *FirestoreChatRepository) ListThreads(ctx context.Context, userID string, tag string) ([]model.ChatThread, error) {
+	query := r.client.Collection("threads").Where("userId", "==", userID)
+	if tag != "" {
+		query = query.Where("tags", "array-contains", tag)
+	}
+
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query threads: %w", err)
+	}
+
+	threads := make([]model.ChatThread, 0, len(docs))
+	for _, doc := range docs {
+		var thread model.ChatThread
+		if err := doc.DataTo(&thread); err != nil {
+			return nil, fmt.Errorf("failed to parse thread data: %w", err)
+		}
+		thread.ID = doc.Ref.ID
+		threads = append(threads, thread)
+	}
+
+	// Sort in memory (newest first) to avoid a composite index per filter combination
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].CreatedAt.After(threads[j].CreatedAt)
+	})
+
+	return threads, nil
+}
+
+func (r *FirestoreChatRepository) UpdateThreadTitle(ctx context.Context, threadID string, title string, tags []string) error {
+	_, err := r.client.Collection("threads").Doc(threadID).Update(ctx, []firestore.Update{
+		{Path: "title", Value: title},
+		{Path: "tags", Value: tags},
+	})
+	return err
+}
+
+// UpdateMessageStatus records the processing status of a user message.
+// errorCode and retryable are only kept for the error status.
+func (r *FirestoreChatRepository) UpdateMessageStatus(ctx context.Context, threadID string, messageID string, status string, errorCode string, retryable bool) error {
+	updates := []firestore.Update{{Path: "status", Value: status}}
+	if status == model.MessageStatusError {
+		updates = append(updates,
+			firestore.Update{Path: "errorCode", Value: errorCode},
+			firestore.Update{Path: "retryable", Value: retryable},
+		)
+	} else {
+		updates = append(updates,
+			firestore.Update{Path: "errorCode", Value: firestore.Delete},
+			firestore.Update{Path: "retryable", Value: firestore.Delete},
+		)
+	}
+	_, err := r.client.Collection("threads").Doc(threadID).Collection("messages").Doc(messageID).Update(ctx, updates)
+	return err
+}
+
+// RequestCancel asks agent runs on the thread that started before at to stop
+func (r *FirestoreChatRepository) RequestCancel(ctx context.Context, threadID string, at time.Time) error {
+	_, err := r.client.Collection("threads").Doc(threadID).Update(ctx, []firestore.Update{
+		{Path: "cancelRequestedAt", Value: at},
 	})
 	return err
 }
+
+func (r *FirestoreChatRepository) SetActiveBranch(ctx context.Context, threadID string, branchID string) error {
+	_, err := r.client.Collection("threads").Doc(threadID).Update(ctx, []firestore.Update{
+		{Path: "activeBranchId", Value: branchID},
+	})
+	return err
+}
+
+// SetMessageBranch moves a message onto a branch, e.g. when a client posted
+// into a branched thread without setting parentId / branchId
+func (r *FirestoreChatRepository) SetMessageBranch(ctx context.Context, threadID string, messageID string, parentID string, branchID string) error {
+	_, err := r.client.Collection("threads").Doc(threadID).Collection("messages").Doc(messageID).Update(ctx, []firestore.Update{
+		{Path: "parentId", Value: parentID},
+		{Path: "branchId", Value: branchID},
+	})
+	return err
+}
+
+// RestoreThread writes a thread and its messages as-is, keeping their IDs and
+// stored counters. Unlike SaveMessage it does not increment counters.
+// Messages already in the thread are deleted first, along with their search
+// index entries, so the thread ends up exactly as given; index the restored
+// messages with search.Searcher.IndexThread. Messages without an ID get a new
+// UUID v7 (set in messages), and user messages without a source are marked
+// as imported so the trigger does not answer them.
+func (r *FirestoreChatRepository) RestoreThread(ctx context.Context, thread *model.ChatThread, messages []model.ChatMessage) error {
+	if thread.ID == "" {
+		return fmt.Errorf("thread ID is required")
+	}
+	if err := validateDocument(thread, "threads"); err != nil {
+		return err
+	}
+	for i := range messages {
+		if err := validateDocument(&messages[i], "threads", "messages"); err != nil {
+			return fmt.Errorf("message %d: %w", i, err)
+		}
+		if messages[i].ID == "" {
+			id, err := uuid.NewV7()
+			if err != nil {
+				return fmt.Errorf("failed to generate UUID v7: %w", err)
+			}
+			messages[i].ID = id.String()
+		}
+	}
+	threadRef := r.client.Collection("threads").Doc(thread.ID)
+
+	existing, err := threadRef.Collection("messages").DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to list existing messages: %w", err)
+	}
+	if len(existing) > 0 {
+		dw := r.client.BulkWriter(ctx)
+		var deletes []*firestore.BulkWriterJob
+		for _, ref := range existing {
+			job, err := dw.Delete(ref)
+			if err != nil {
+				return fmt.Errorf("failed to enqueue message delete: %w", err)
+			}
+			deletes = append(deletes, job)
+		}
+		dw.End()
+		for _, job := range deletes {
+			if _, err := job.Results(); err != nil {
+				return fmt.Errorf("failed to delete existing messages: %w", err)
+			}
+		}
+	}
+	if err := deleteThreadEntries(ctx, r.client, thread.ID); err != nil {
+		return err
+	}
+
+	bw := r.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+
+	job, err := bw.Set(threadRef, thread)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue thread: %w", err)
+	}
+	jobs = append(jobs, job)
+
+	for i := range messages {
+		msg := messages[i]
+		msg.ThreadID = thread.ID
+		// The thread's stored counters already include the restored messages
+		msg.Counted = true
+		if msg.Role == model.RoleUser && msg.Source == "" {
+			msg.Source = model.MessageSourceImport
+		}
+
+		job, err := bw.Set(threadRef.Collection("messages").Doc(msg.ID), &msg)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue message: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return fmt.Errorf("failed to write thread data: %w", err)
+		}
+	}
+	return nil
+}
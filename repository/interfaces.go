@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"youdoyou-server/model"
 )
@@ -9,9 +10,82 @@ import (
 // ChatRepository - Firestore
 type ChatRepository interface {
 	GetUnmemorizedMessages(ctx context.Context, threadID string) ([]model.ChatMessage, error)
+	GetMessagesUpTo(ctx context.Context, threadID string, messageID string) ([]model.ChatMessage, error)
+	GetMessages(ctx context.Context, threadID string) ([]model.ChatMessage, error)
+	GetMessage(ctx context.Context, threadID string, messageID string) (*model.ChatMessage, error)
 	GetThread(ctx context.Context, threadID string) (*model.ChatThread, error)
 	SaveMessage(ctx context.Context, message *model.ChatMessage) (string, error)
+	CountMessage(ctx context.Context, threadID string, messageID string) error
 	CreateThread(ctx context.Context, thread *model.ChatThread) error
+	ListThreads(ctx context.Context, userID string, tag string) ([]model.ChatThread, error)
+	UpdateThreadTitle(ctx context.Context, threadID string, title string, tags []string) error
+	UpdateMessageStatus(ctx context.Context, threadID string, messageID string, status string, errorCode string, retryable bool) error
+	RequestCancel(ctx context.Context, threadID string, at time.Time) error
+	SetActiveBranch(ctx context.Context, threadID string, branchID string) error
+	SetMessageBranch(ctx context.Context, threadID string, messageID string, parentID string, branchID string) error
+	RestoreThread(ctx context.Context, thread *model.ChatThread, messages []model.ChatMessage) error
+}
+
+// SearchRepository - Firestore token index
+type SearchRepository interface {
+	IndexMessage(ctx context.Context, entry *model.SearchEntry) error
+	FindByToken(ctx context.Context, userID string, token string, limit int) ([]model.SearchEntry, error)
+}
+
+// FailedRunRepository - Firestore dead-letter queue of agent runs
+type FailedRunRepository interface {
+	GetFailedRun(ctx context.Context, id string) (*model.FailedRun, error)
+	SaveFailedRun(ctx context.Context, run *model.FailedRun) error
+	ListDueFailedRuns(ctx context.Context, now time.Time, limit int) ([]model.FailedRun, error)
+}
+
+// ToolPolicyRepository - Firestore tool permission policies
+type ToolPolicyRepository interface {
+	GetToolPolicy(ctx context.Context, id string) (*model.ToolPolicy, error)
+	SaveToolPolicy(ctx context.Context, policy *model.ToolPolicy) error
+}
+
+// TaskRepository - Firestore todo / reminder items
+type TaskRepository interface {
+	SaveTask(ctx context.Context, task *model.Task) error
+	GetTask(ctx context.Context, id string) (*model.Task, error)
+	DeleteTask(ctx context.Context, id string) error
+	ListTasks(ctx context.Context, userID string, includeCompleted bool) ([]model.Task, error)
+	ListDueTasks(ctx context.Context, now time.Time, limit int) ([]model.Task, error)
+	ClaimReminder(ctx context.Context, task *model.Task) (bool, error)
+}
+
+// ScheduleRepository - Firestore scheduled agent runs
+type ScheduleRepository interface {
+	SaveSchedule(ctx context.Context, schedule *model.Schedule) error
+	GetSchedule(ctx context.Context, id string) (*model.Schedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+	ListSchedules(ctx context.Context, userID string) ([]model.Schedule, error)
+	ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]model.Schedule, error)
+	ClaimScheduleRun(ctx context.Context, schedule *model.Schedule, due time.Time) (bool, error)
+}
+
+// NotificationRepository - Firestore push device tokens and per-user notification settings
+type NotificationRepository interface {
+	SaveDeviceToken(ctx context.Context, device *model.DeviceToken) error
+	DeleteDeviceToken(ctx context.Context, userID string, token string) error
+	ListDeviceTokens(ctx context.Context, userID string) ([]model.DeviceToken, error)
+	GetNotificationSettings(ctx context.Context, userID string) (*model.NotificationSettings, error)
+	SaveNotificationSettings(ctx context.Context, settings *model.NotificationSettings) error
+}
+
+// ChannelRepository - Firestore mapping of Slack / LINE conversations and users
+type ChannelRepository interface {
+	GetChannelThread(ctx context.Context, channel string, conversationKey string) (*model.ChannelThread, error)
+	LinkChannelThread(ctx context.Context, link *model.ChannelThread) (*model.ChannelThread, bool, error)
+	UnlinkChannelThread(ctx context.Context, channel string, conversationKey string) error
+	GetChannelAccount(ctx context.Context, channel string, externalUserID string) (*model.ChannelAccount, error)
+}
+
+// AttachmentStore - Cloud Storage files attached to messages
+type AttachmentStore interface {
+	// PutAttachment stores data at path and returns its gs:// URL
+	PutAttachment(ctx context.Context, path string, contentType string, data []byte) (string, error)
 }
 
 // CalendarRepository - Google Calendar
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+)
+
+// DeviceTokenID is the deviceTokens document ID of an FCM token. Tokens are
+// long and opaque, so they are hashed rather than used as IDs directly.
+func DeviceTokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type FirestoreNotificationRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreNotificationRepository(client *firestore.Client) NotificationRepository {
+	return &FirestoreNotificationRepository{client: client}
+}
+
+// SaveDeviceToken registers the token for device.UserID. A token registered
+// by another user before (e.g. after signing out) moves to this user.
+func (r *FirestoreNotificationRepository) SaveDeviceToken(ctx context.Context, device *model.DeviceToken) error {
+	if err := validateDocument(device, "deviceTokens"); err != nil {
+		return err
+	}
+	device.ID = DeviceTokenID(device.Token)
+	if _, err := r.client.Collection("deviceTokens").Doc(device.ID).Set(ctx, device); err != nil {
+		return fmt.Errorf("failed to save device token: %w", err)
+	}
+	return nil
+}
+
+// DeleteDeviceToken removes the token if it belongs to userID
+func (r *FirestoreNotificationRepository) DeleteDeviceToken(ctx context.Context, userID string, token string) error {
+	ref := r.client.Collection("deviceTokens").Doc(DeviceTokenID(token))
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if doc != nil && !doc.Exists() {
+				return nil
+			}
+			return err
+		}
+		var device model.DeviceToken
+		if err := doc.DataTo(&device); err != nil {
+			return fmt.Errorf("failed to parse device token: %w", err)
+		}
+		if device.UserID != userID {
+			return nil
+		}
+		return tx.Delete(ref)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete device token: %w", err)
+	}
+	return nil
+}
+
+func (r *FirestoreNotificationRepository) ListDeviceTokens(ctx context.Context, userID string) ([]model.DeviceToken, error) {
+	docs, err := r.client.Collection("deviceTokens").
+		Where("userId", "==", userID).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query device tokens: %w", err)
+	}
+
+	devices := make([]model.DeviceToken, 0, len(docs))
+	for _, doc := range docs {
+		var device model.DeviceToken
+		if err := doc.DataTo(&device); err != nil {
+			return nil, fmt.Errorf("failed to parse device token: %w", err)
+		}
+		device.ID = doc.Ref.ID
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// GetNotificationSettings returns the default settings (zero value) when the
+// user has not changed them
+func (r *FirestoreNotificationRepository) GetNotificationSettings(ctx context.Context, userID string) (*model.NotificationSettings, error) {
+	doc, err := r.client.Collection("notificationSettings").Doc(userID).Get(ctx)
+	if err != nil {
+		if doc != nil && !doc.Exists() {
+			return &model.NotificationSettings{ID: userID}, nil
+		}
+		return nil, fmt.Errorf("failed to get notification settings: %w", err)
+	}
+	var settings model.NotificationSettings
+	if err := doc.DataTo(&settings); err != nil {
+		return nil, fmt.Errorf("failed to parse notification settings: %w", err)
+	}
+	settings.ID = doc.Ref.ID
+	return &settings, nil
+}
+
+func (r *FirestoreNotificationRepository) SaveNotificationSettings(ctx context.Context, settings *model.NotificationSettings) error {
+	if err := validateDocument(settings, "notificationSettings"); err != nil {
+		return err
+	}
+	if _, err := r.client.Collection("notificationSettings").Doc(settings.ID).Set(ctx, settings); err != nil {
+		return fmt.Errorf("failed to save notification settings: %w", err)
+	}
+	return nil
+}
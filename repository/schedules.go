@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+)
+
+type FirestoreScheduleRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreScheduleRepository(client *firestore.Client) ScheduleRepository {
+	return &FirestoreScheduleRepository{client: client}
+}
+
+// SaveSchedule creates the schedule, or overwrites it when schedule.ID is set
+func (r *FirestoreScheduleRepository) SaveSchedule(ctx context.Context, schedule *model.Schedule) error {
+	if err := validateDocument(schedule, "schedules"); err != nil {
+		return err
+	}
+
+	if schedule.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("failed to generate UUID v7: %w", err)
+		}
+		schedule.ID = id.String()
+	}
+	if _, err := r.client.Collection("schedules").Doc(schedule.ID).Set(ctx, schedule); err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+	return nil
+}
+
+func (r *FirestoreScheduleRepository) GetSchedule(ctx context.Context, id string) (*model.Schedule, error) {
+	doc, err := r.client.Collection("schedules").Doc(id).Get(ctx)
+	if err != nil {
+		if doc != nil && !doc.Exists() {
+			return nil, fmt.Errorf("schedule %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	return parseSchedule(doc)
+}
+
+func (r *FirestoreScheduleRepository) DeleteSchedule(ctx context.Context, id string) error {
+	if _, err := r.client.Collection("schedules").Doc(id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	return nil
+}
+
+// ListSchedules returns the user's schedules, next run first
+func (r *FirestoreScheduleRepository) ListSchedules(ctx context.Context, userID string) ([]model.Schedule, error) {
+	docs, err := r.client.Collection("schedules").
+		Where("userId", "==", userID).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedules: %w", err)
+	}
+
+	schedules, err := parseSchedules(docs)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(schedules, func(i, j int) bool { return schedules[i].NextRunAt.Before(schedules[j].NextRunAt) })
+	return schedules, nil
+}
+
+// ListDueSchedules returns enabled schedules whose next run has passed,
+// oldest first. Like ListDueTasks, the due filter runs in memory.
+func (r *FirestoreScheduleRepository) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]model.Schedule, error) {
+	docs, err := r.client.Collection("schedules").
+		Where("enabled", "==", true).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedules: %w", err)
+	}
+
+	schedules, err := parseSchedules(docs)
+	if err != nil {
+		return nil, err
+	}
+	var due []model.Schedule
+	for _, s := range schedules {
+		if !s.NextRunAt.After(now) {
+			due = append(due, s)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool { return due[i].NextRunAt.Before(due[j].NextRunAt) })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// ClaimScheduleRun saves schedule only if its stored nextRunAt still equals
+// due, so a run dispatched by two overlapping passes is dispatched once.
+// It returns false when another pass got there first.
+func (r *FirestoreScheduleRepository) ClaimScheduleRun(ctx context.Context, schedule *model.Schedule, due time.Time) (bool, error) {
+	if err := validateDocument(schedule, "schedules"); err != nil {
+		return false, err
+	}
+
+	ref := r.client.Collection("schedules").Doc(schedule.ID)
+	var claimed bool
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		claimed = false
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		current, err := parseSchedule(doc)
+		if err != nil {
+			return err
+		}
+		if !current.Enabled || !current.NextRunAt.Equal(due) {
+			return nil
+		}
+		claimed = true
+		return tx.Set(ref, schedule)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim schedule run: %w", err)
+	}
+	return claimed, nil
+}
+
+func parseSchedule(doc *firestore.DocumentSnapshot) (*model.Schedule, error) {
+	var schedule model.Schedule
+	if err := doc.DataTo(&schedule); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule: %w", err)
+	}
+	schedule.ID = doc.Ref.ID
+	return &schedule, nil
+}
+
+func parseSchedules(docs []*firestore.DocumentSnapshot) ([]model.Schedule, error) {
+	schedules := make([]model.Schedule, 0, len(docs))
+	for _, doc := range docs {
+		s, err := parseSchedule(doc)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *s)
+	}
+	return schedules, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+)
+
+type FirestoreSearchRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreSearchRepository(client *firestore.Client) SearchRepository {
+	return &FirestoreSearchRepository{client: client}
+}
+
+// IndexMessage stores the entry under its ID, so re-indexing is idempotent
+func (r *FirestoreSearchRepository) IndexMessage(ctx context.Context, entry *model.SearchEntry) error {
+	_, err := r.client.Collection("searchIndex").Doc(entry.ID).Set(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to index message: %w", err)
+	}
+	return nil
+}
+
+// FindByToken returns up to limit entries of userID's threads containing
+// token, newest first
+func (r *FirestoreSearchRepository) FindByToken(ctx context.Context, userID string, token string, limit int) ([]model.SearchEntry, error) {
+	docs, err := r.client.Collection("searchIndex").
+		Where("userId", "==", userID).
+		Where("tokens", "array-contains", token).
+		OrderBy("createdAt", firestore.Desc).
+		Limit(limit).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query search index: %w", err)
+	}
+
+	entries := make([]model.SearchEntry, 0, len(docs))
+	for _, doc := range docs {
+		var entry model.SearchEntry
+		if err := doc.DataTo(&entry); err != nil {
+			return nil, fmt.Errorf("failed to parse search entry: %w", err)
+		}
+		entry.ID = doc.Ref.ID
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// deleteThreadEntries removes the index entries of a thread's messages
+func deleteThreadEntries(ctx context.Context, client *firestore.Client, threadID string) error {
+	refs, err := client.Collection("searchIndex").Where("threadId", "==", threadID).Documents(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to list search entries: %w", err)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	bw := client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	for _, doc := range refs {
+		job, err := bw.Delete(doc.Ref)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue search entry delete: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return fmt.Errorf("failed to delete search entries: %w", err)
+		}
+	}
+	return nil
+}
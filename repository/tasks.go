@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type FirestoreTaskRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreTaskRepository(client *firestore.Client) TaskRepository {
+	return &FirestoreTaskRepository{client: client}
+}
+
+// SaveTask creates the task, or overwrites it when task.ID is set
+func (r *FirestoreTaskRepository) SaveTask(ctx context.Context, task *model.Task) error {
+	if err := validateDocument(task, "tasks"); err != nil {
+		return err
+	}
+
+	if task.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("failed to generate UUID v7: %w", err)
+		}
+		task.ID = id.String()
+	}
+	if _, err := r.client.Collection("tasks").Doc(task.ID).Set(ctx, task); err != nil {
+		return fmt.Errorf("failed to save task: %w", err)
+	}
+	return nil
+}
+
+func (r *FirestoreTaskRepository) GetTask(ctx context.Context, id string) (*model.Task, error) {
+	doc, err := r.client.Collection("tasks").Doc(id).Get(ctx)
+	if err != nil {
+		if doc != nil && !doc.Exists() {
+			return nil, fmt.Errorf("task %s not found", id)
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	var task model.Task
+	if err := doc.DataTo(&task); err != nil {
+		return nil, fmt.Errorf("failed to parse task: %w", err)
+	}
+	task.ID = doc.Ref.ID
+	return &task, nil
+}
+
+func (r *FirestoreTaskRepository) DeleteTask(ctx context.Context, id string) error {
+	if _, err := r.client.Collection("tasks").Doc(id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
+	}
+	return nil
+}
+
+// ListTasks returns the user's tasks, soonest due first (tasks without a due date last)
+func (r *FirestoreTaskRepository) ListTasks(ctx context.Context, userID string, includeCompleted bool) ([]model.Task, error) {
+	docs, err := r.client.Collection("tasks").
+		Where("userId", "==", userID).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+
+	tasks, err := parseTasks(docs)
+	if err != nil {
+		return nil, err
+	}
+	var result []model.Task
+	for _, t := range tasks {
+		if includeCompleted || !t.Completed {
+			result = append(result, t)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i].DueAt, result[j].DueAt
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		return a.Before(b)
+	})
+	return result, nil
+}
+
+// ListDueTasks returns open tasks whose reminder is due and not yet posted,
+// oldest due first. The query uses the (completed, dueAt) composite index in
+// firebase/firestore.indexes.json.
+func (r *FirestoreTaskRepository) ListDueTasks(ctx context.Context, now time.Time, limit int) ([]model.Task, error) {
+	iter := r.client.Collection("tasks").
+		Where("completed", "==", false).
+		Where("dueAt", "<=", now).
+		OrderBy("dueAt", firestore.Asc).
+		Documents(ctx)
+	defer iter.Stop()
+
+	var due []model.Task
+	for limit <= 0 || len(due) < limit {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tasks: %w", err)
+		}
+		var task model.Task
+		if err := doc.DataTo(&task); err != nil {
+			return nil, fmt.Errorf("failed to parse task: %w", err)
+		}
+		task.ID = doc.Ref.ID
+		// A one-off task stays past due after its reminder until it is completed
+		if task.RemindedAt.Equal(task.DueAt) {
+			continue
+		}
+		due = append(due, task)
+	}
+	return due, nil
+}
+
+// ClaimReminder marks the reminder for the task's current due time as posted,
+// so overlapping passes post it only once. It returns false if the reminder was
+// already claimed, or the task was completed, rescheduled or deleted since it
+// was read.
+func (r *FirestoreTaskRepository) ClaimReminder(ctx context.Context, task *model.Task) (bool, error) {
+	ref := r.client.Collection("tasks").Doc(task.ID)
+	claimed := false
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		claimed = false
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var current model.Task
+		if err := doc.DataTo(&current); err != nil {
+			return fmt.Errorf("failed to parse task: %w", err)
+		}
+		if current.Completed || !current.DueAt.Equal(task.DueAt) || current.RemindedAt.Equal(current.DueAt) {
+			return nil
+		}
+		claimed = true
+		return tx.Update(ref, []firestore.Update{{Path: "remindedAt", Value: current.DueAt}})
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder: %w", err)
+	}
+	return claimed, nil
+}
+
+func parseTasks(docs []*firestore.DocumentSnapshot) ([]model.Task, error) {
+	tasks := make([]model.Task, 0, len(docs))
+	for _, doc := range docs {
+		var task model.Task
+		if err := doc.DataTo(&task); err != nil {
+			return nil, fmt.Errorf("failed to parse task: %w", err)
+		}
+		task.ID = doc.Ref.ID
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"youdoyou-server/model"
+
+	"cloud.google.com/go/firestore"
+)
+
+// UserPolicyID is the toolPolicies document ID of a user's policy
+func UserPolicyID(userID string) string {
+	return "user_" + userID
+}
+
+// ThreadPolicyID is the toolPolicies document ID of a thread's policy
+func ThreadPolicyID(threadID string) string {
+	return "thread_" + threadID
+}
+
+type FirestoreToolPolicyRepository struct {
+	client *firestore.Client
+}
+
+func NewFirestoreToolPolicyRepository(client *firestore.Client) ToolPolicyRepository {
+	return &FirestoreToolPolicyRepository{client: client}
+}
+
+// GetToolPolicy returns nil without error when no policy is set
+func (r *FirestoreToolPolicyRepository) GetToolPolicy(ctx context.Context, id string) (*model.ToolPolicy, error) {
+	doc, err := r.client.Collection("toolPolicies").Doc(id).Get(ctx)
+	if err != nil {
+		if doc != nil && !doc.Exists() {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tool policy: %w", err)
+	}
+	var policy model.ToolPolicy
+	if err := doc.DataTo(&policy); err != nil {
+		return nil, fmt.Errorf("failed to parse tool policy: %w", err)
+	}
+	policy.ID = doc.Ref.ID
+	return &policy, nil
+}
+
+func (r *FirestoreToolPolicyRepository) SaveToolPolicy(ctx context.Context, policy *model.ToolPolicy) error {
+	if err := validateDocument(policy, "toolPolicies"); err != nil {
+		return err
+	}
+	if _, err := r.client.Collection("toolPolicies").Doc(policy.ID).Set(ctx, policy); err != nil {
+		return fmt.Errorf("failed to save tool policy: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"youdoyou-server/schema"
+)
+
+// ErrInvalidDocument is returned when a write would break schema/firestore.yaml
+var ErrInvalidDocument = errors.New("invalid document")
+
+// validateDocument checks enums, required fields and types before a write
+func validateDocument(v any, path ...string) error {
+	s, err := schema.Default()
+	if err != nil {
+		return fmt.Errorf("failed to load schema: %w", err)
+	}
+	if err := s.ValidateValue(v, path...); err != nil {
+		return fmt.Errorf("%w (%s): %v", ErrInvalidDocument, strings.Join(path, "/"), err)
+	}
+	return nil
+}
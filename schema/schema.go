@@ -0,0 +1,240 @@
+// Package schema loads firestore.yaml, the Firestore schema shared by the
+// server and the SwiftUI client, and validates documents against it.
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed firestore.yaml
+var firestoreYAML []byte
+
+type Schema struct {
+	Collections map[string]Collection `yaml:"collections"`
+}
+
+type Collection struct {
+	GoType         string                `yaml:"goType"`
+	Description    string                `yaml:"description"`
+	Fields         []Field               `yaml:"fields"`
+	Subcollections map[string]Collection `yaml:"subcollections"`
+}
+
+type Field struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`
+	Description string   `yaml:"description"`
+	Required    bool     `yaml:"required"`
+	Enum        []string `yaml:"enum"`
+	Items       *Items   `yaml:"items"`
+	Fields      []Field  `yaml:"fields"`
+
+	// Code generation hints, see the header of firestore.yaml
+	GoType     string `yaml:"goType"`
+	GoName     string `yaml:"goName"`
+	GoKind     string `yaml:"goKind"`
+	Omitempty  bool   `yaml:"omitempty"`
+	Pointer    bool   `yaml:"pointer"`
+	EnumPrefix string `yaml:"enumPrefix"`
+}
+
+type Items struct {
+	Type   string  `yaml:"type"`
+	GoType string  `yaml:"goType"`
+	Fields []Field `yaml:"fields"`
+}
+
+var (
+	defaultSchema *Schema
+	defaultErr    error
+	defaultOnce   sync.Once
+)
+
+// Default returns the embedded schema, parsed once
+func Default() (*Schema, error) {
+	defaultOnce.Do(func() {
+		defaultSchema, defaultErr = Load()
+	})
+	return defaultSchema, defaultErr
+}
+
+// Load parses the embedded firestore.yaml
+func Load() (*Schema, error) {
+	return Parse(firestoreYAML)
+}
+
+// Parse parses a schema document
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	return &s, nil
+}
+
+// Collection looks up a collection by path, e.g. Collection("threads", "messages")
+func (s *Schema) Collection(path ...string) (*Collection, bool) {
+	if len(path) == 0 {
+		return nil, false
+	}
+	c, ok := s.Collections[path[0]]
+	for _, name := range path[1:] {
+		if !ok {
+			break
+		}
+		c, ok = c.Subcollections[name]
+	}
+	if !ok {
+		return nil, false
+	}
+	return &c, true
+}
+
+// Validate checks a document's fields against the collection definition.
+// Missing or empty required fields (see isZero), unknown fields, type
+// mismatches and enum violations are reported; every problem found is
+// returned, sorted for stable output.
+func (c *Collection) Validate(doc map[string]any) []error {
+	var errs []error
+	validateFields(c.Fields, doc, "", &errs)
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
+}
+
+// ValidateValue validates a Go value against the collection at path. The value
+// is converted through its json tags, which mirror the firestore tags; the
+// document ID ("id") is not part of the document and is ignored.
+func (s *Schema) ValidateValue(v any, path ...string) error {
+	c, ok := s.Collection(path...)
+	if !ok {
+		return fmt.Errorf("unknown collection: %v", path)
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	delete(doc, "id")
+
+	return errors.Join(c.Validate(doc)...)
+}
+
+func validateFields(fields []Field, doc map[string]any, prefix string, errs *[]error) {
+	byName := make(map[string]Field, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+		if v, ok := doc[f.Name]; f.Required && (!ok || v == nil || isZero(f.Type, v)) {
+			*errs = append(*errs, fmt.Errorf("%s%s: required", prefix, f.Name))
+		}
+	}
+
+	for key, value := range doc {
+		f, ok := byName[key]
+		if !ok {
+			*errs = append(*errs, fmt.Errorf("%s%s: unknown field", prefix, key))
+			continue
+		}
+		validateValue(f.Type, f.Enum, f.Fields, f.Items, value, prefix+key, errs)
+	}
+}
+
+func validateValue(typ string, enum []string, fields []Field, items *Items, value any, path string, errs *[]error) {
+	if value == nil {
+		return
+	}
+
+	switch typ {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			*errs = append(*errs, fmt.Errorf("%s: expected string, got %T", path, value))
+			return
+		}
+		if len(enum) > 0 && !contains(enum, s) {
+			*errs = append(*errs, fmt.Errorf("%s: %q is not one of %v", path, s, enum))
+		}
+	case "number":
+		switch value.(type) {
+		case int, int32, int64, float32, float64:
+		default:
+			*errs = append(*errs, fmt.Errorf("%s: expected number, got %T", path, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			*errs = append(*errs, fmt.Errorf("%s: expected boolean, got %T", path, value))
+		}
+	case "timestamp":
+		switch v := value.(type) {
+		case time.Time:
+		case string:
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				*errs = append(*errs, fmt.Errorf("%s: expected RFC3339 timestamp, got %q", path, v))
+			}
+		default:
+			*errs = append(*errs, fmt.Errorf("%s: expected timestamp, got %T", path, value))
+		}
+	case "map":
+		m, ok := value.(map[string]any)
+		if !ok {
+			*errs = append(*errs, fmt.Errorf("%s: expected map, got %T", path, value))
+			return
+		}
+		if len(fields) == 0 {
+			// Free-form map
+			return
+		}
+		validateFields(fields, m, path+".", errs)
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			*errs = append(*errs, fmt.Errorf("%s: expected array, got %T", path, value))
+			return
+		}
+		if items == nil {
+			return
+		}
+		for i, v := range arr {
+			validateValue(items.Type, nil, items.Fields, nil, v, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+// isZero reports whether a required value is empty: "" for strings and the
+// zero time for timestamps. Numbers, booleans, maps and arrays are never
+// considered empty, since 0, false and {} are meaningful values.
+func isZero(typ string, value any) bool {
+	switch typ {
+	case "string":
+		return value == ""
+	case "timestamp":
+		switch v := value.(type) {
+		case time.Time:
+			return v.IsZero()
+		case string:
+			t, err := time.Parse(time.RFC3339, v)
+			return err == nil && t.IsZero()
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(`
+collections:
+  notes:
+    goType: Note
+    fields:
+      - name: title
+        type: string
+        required: true
+      - name: kind
+        type: string
+        enum: [memo, todo]
+      - name: count
+        type: number
+      - name: createdAt
+        type: timestamp
+        required: true
+      - name: meta
+        type: map
+        fields:
+          - name: source
+            type: string
+      - name: tags
+        type: array
+        items:
+          type: string
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes, ok := s.Collection("notes")
+	if !ok {
+		t.Fatal("notes collection is missing")
+	}
+
+	valid := map[string]any{
+		"title":     "a",
+		"kind":      "memo",
+		"count":     float64(0),
+		"createdAt": time.Now().Format(time.RFC3339),
+		"meta":      map[string]any{"source": "email"},
+		"tags":      []any{"x"},
+	}
+	if errs := notes.Validate(valid); len(errs) > 0 {
+		t.Errorf("Validate(valid) = %v", errs)
+	}
+
+	errs := notes.Validate(map[string]any{
+		"title":     "",
+		"kind":      "idea",
+		"count":     "1",
+		"createdAt": "0001-01-01T00:00:00Z",
+		"meta":      map[string]any{"author": "u1"},
+		"tags":      []any{1},
+		"colour":    "blue",
+	})
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	joined := strings.Join(got, "\n")
+	for _, want := range []string{
+		"title: required",
+		"createdAt: required",
+		`kind: "idea" is not one of [memo todo]`,
+		"count: expected number",
+		"meta.author: unknown field",
+		"tags[0]: expected string",
+		"colour: unknown field",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("errors are missing %q:\n%s", want, joined)
+		}
+	}
+}
+
+func TestValidateValueAgainstEmbeddedSchema(t *testing.T) {
+	s, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type message struct {
+		ID        string    `json:"id"`
+		Role      string    `json:"role"`
+		Content   string    `json:"content"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	if err := s.ValidateValue(message{ID: "m1", Role: "robot", Content: "hi", CreatedAt: time.Now()}, "threads", "messages"); err == nil || !strings.Contains(err.Error(), "role") {
+		t.Errorf("ValidateValue = %v, want a role error", err)
+	}
+	if err := s.ValidateValue(message{}, "nope"); err == nil {
+		t.Error("ValidateValue of an unknown collection succeeded")
+	}
+}
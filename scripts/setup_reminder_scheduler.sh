@@ -0,0 +1,51 @@
+#!/bin/bash
+# scripts/setup_reminder_scheduler.sh
+# Setup Cloud Scheduler job that posts due task reminders (tasks collection)
+
+set -e
+
+PROJECT_ID="${PROJECT_ID:-youdoyou-intelligence}"
+SERVICE_REGION="asia-northeast2"  # Cloud Run service region
+SERVICE_NAME="youdoyou-server"
+JOB_NAME="task-reminders"
+SCHEDULE="${SCHEDULE:-* * * * *}"
+ENDPOINT_PATH="/v1/hooks/reminders"
+
+# Get project number for service account
+PROJECT_NUMBER=$(gcloud projects describe "$PROJECT_ID" --format="value(projectNumber)")
+SERVICE_ACCOUNT="${PROJECT_NUMBER}-compute@developer.gserviceaccount.com"
+SERVICE_URL=$(gcloud run services describe "$SERVICE_NAME" \
+  --region="$SERVICE_REGION" \
+  --project="$PROJECT_ID" \
+  --format="value(status.url)")
+
+echo "========================================"
+echo "Setting up Cloud Scheduler Job"
+echo "========================================"
+echo "Project:         $PROJECT_ID"
+echo "Service Account: $SERVICE_ACCOUNT"
+echo "Job:             $JOB_NAME"
+echo "Schedule:        $SCHEDULE"
+echo "Endpoint:        $SERVICE_URL$ENDPOINT_PATH"
+echo "========================================"
+echo ""
+
+if gcloud scheduler jobs describe "$JOB_NAME" \
+  --location="$SERVICE_REGION" \
+  --project="$PROJECT_ID" &>/dev/null; then
+  COMMAND="update"
+else
+  COMMAND="create"
+fi
+
+gcloud scheduler jobs "$COMMAND" http "$JOB_NAME" \
+  --location="$SERVICE_REGION" \
+  --schedule="$SCHEDULE" \
+  --uri="$SERVICE_URL$ENDPOINT_PATH" \
+  --http-method=POST \
+  --oidc-service-account-email="$SERVICE_ACCOUNT" \
+  --oidc-token-audience="$SERVICE_URL" \
+  --project="$PROJECT_ID"
+
+echo ""
+echo "✅ Scheduler job ${COMMAND}d successfully!"
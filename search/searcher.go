@@ -0,0 +1,212 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+)
+
+const (
+	// candidateLimit bounds how many index entries are scanned per query;
+	// the newest are scanned first
+	candidateLimit = 500
+	snippetRadius  = 40
+)
+
+// Result is a single message hit
+type Result struct {
+	ThreadID    string    `json:"threadId"`
+	ThreadTitle string    `json:"threadTitle"`
+	MessageID   string    `json:"messageId"`
+	Role        string    `json:"role"`
+	Snippet     string    `json:"snippet"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Searcher runs queries against the message index, limited to the caller's threads
+type Searcher struct {
+	searchRepo repository.SearchRepository
+	chatRepo   repository.ChatRepository
+}
+
+func NewSearcher(searchRepo repository.SearchRepository, chatRepo repository.ChatRepository) *Searcher {
+	return &Searcher{
+		searchRepo: searchRepo,
+		chatRepo:   chatRepo,
+	}
+}
+
+// IndexMessage tokenizes a saved message and writes it to the search index
+func (s *Searcher) IndexMessage(ctx context.Context, msg *model.ChatMessage) error {
+	if msg.ID == "" || msg.ThreadID == "" {
+		return fmt.Errorf("message ID and thread ID are required for indexing")
+	}
+	// Entries carry the thread owner so queries only scan the caller's messages
+	thread, err := s.chatRepo.GetThread(ctx, msg.ThreadID)
+	if err != nil {
+		return fmt.Errorf("failed to get thread owner: %w", err)
+	}
+	return s.index(ctx, thread, msg)
+}
+
+// IndexThread indexes messages of the thread, e.g. after RestoreThread
+// replaced them
+func (s *Searcher) IndexThread(ctx context.Context, threadID string, messages []model.ChatMessage) error {
+	thread, err := s.chatRepo.GetThread(ctx, threadID)
+	if err != nil {
+		return fmt.Errorf("failed to get thread owner: %w", err)
+	}
+	for i := range messages {
+		if messages[i].ID == "" {
+			return fmt.Errorf("message ID is required for indexing")
+		}
+		if err := s.index(ctx, thread, &messages[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// index writes one entry per chunk of the message
+func (s *Searcher) index(ctx context.Context, thread *model.ChatThread, msg *model.ChatMessage) error {
+	for i, chunk := range Chunks(msg.Content) {
+		id := msg.ID
+		if i > 0 {
+			id = fmt.Sprintf("%s_%d", msg.ID, i+1)
+		}
+		err := s.searchRepo.IndexMessage(ctx, &model.SearchEntry{
+			ID:        id,
+			MessageID: msg.ID,
+			UserID:    thread.UserID,
+			ThreadID:  thread.ID,
+			Role:      msg.Role,
+			Content:   chunk,
+			Tokens:    Tokenize(chunk),
+			CreatedAt: msg.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search returns the caller's messages containing every token of the query,
+// newest first. Only threads owned by userID are searched; an empty userID
+// finds nothing.
+func (s *Searcher) Search(ctx context.Context, query string, userID string, limit int) ([]Result, error) {
+	tokens := QueryTokens(query)
+	if len(tokens) == 0 || userID == "" {
+		return []Result{}, nil
+	}
+
+	// Query the index with the longest token, it is usually the most selective
+	primary := tokens[0]
+	for _, t := range tokens[1:] {
+		if utf8.RuneCountInString(t) > utf8.RuneCountInString(primary) {
+			primary = t
+		}
+	}
+
+	candidates, err := s.searchRepo.FindByToken(ctx, userID, primary, candidateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	threads := make(map[string]*model.ChatThread)
+	found := make(map[string]bool)
+	results := []Result{}
+	for _, entry := range candidates {
+		messageID := entry.MessageID
+		if messageID == "" {
+			messageID = entry.ID
+		}
+		// Overlapping chunks of one message may both match
+		if found[messageID] || !containsAll(entry.Tokens, tokens) {
+			continue
+		}
+
+		thread, ok := threads[entry.ThreadID]
+		if !ok {
+			// Ownership is checked against the live thread too, in case the entry is stale
+			thread, err = s.chatRepo.GetThread(ctx, entry.ThreadID)
+			if err != nil {
+				thread = nil // deleted thread; skip its stale entries
+			}
+			threads[entry.ThreadID] = thread
+		}
+		if thread == nil || !canView(thread, userID) {
+			continue
+		}
+
+		found[messageID] = true
+		title := thread.Title
+		if title == "" {
+			title = thread.FirstMessage
+		}
+		results = append(results, Result{
+			ThreadID:    entry.ThreadID,
+			ThreadTitle: title,
+			MessageID:   messageID,
+			Role:        entry.Role,
+			Snippet:     snippet(entry.Content, query),
+			CreatedAt:   entry.CreatedAt,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// canView allows only the owner; private and shared threads alike
+func canView(thread *model.ChatThread, userID string) bool {
+	return userID != "" && thread.UserID == userID
+}
+
+func containsAll(have []string, want []string) bool {
+	set := make(map[string]bool, len(have))
+	for _, t := range have {
+		set[t] = true
+	}
+	for _, t := range want {
+		if !set[t] {
+			return false
+		}
+	}
+	return true
+}
+
+// snippet cuts the content around the first occurrence of the query's first word
+func snippet(content string, query string) string {
+	runes := []rune(content)
+	start, end := 0, len(runes)
+
+	needle := strings.ToLower(strings.Fields(query + " ")[0])
+	if idx := strings.Index(strings.ToLower(content), needle); idx >= 0 {
+		pos := utf8.RuneCountInString(strings.ToLower(content)[:idx])
+		start = max(0, pos-snippetRadius)
+	}
+	if end-start > snippetRadius*3 {
+		end = start + snippetRadius*3
+	}
+
+	result := string(runes[start:end])
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(runes) {
+		result += "…"
+	}
+	return strings.ReplaceAll(result, "\n", " ")
+}
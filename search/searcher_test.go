@@ -0,0 +1,115 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/test"
+)
+
+// ownedChatRepository serves threads owned by the given users
+type ownedChatRepository struct {
+	test.MockChatRepository
+	owners map[string]string // thread ID -> user ID
+}
+
+func (r *ownedChatRepository) GetThread(ctx context.Context, threadID string) (*model.ChatThread, error) {
+	owner, ok := r.owners[threadID]
+	if !ok {
+		return nil, fmt.Errorf("thread %s not found", threadID)
+	}
+	return &model.ChatThread{ID: threadID, UserID: owner, Title: threadID}, nil
+}
+
+func newTestSearcher() *Searcher {
+	chatRepo := &ownedChatRepository{owners: map[string]string{"t1": "u1", "t2": "u2"}}
+	return NewSearcher(&test.MockSearchRepository{}, chatRepo)
+}
+
+func TestSearchFindsTheEndOfLongMessages(t *testing.T) {
+	s := newTestSearcher()
+	ctx := context.Background()
+	long := strings.Repeat("今日は晴れて気持ちのいい一日でした。", 100) + "最後に旅行の予約を確認します。"
+
+	if err := s.IndexMessage(ctx, &model.ChatMessage{ID: "m1", ThreadID: "t1", Content: long}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.Search(ctx, "旅行の予約", "u1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].MessageID != "m1" {
+		t.Fatalf("results = %+v, want m1 once", results)
+	}
+	if !strings.Contains(results[0].Snippet, "旅行の予約") {
+		t.Errorf("snippet = %q, want the matching chunk", results[0].Snippet)
+	}
+}
+
+func TestSearchFindsPhrasesAcrossChunkBoundaries(t *testing.T) {
+	s := newTestSearcher()
+	ctx := context.Background()
+	words := make([]string, 200)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i)
+	}
+
+	if err := s.IndexMessage(ctx, &model.ChatMessage{ID: "m1", ThreadID: "t1", Content: strings.Join(words, " ")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i+1 < len(words); i++ {
+		query := words[i] + " " + words[i+1]
+		results, err := s.Search(ctx, query, "u1", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("%q: got %d results, want 1", query, len(results))
+		}
+	}
+}
+
+func TestSearchScansNewestCandidatesFirst(t *testing.T) {
+	s := newTestSearcher()
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range candidateLimit + 10 {
+		msg := &model.ChatMessage{ID: fmt.Sprintf("m%d", i), ThreadID: "t1", Content: "meeting notes", CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if err := s.IndexMessage(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := s.Search(ctx, "meeting", "u1", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("m%d", candidateLimit+9)
+	if len(results) != 3 || results[0].MessageID != want {
+		t.Errorf("results start with %+v, want the newest message %s", results, want)
+	}
+}
+
+func TestSearchOnlyReturnsOwnThreads(t *testing.T) {
+	s := newTestSearcher()
+	ctx := context.Background()
+	if err := s.IndexThread(ctx, "t2", []model.ChatMessage{{ID: "m2", Content: "secret plan"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, userID := range []string{"u1", ""} {
+		results, err := s.Search(ctx, "secret", userID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 0 {
+			t.Errorf("user %q found %+v in another user's thread", userID, results)
+		}
+	}
+}
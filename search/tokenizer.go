@@ -0,0 +1,162 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxTokens caps the number of tokens stored per indexed document
+	MaxTokens = 500
+
+	// A chunk of chunkRunes characters yields at most two tokens per
+	// character (Japanese unigrams and bigrams), so it stays under MaxTokens
+	chunkRunes = MaxTokens / 2
+	// chunkOverlap repeats the end of each chunk at the start of the next, so
+	// words and phrases on a boundary are still found
+	chunkOverlap = 30
+)
+
+// Tokenize splits text into index tokens.
+// Latin letters and digits become lowercase words. Japanese (kanji, hiragana,
+// katakana) has no word boundaries, so each run is indexed as unigrams and
+// bigrams, which lets a query match without a morphological analyzer.
+func Tokenize(text string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(t string) {
+		if t == "" || seen[t] || len(tokens) >= MaxTokens {
+			return
+		}
+		seen[t] = true
+		tokens = append(tokens, t)
+	}
+
+	for _, run := range splitRuns(normalize(text)) {
+		if !run.cjk {
+			add(string(run.chars))
+			continue
+		}
+		for i := range run.chars {
+			add(string(run.chars[i]))
+			if i+1 < len(run.chars) {
+				add(string(run.chars[i : i+2]))
+			}
+		}
+	}
+	return tokens
+}
+
+// Chunks splits text into overlapping pieces that are each indexed as one
+// document, so every part of a long message stays searchable. Chunks end at
+// a separator where possible, so words are not cut in two.
+func Chunks(text string) []string {
+	runes := []rune(text)
+	if len(runes) <= chunkRunes {
+		return []string{text}
+	}
+
+	var chunks []string
+	start := 0
+	for {
+		end := start + chunkRunes
+		if end >= len(runes) {
+			return append(chunks, string(runes[start:]))
+		}
+		for i := end; i > end-chunkOverlap; i-- {
+			if isSeparator(runes[i]) {
+				end = i
+				break
+			}
+		}
+		chunks = append(chunks, string(runes[start:end]))
+		start = end - chunkOverlap
+	}
+}
+
+// QueryTokens splits a search query into the tokens every hit must contain.
+// Japanese runs use bigrams only (unigrams for single characters) so that
+// "会議" does not also match documents that merely contain "会" and "議".
+func QueryTokens(query string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(t string) {
+		if t == "" || seen[t] {
+			return
+		}
+		seen[t] = true
+		tokens = append(tokens, t)
+	}
+
+	for _, run := range splitRuns(normalize(query)) {
+		if !run.cjk || len(run.chars) == 1 {
+			add(string(run.chars))
+			continue
+		}
+		for i := 0; i+1 < len(run.chars); i++ {
+			add(string(run.chars[i : i+2]))
+		}
+	}
+	return tokens
+}
+
+type run struct {
+	chars []rune
+	cjk   bool
+}
+
+func splitRuns(text string) []run {
+	var runs []run
+	var current *run
+
+	for _, r := range text {
+		if isSeparator(r) {
+			current = nil
+			continue
+		}
+		cjk := isCJK(r)
+
+		if current == nil || current.cjk != cjk {
+			runs = append(runs, run{cjk: cjk})
+			current = &runs[len(runs)-1]
+		}
+		current.chars = append(current.chars, r)
+	}
+	return runs
+}
+
+// isSeparator reports whether r splits words: punctuation, spaces, symbols
+func isSeparator(r rune) bool {
+	return !isCJK(r) && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		r == 'ー' // prolonged sound mark is Common, but belongs to katakana words
+}
+
+// normalize lowercases text and folds full-width ASCII and half-width katakana
+// commonly typed in Japanese input.
+func normalize(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range text {
+		switch {
+		case r >= '！' && r <= '～':
+			// Full-width ASCII -> ASCII
+			r -= 0xFEE0
+		case r == '　':
+			r = ' '
+		case r >= 'ｦ' && r <= 'ﾝ':
+			r = halfwidthKatakana[r-'ｦ']
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// halfwidthKatakana maps U+FF66 (ｦ) .. U+FF9D (ﾝ) to full-width katakana.
+// Voiced sound marks are left as separate characters.
+var halfwidthKatakana = []rune("ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン")
@@ -8,6 +8,7 @@ import (
 
 	"youdoyou-server/model"
 	"youdoyou-server/repository"
+	"youdoyou-server/tool"
 
 	"github.com/firebase/genkit/go/ai"
 	"github.com/firebase/genkit/go/genkit"
@@ -18,7 +19,12 @@ type AgentService struct {
 	calendarRepo repository.CalendarRepository
 	notionRepo   repository.NotionRepository
 	genkitClient *genkit.Genkit
-	tools        []ai.Tool
+	toolFactory  *tool.ToolFactory
+	toolPolicies *ToolPolicyService
+	toolExecutor *ToolExecutor
+	router       *WorkflowRouter
+	titleService *TitleService
+	runs         *RunRegistry
 }
 
 func NewAgentService(
@@ -26,20 +32,52 @@ func NewAgentService(
 	calendarRepo repository.CalendarRepository,
 	notionRepo repository.NotionRepository,
 	genkitClient *genkit.Genkit,
-	tools []ai.Tool,
+	toolFactory *tool.ToolFactory,
+	toolPolicies *ToolPolicyService,
+	toolExecutor *ToolExecutor,
+	router *WorkflowRouter,
+	titleService *TitleService,
 ) *AgentService {
 	return &AgentService{
 		chatRepo:     chatRepo,
 		calendarRepo: calendarRepo,
 		notionRepo:   notionRepo,
 		genkitClient: genkitClient,
-		tools:        tools,
+		toolFactory:  toolFactory,
+		toolPolicies: toolPolicies,
+		toolExecutor: toolExecutor,
+		router:       router,
+		titleService: titleService,
+		runs:         NewRunRegistry(),
 	}
 }
 
-func (s *AgentService) Chat(ctx context.Context, threadID string) error {
+// ChatOptions selects which message the agent answers
+type ChatOptions struct {
+	// ReplyTo is the message to answer. Empty means the latest message on the active branch.
+	ReplyTo string
+	// BranchID puts the reply on this branch instead of the branch of the message it answers
+	BranchID string
+	// Retry marks a re-run of a failed run; the user was already told about the failure
+	Retry bool
+	// Initiate starts a turn without a user message: the agent answers this
+	// prompt on the active branch. The prompt itself is not saved.
+	Initiate string
+	// ScheduleID is recorded on the reply of an initiated run
+	ScheduleID string
+}
+
+// initiatePrompt frames the prompt of an initiated run, which nobody is waiting for
+const initiatePrompt = "【定期実行】ユーザーが事前に予約した依頼です。ユーザーはこの場にいないため、質問で止めずに実行して結果を報告してください。\n\n%s"
+
+func (s *AgentService) Chat(ctx context.Context, threadID string, opts ChatOptions) error {
 	log.Printf("ProcessMessage started for thread: %s", threadID)
 
+	// Register the run so Cancel can stop it
+	startedAt := time.Now()
+	ctx, done := s.runs.Register(ctx, threadID)
+	defer done()
+
 	// 1. Get Thread for SessionMemory
 	thread, err := s.chatRepo.GetThread(ctx, threadID)
 	if err != nil {
@@ -50,21 +88,112 @@ func (s *AgentService) Chat(ctx context.Context, threadID string) error {
 		sessionMemory = thread.SessionMemory
 	}
 
-	// 2. Get unmemorized messages (messages after memorizedUntil)
-	history, err := s.chatRepo.GetUnmemorizedMessages(ctx, threadID)
+	// 2. Get unmemorized messages (messages after memorizedUntil) on the branch being answered
+	var history []model.ChatMessage
+	if opts.ReplyTo != "" && opts.Initiate == "" {
+		if thread != nil {
+			if err := s.adoptMessage(ctx, thread, opts.ReplyTo); err != nil {
+				log.Printf("Warning: Failed to move message %s onto the active branch: %v", opts.ReplyTo, err)
+			}
+		}
+		history, err = s.chatRepo.GetMessagesUpTo(ctx, threadID, opts.ReplyTo)
+	} else {
+		history, err = s.chatRepo.GetUnmemorizedMessages(ctx, threadID)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get unmemorized messages: %w", err)
+		err = &RunError{Code: model.ErrorCodeStorageError, Retryable: true, Err: fmt.Errorf("failed to get unmemorized messages: %w", err)}
+		if opts.ReplyTo != "" {
+			target := &model.ChatMessage{ID: opts.ReplyTo, Role: model.RoleUser}
+			return s.recordFailure(ctx, threadID, target, []*model.ChatMessage{target}, opts, err)
+		}
+		return err
 	}
 	log.Printf("Retrieved %d unmemorized messages for thread %s", len(history), threadID)
 
+	// The user messages being answered carry the processing status
+	var target *model.ChatMessage
+	var answering []*model.ChatMessage
+	if opts.Initiate != "" {
+		// Answer the prompt as an unsaved user turn; the reply goes on the active branch
+		history = append(history, model.ChatMessage{
+			ThreadID:  threadID,
+			Role:      model.RoleUser,
+			Content:   fmt.Sprintf(initiatePrompt, opts.Initiate),
+			CreatedAt: startedAt,
+		})
+	} else {
+		if len(history) > 0 {
+			target = &history[len(history)-1]
+		}
+		answering = answeredMessages(history)
+	}
+	s.updateStatus(ctx, threadID, answering, model.MessageStatusProcessing, nil)
+
+	// 3-6. Run the agent loop; tools see who they are running for and what they may do
+	runCtx := toolRunContext(thread, threadID)
+	if s.toolPolicies != nil {
+		runCtx.Permissions, err = s.toolPolicies.Resolve(ctx, thread)
+		if err != nil {
+			log.Printf("Warning: Failed to resolve tool policy for thread %s, running read-only: %v", threadID, err)
+		}
+	}
+	toolCtx := tool.WithRunContext(ctx, runCtx)
+	routing := s.routeTools(ctx, history)
+	log.Printf("Routed thread %s to tools %v (%s: %s)", threadID, routing.Groups, routing.Method, routing.Reason)
+	finalContent, err := s.generateReply(toolCtx, threadID, startedAt, history, sessionMemory, routing.Groups)
+	if err != nil {
+		return s.recordFailure(ctx, threadID, target, answering, opts, err)
+	}
+
+	// 7. Save response to Firestore, following the message it answers
+	responseMsg := &model.ChatMessage{
+		ThreadID:   threadID,
+		Role:       model.RoleAssistant,
+		Content:    finalContent,
+		Routing:    routing,
+		ScheduleID: opts.ScheduleID,
+		CreatedAt:  time.Now(),
+	}
+	responseMsg.ParentID, responseMsg.BranchID = replyLineage(thread, target, opts)
+
+	_, err = s.chatRepo.SaveMessage(ctx, responseMsg)
+	if err != nil {
+		err = &RunError{Code: model.ErrorCodeStorageError, Retryable: true, Err: fmt.Errorf("failed to save response: %w", err)}
+		return s.recordFailure(ctx, threadID, target, answering, opts, err)
+	}
+	s.updateStatus(ctx, threadID, answering, model.MessageStatusCompleted, nil)
+
+	// Show the branch that was just answered
+	if thread != nil && responseMsg.BranchID != thread.ActiveBranchID {
+		if err := s.chatRepo.SetActiveBranch(ctx, threadID, responseMsg.BranchID); err != nil {
+			log.Printf("Warning: Failed to switch thread %s to branch %q: %v", threadID, responseMsg.BranchID, err)
+		}
+	}
+
+	log.Printf("Response saved successfully for thread %s", threadID)
+
+	// 8. Title the thread after its first assistant reply (best effort)
+	if s.titleService != nil && thread != nil && thread.Title == "" {
+		if err := s.titleService.TitleThread(ctx, thread, finalContent); err != nil {
+			log.Printf("Warning: Failed to title thread %s: %v", threadID, err)
+		}
+	}
+
+	return nil
+}
+
+// generateReply runs the agent loop over the history and returns the final answer.
+// It stops between turns and tool calls if the run is cancelled.
+func (s *AgentService) generateReply(ctx context.Context, threadID string, startedAt time.Time, history []model.ChatMessage, sessionMemory string, groups []string) (string, error) {
 	// 3. Build Genkit Messages (System + SessionMemory + History)
-	messages := s.buildHistoryMessages(history, sessionMemory)
+	messages := s.buildHistoryMessages(history, sessionMemory, tool.RunContextFrom(ctx).Timezone)
 
-	// 4. Provide Tools
+	// 4. Provide the routed tools the run's permissions allow
 	// Map map[string]ai.Tool for efficient execution
+	tools := s.toolFactory.CreateToolsByDependencies(groups, tool.RunContextFrom(ctx).Permissions)
 	toolMap := make(map[string]ai.Tool)
 	var toolRefs []ai.ToolRef
-	for _, t := range s.tools {
+	for _, t := range tools {
 		toolMap[t.Name()] = t
 		toolRefs = append(toolRefs, t)
 	}
@@ -72,15 +201,21 @@ func (s *AgentService) Chat(ctx context.Context, threadID string) error {
 	// 5. Look up the model
 	m := genkit.LookupModel(s.genkitClient, "googleai/gemini-3-flash-preview")
 	if m == nil {
-		return fmt.Errorf("model not found")
+		return "", &RunError{Code: model.ErrorCodeModelError, Err: fmt.Errorf("model not found")}
 	}
 
 	// 6. Agent Loop
 	// We will loop until the model stops generating tool calls
 	maxTurns := 5
 	var finalContent string
+	// Text the model wrote alongside tool calls, kept if the run is cancelled
+	var partial string
 
 	for i := 0; i < maxTurns; i++ {
+		if s.cancelled(ctx, threadID, startedAt, true) {
+			return "", cancelledError(partial)
+		}
+
 		log.Printf("Turn %d: Generating...", i)
 		resp, err := genkit.Generate(ctx, s.genkitClient,
 			ai.WithModel(m),
@@ -89,7 +224,10 @@ func (s *AgentService) Chat(ctx context.Context, threadID string) error {
 			ai.WithTools(toolRefs...),
 		)
 		if err != nil {
-			return fmt.Errorf("genkit call failed: %w", err)
+			if s.cancelled(ctx, threadID, startedAt, false) {
+				return "", cancelledError(partial)
+			}
+			return "", classifyError(fmt.Errorf("genkit call failed: %w", err), model.ErrorCodeModelError)
 		}
 
 		// Append the model's response to history
@@ -102,39 +240,17 @@ func (s *AgentService) Chat(ctx context.Context, threadID string) error {
 			break
 		}
 
-		// Handle Tool Calls
-		log.Printf("Turn %d: Model requested %d tools", i, len(toolReqs))
-		var toolParts []*ai.Part
-		for _, req := range toolReqs {
-			t, ok := toolMap[req.Name]
-			if !ok {
-				log.Printf("Tool not found: %s", req.Name)
-				toolParts = append(toolParts, ai.NewToolResponsePart(&ai.ToolResponse{
-					Name:   req.Name,
-					Ref:    req.Ref,
-					Output: fmt.Sprintf("Error: Tool %s not found", req.Name),
-				}))
-				continue
-			}
-
-			// Run Tool
-			log.Printf("Running tool: %s", req.Name)
-			out, err := t.RunRaw(ctx, req.Input)
-			if err != nil {
-				log.Printf("Tool execution failed: %v", err)
-				toolParts = append(toolParts, ai.NewToolResponsePart(&ai.ToolResponse{
-					Name:   req.Name,
-					Ref:    req.Ref,
-					Output: fmt.Sprintf("Error: %v", err),
-				}))
-				continue
-			}
+		if text := resp.Text(); text != "" {
+			partial = text
+		}
 
-			toolParts = append(toolParts, ai.NewToolResponsePart(&ai.ToolResponse{
-				Name:   req.Name,
-				Ref:    req.Ref,
-				Output: out,
-			}))
+		// Handle Tool Calls (independent calls run in parallel, responses stay in request order)
+		log.Printf("Turn %d: Model requested %d tools", i, len(toolReqs))
+		toolParts, ok := s.toolExecutor.Run(ctx, toolMap, toolReqs, func() bool {
+			return s.cancelled(ctx, threadID, startedAt, false)
+		})
+		if !ok {
+			return "", cancelledError(partial)
 		}
 
 		// Append Tool Response Message
@@ -145,35 +261,94 @@ func (s *AgentService) Chat(ctx context.Context, threadID string) error {
 	if finalContent == "" {
 		finalContent = "申し訳ありません、処理を完了できませんでした (Max turns reached)."
 	}
+	return finalContent, nil
+}
 
-	// 7. Save response to Firestore
-	responseMsg := &model.ChatMessage{
-		ThreadID:  threadID,
-		Role:      "assistant",
-		Content:   finalContent,
-		CreatedAt: time.Now(),
+// routeTools picks the tool groups for a reply to the end of history
+func (s *AgentService) routeTools(ctx context.Context, history []model.ChatMessage) *model.ToolRouting {
+	if s.router == nil || len(history) == 0 || history[len(history)-1].Role != model.RoleUser {
+		return &model.ToolRouting{Groups: tool.AllGroups, Method: model.RoutingMethodOff}
 	}
 
-	_, err = s.chatRepo.SaveMessage(ctx, responseMsg)
-	if err != nil {
-		return fmt.Errorf("failed to save response: %w", err)
+	var previous string
+	for i := len(history) - 2; i >= 0; i-- {
+		if history[i].Role == model.RoleAssistant {
+			previous = history[i].Content
+			break
+		}
+	}
+	return s.router.Route(ctx, history[len(history)-1].Content, previous)
+}
+
+// toolRunContext identifies the run to the tools it calls
+func toolRunContext(thread *model.ChatThread, threadID string) tool.RunContext {
+	rc := tool.RunContext{ThreadID: threadID, Timezone: tool.DefaultTimezone}
+	if thread != nil {
+		rc.UserID = thread.UserID
+		if thread.Timezone != "" {
+			rc.Timezone = thread.Timezone
+		}
 	}
+	return rc
+}
 
-	log.Printf("Response saved successfully for thread %s", threadID)
-	return nil
+// replyLineage returns the parentId / branchId for a reply to target.
+// Replies on the target's own branch follow the branch in time order, so
+// messages that arrived during the run stay on the path; only a reply that
+// forks a new branch links to target explicitly.
+func replyLineage(thread *model.ChatThread, target *model.ChatMessage, opts ChatOptions) (string, string) {
+	var branchID string
+	if target != nil {
+		branchID = target.BranchID
+	} else if thread != nil {
+		branchID = thread.ActiveBranchID
+	}
+	if opts.BranchID != "" {
+		branchID = opts.BranchID
+	}
+	var parentID string
+	if target != nil && branchID != target.BranchID {
+		parentID = target.ID
+	}
+	return parentID, branchID
+}
+
+// answeredMessages returns the user messages a reply to the end of history
+// answers: the last message, plus earlier messages still pending because
+// their runs were coalesced into this one
+func answeredMessages(history []model.ChatMessage) []*model.ChatMessage {
+	var result []*model.ChatMessage
+	for i := range history {
+		msg := &history[i]
+		if msg.Role != model.RoleUser {
+			continue
+		}
+		if i == len(history)-1 || msg.Status == model.MessageStatusPending {
+			result = append(result, msg)
+		}
+	}
+	return result
 }
 
-func (s *AgentService) buildHistoryMessages(history []model.ChatMessage, sessionMemory string) []*ai.Message {
+func (s *AgentService) buildHistoryMessages(history []model.ChatMessage, sessionMemory string, timezone string) []*ai.Message {
 	var messages []*ai.Message
 
 	// System Prompt
 	systemPrompt := `あなたは業務自動化アシスタント YouDoYou です。
 ユーザーの業務をサポートするため、以下の能力があります：
 - Notion database へのアクセス（タスク管理）
+- 過去の会話の検索（「以前決めたこと」などを思い出す）
+- やること・リマインダーの管理（期限になるとこのスレッドでお知らせ）
+- 定期実行の管理（「毎朝ニュースをまとめて」など、決まった時間に自動で依頼を実行）
 
 ユーザーの要望に応じて、必要なツールを使用してサポートしてください。
 回答は日本語で、簡潔かつ分かりやすく。`
 
+	// Relative dates ("明日の9時") need the user's current time
+	if loc, err := time.LoadLocation(timezone); err == nil {
+		systemPrompt += fmt.Sprintf("\n\n現在日時: %s (%s)", time.Now().In(loc).Format("2006-01-02 15:04 (Mon)"), timezone)
+	}
+
 	if sessionMemory != "" {
 		systemPrompt = fmt.Sprintf("【これまでの要約】\n%s\n\n%s", sessionMemory, systemPrompt)
 	}
@@ -182,10 +357,11 @@ func (s *AgentService) buildHistoryMessages(history []model.ChatMessage, session
 
 	// History
 	for _, msg := range history {
-		if msg.Role == "user" {
+		if msg.Role == model.RoleUser {
+			// Kept even if answering it failed; it is still what the user asked
 			messages = append(messages, ai.NewUserTextMessage(msg.Content))
-		} else {
-			// Assistant message
+		} else if msg.ErrorCode == "" && msg.Status != model.MessageStatusError {
+			// Assistant message; canned error replies are not something the model said
 			messages = append(messages, ai.NewModelTextMessage(msg.Content))
 		}
 	}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"testing"
+
+	"youdoyou-server/model"
+	"youdoyou-server/tool"
+)
+
+func TestToolRunContext(t *testing.T) {
+	rc := toolRunContext(&model.ChatThread{ID: "t1", UserID: "u1", Timezone: "Europe/Paris"}, "t1")
+	if rc.UserID != "u1" || rc.ThreadID != "t1" || rc.Timezone != "Europe/Paris" {
+		t.Errorf("run context = %+v, want u1/t1 in Europe/Paris", rc)
+	}
+
+	// A missing thread still identifies the run, in the default timezone
+	rc = toolRunContext(nil, "t1")
+	if rc.UserID != "" || rc.ThreadID != "t1" || rc.Timezone != tool.DefaultTimezone {
+		t.Errorf("run context = %+v, want t1 without a user", rc)
+	}
+}
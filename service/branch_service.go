@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/queue"
+	"youdoyou-server/repository"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrMessageNotFound is returned when the message to regenerate or edit is
+// not in the thread
+var ErrMessageNotFound = errors.New("message not found")
+
+// Regenerate queues a new answer to a message on a new branch. messageID may
+// be an assistant reply (its user message is answered again) or a user message.
+// Earlier replies stay on their branches, and the thread switches to the new
+// branch when its reply is saved. Returns the new branch ID.
+func (s *AgentService) Regenerate(ctx context.Context, jobQueue queue.Queue, threadID string, messageID string) (string, error) {
+	msg, err := s.getMessage(ctx, threadID, messageID)
+	if err != nil {
+		return "", err
+	}
+
+	target := msg.ID
+	if msg.Role == model.RoleAssistant {
+		all, err := s.chatRepo.GetMessages(ctx, threadID)
+		if err != nil {
+			return "", err
+		}
+		target = repository.ResolveParents(all)[msg.ID]
+		if target == "" {
+			return "", fmt.Errorf("message %s does not answer any message", messageID)
+		}
+	}
+
+	branchID, err := newBranchID()
+	if err != nil {
+		return "", err
+	}
+	if err := jobQueue.Enqueue(ctx, queue.Job{ThreadID: threadID, MessageID: target, BranchID: branchID}); err != nil {
+		return "", fmt.Errorf("failed to queue regenerate: %w", err)
+	}
+	return branchID, nil
+}
+
+// EditMessage saves an edited copy of a user message on a new branch and
+// makes it active. The original message and its replies stay on their branch.
+// The assistant reply is produced by the Firestore trigger for the new message.
+func (s *AgentService) EditMessage(ctx context.Context, threadID string, messageID string, content string) (*model.ChatMessage, error) {
+	msg, err := s.getMessage(ctx, threadID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Role != model.RoleUser {
+		return nil, fmt.Errorf("only user messages can be edited")
+	}
+
+	all, err := s.chatRepo.GetMessages(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	branchID, err := newBranchID()
+	if err != nil {
+		return nil, err
+	}
+	edited := &model.ChatMessage{
+		ThreadID:    threadID,
+		ParentID:    repository.ResolveParents(all)[msg.ID],
+		BranchID:    branchID,
+		Role:        model.RoleUser,
+		Content:     content,
+		Attachments: msg.Attachments,
+		Status:      model.MessageStatusPending,
+		CreatedAt:   time.Now(),
+	}
+
+	// Switch first so the trigger for the new message already sees its branch as active
+	if err := s.chatRepo.SetActiveBranch(ctx, threadID, branchID); err != nil {
+		return nil, fmt.Errorf("failed to switch branch: %w", err)
+	}
+	id, err := s.chatRepo.SaveMessage(ctx, edited)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save edited message: %w", err)
+	}
+	edited.ID = id
+	return edited, nil
+}
+
+// adoptMessage puts a user message that a client posted without parentId /
+// branchId onto the thread's active branch, after the branch's latest message
+func (s *AgentService) adoptMessage(ctx context.Context, thread *model.ChatThread, messageID string) error {
+	if thread.ActiveBranchID == "" {
+		return nil
+	}
+	msg, err := s.chatRepo.GetMessage(ctx, thread.ID, messageID)
+	if err != nil {
+		return err
+	}
+	if msg.ParentID != "" || msg.BranchID != "" {
+		return nil
+	}
+
+	all, err := s.chatRepo.GetMessages(ctx, thread.ID)
+	if err != nil {
+		return err
+	}
+	var others []model.ChatMessage
+	for _, m := range all {
+		if m.ID != messageID {
+			others = append(others, m)
+		}
+	}
+	head, ok := repository.BranchHead(others, thread.ActiveBranchID)
+	if !ok || head.CreatedAt.After(msg.CreatedAt) {
+		// Older than the branch; it belongs to the root branch
+		return nil
+	}
+	return s.chatRepo.SetMessageBranch(ctx, thread.ID, messageID, head.ID, thread.ActiveBranchID)
+}
+
+func newBranchID() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate branch ID: %w", err)
+	}
+	return id.String(), nil
+}
+
+// getMessage returns ErrMessageNotFound for a message missing from the thread
+func (s *AgentService) getMessage(ctx context.Context, threadID string, messageID string) (*model.ChatMessage, error) {
+	msg, err := s.chatRepo.GetMessage(ctx, threadID, messageID)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+	}
+	return msg, err
+}
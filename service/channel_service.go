@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"youdoyou-server/channel"
+	"youdoyou-server/model"
+	"youdoyou-server/queue"
+	"youdoyou-server/repository"
+
+	"github.com/google/uuid"
+)
+
+// ChannelService brings Slack / LINE messages into threads. Each sender in a
+// platform conversation continues their own thread, so in a shared Slack
+// thread or LINE group nobody's message runs with another user's tools and
+// data. Messages are saved like app messages and queued for the agent.
+// Replies go back through ChannelChatRepository.
+type ChannelService struct {
+	channelRepo repository.ChannelRepository
+	chatRepo    repository.ChatRepository
+	jobQueue    queue.Queue
+}
+
+func NewChannelService(channelRepo repository.ChannelRepository, chatRepo repository.ChatRepository, jobQueue queue.Queue) *ChannelService {
+	return &ChannelService{
+		channelRepo: channelRepo,
+		chatRepo:    chatRepo,
+		jobQueue:    jobQueue,
+	}
+}
+
+// Receive saves msg to its conversation's thread and queues the agent run
+// that answers it. The message is marked with its source, so the Firestore
+// trigger does not queue it a second time. An event delivered again (same
+// event ID) is dropped.
+func (s *ChannelService) Receive(ctx context.Context, msg channel.Message) (err error) {
+	userID, err := s.userID(ctx, msg)
+	if err != nil {
+		return err
+	}
+	thread, err := s.thread(ctx, msg, userID)
+	if err != nil {
+		return err
+	}
+
+	if msg.EventID != "" {
+		key := "event:" + msg.EventID
+		_, created, err := s.channelRepo.LinkChannelThread(ctx, &model.ChannelThread{
+			Channel:         msg.Channel,
+			ConversationKey: key,
+			ThreadID:        thread.ID,
+			CreatedAt:       time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if !created {
+			log.Printf("%s event %s was already received in thread %s", msg.Channel, msg.EventID, thread.ID)
+			return nil
+		}
+		// Let the platform's redelivery try again if this attempt fails
+		defer func() {
+			if err == nil {
+				return
+			}
+			if unlinkErr := s.channelRepo.UnlinkChannelThread(context.WithoutCancel(ctx), msg.Channel, key); unlinkErr != nil {
+				log.Printf("Warning: Failed to unlink %s event %s: %v", msg.Channel, msg.EventID, unlinkErr)
+			}
+		}()
+	}
+
+	message := &model.ChatMessage{
+		ThreadID:  thread.ID,
+		BranchID:  thread.ActiveBranchID,
+		Role:      model.RoleUser,
+		Content:   msg.Text,
+		Status:    model.MessageStatusPending,
+		Source:    msg.Channel,
+		CreatedAt: msg.SentAt,
+	}
+	messageID, err := s.chatRepo.SaveMessage(ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to save %s message: %w", msg.Channel, err)
+	}
+
+	if err := s.jobQueue.Enqueue(ctx, queue.Job{ThreadID: thread.ID, MessageID: messageID}); err != nil {
+		return fmt.Errorf("failed to enqueue agent job: %w", err)
+	}
+	return nil
+}
+
+// userID returns the youdoyou user linked to the sender in channelAccounts.
+// Messages from senders nobody linked are rejected with ErrUnknownSender.
+func (s *ChannelService) userID(ctx context.Context, msg channel.Message) (string, error) {
+	account, err := s.channelRepo.GetChannelAccount(ctx, msg.Channel, msg.UserID)
+	if err != nil {
+		return "", err
+	}
+	if account == nil {
+		return "", fmt.Errorf("%w: %s user %s", ErrUnknownSender, msg.Channel, msg.UserID)
+	}
+	return account.UserID, nil
+}
+
+// thread returns the sender's thread in the conversation, creating it for a
+// new conversation
+func (s *ChannelService) thread(ctx context.Context, msg channel.Message, userID string) (*model.ChatThread, error) {
+	key := conversationKey(msg, userID)
+	link, err := s.channelRepo.GetChannelThread(ctx, msg.Channel, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if link == nil {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate UUID v7: %w", err)
+		}
+		link, _, err = s.channelRepo.LinkChannelThread(ctx, &model.ChannelThread{
+			Channel:         msg.Channel,
+			ConversationKey: key,
+			ThreadID:        id.String(),
+			CreatedAt:       time.Now(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if thread, err := s.chatRepo.GetThread(ctx, link.ThreadID); err == nil {
+		if thread.UserID != userID {
+			return nil, fmt.Errorf("%w: thread %s belongs to another user", ErrUnknownSender, thread.ID)
+		}
+		return thread, nil
+	}
+
+	// New conversation, or its thread was deleted: (re)create it under the linked ID
+	reply := msg.Reply
+	thread := &model.ChatThread{
+		ID:           link.ThreadID,
+		UserID:       userID,
+		FirstMessage: msg.Text,
+		Channel:      &reply,
+		LastReadAt:   msg.SentAt,
+		CreatedAt:    msg.SentAt,
+	}
+	if err := s.chatRepo.CreateThread(ctx, thread); err != nil {
+		return nil, fmt.Errorf("failed to create thread: %w", err)
+	}
+	log.Printf("Created thread %s for %s conversation %s", thread.ID, msg.Channel, key)
+	return thread, nil
+}
+
+// conversationKey keys the sender's thread in the platform conversation
+func conversationKey(msg channel.Message, userID string) string {
+	return msg.ConversationKey + ":" + userID
+}
+
+// ChannelChatRepository posts each assistant message it saves to the Slack /
+// LINE conversation of its thread, whichever service saved it (agent replies,
+// error replies, reminders). A post that fails is logged; the message is
+// saved either way.
+type ChannelChatRepository struct {
+	repository.ChatRepository
+	outbound map[string]channel.Outbound
+}
+
+// NewChannelChatRepository wraps chatRepo. outbound holds the API client of
+// each configured channel, keyed by model.Channel*.
+func NewChannelChatRepository(chatRepo repository.ChatRepository, outbound map[string]channel.Outbound) repository.ChatRepository {
+	return &ChannelChatRepository{
+		ChatRepository: chatRepo,
+		outbound:       outbound,
+	}
+}
+
+func (r *ChannelChatRepository) SaveMessage(ctx context.Context, message *model.ChatMessage) (string, error) {
+	id, err := r.ChatRepository.SaveMessage(ctx, message)
+	if err != nil || message.Role != model.RoleAssistant {
+		return id, err
+	}
+
+	// The message is stored; a run that just timed out still posts it
+	ctx = context.WithoutCancel(ctx)
+	thread, err := r.ChatRepository.GetThread(ctx, message.ThreadID)
+	if err != nil {
+		log.Printf("Warning: Failed to get thread %s to post message %s: %v", message.ThreadID, id, err)
+		return id, nil
+	}
+	if thread.Channel == nil {
+		return id, nil
+	}
+	out, ok := r.outbound[thread.Channel.Channel]
+	if !ok {
+		log.Printf("Warning: Channel %s is not configured; message %s of thread %s is not posted", thread.Channel.Channel, id, thread.ID)
+		return id, nil
+	}
+	if err := out.Post(ctx, *thread.Channel, message.Content); err != nil {
+		log.Printf("Warning: Failed to post message %s of thread %s to %s: %v", id, thread.ID, thread.Channel.Channel, err)
+	}
+	return id, nil
+}
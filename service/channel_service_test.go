@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"youdoyou-server/channel"
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+	"youdoyou-server/test"
+)
+
+// storeChatRepository keeps the threads and messages it is given
+type storeChatRepository struct {
+	test.MockChatRepository
+	threads  map[string]*model.ChatThread
+	messages []model.ChatMessage
+}
+
+func (r *storeChatRepository) GetThread(ctx context.Context, threadID string) (*model.ChatThread, error) {
+	thread, ok := r.threads[threadID]
+	if !ok {
+		return nil, fmt.Errorf("thread %s not found", threadID)
+	}
+	return thread, nil
+}
+
+func (r *storeChatRepository) CreateThread(ctx context.Context, thread *model.ChatThread) error {
+	if r.threads == nil {
+		r.threads = make(map[string]*model.ChatThread)
+	}
+	r.threads[thread.ID] = thread
+	return nil
+}
+
+func (r *storeChatRepository) SaveMessage(ctx context.Context, message *model.ChatMessage) (string, error) {
+	r.messages = append(r.messages, *message)
+	return fmt.Sprintf("m%d", len(r.messages)), nil
+}
+
+func slackMessage(eventID string) channel.Message {
+	return channel.Message{
+		Channel:         model.ChannelSlack,
+		ConversationKey: "T1:C1:1",
+		UserID:          "T1:U1",
+		Text:            "hi",
+		Reply:           model.ChannelRef{Channel: model.ChannelSlack, Target: "C1", ThreadKey: "1"},
+		SentAt:          time.Unix(1700000000, 0),
+		EventID:         eventID,
+	}
+}
+
+func newChannelTest() (*ChannelService, *storeChatRepository, *test.FakeQueue) {
+	channelRepo := &test.MockChannelRepository{Accounts: map[string]model.ChannelAccount{
+		repository.ChannelDocID(model.ChannelSlack, "T1:U1"): {UserID: "u1"},
+	}}
+	chatRepo := &storeChatRepository{}
+	jobQueue := &test.FakeQueue{}
+	return NewChannelService(channelRepo, chatRepo, jobQueue), chatRepo, jobQueue
+}
+
+func TestReceiveCreatesThreadAndQueuesRun(t *testing.T) {
+	svc, chatRepo, jobQueue := newChannelTest()
+
+	if err := svc.Receive(context.Background(), slackMessage("Ev1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chatRepo.threads) != 1 || len(chatRepo.messages) != 1 || len(jobQueue.Jobs) != 1 {
+		t.Fatalf("threads=%d messages=%d jobs=%d, want one of each", len(chatRepo.threads), len(chatRepo.messages), len(jobQueue.Jobs))
+	}
+	thread := chatRepo.threads[jobQueue.Jobs[0].ThreadID]
+	if thread == nil || thread.UserID != "u1" || thread.Channel == nil || thread.Channel.Target != "C1" {
+		t.Errorf("thread = %+v, want u1's thread replying to C1", thread)
+	}
+	if msg := chatRepo.messages[0]; msg.Source != model.ChannelSlack || msg.Role != model.RoleUser {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestReceiveDropsRedeliveredEvent(t *testing.T) {
+	svc, chatRepo, jobQueue := newChannelTest()
+	ctx := context.Background()
+
+	for range 2 {
+		if err := svc.Receive(ctx, slackMessage("Ev1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := svc.Receive(ctx, slackMessage("Ev2")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chatRepo.messages) != 2 || len(jobQueue.Jobs) != 2 {
+		t.Errorf("messages=%d jobs=%d, want 2 each (Ev1 once, Ev2 once)", len(chatRepo.messages), len(jobQueue.Jobs))
+	}
+	if len(chatRepo.threads) != 1 {
+		t.Errorf("got %d threads, want the conversation's one", len(chatRepo.threads))
+	}
+}
+
+func TestReceiveRejectsUnlinkedSender(t *testing.T) {
+	svc, chatRepo, jobQueue := newChannelTest()
+	msg := slackMessage("Ev1")
+	msg.UserID = "T1:U2"
+
+	if err := svc.Receive(context.Background(), msg); !errors.Is(err, ErrUnknownSender) {
+		t.Fatalf("err = %v, want ErrUnknownSender", err)
+	}
+	if len(chatRepo.threads) != 0 || len(chatRepo.messages) != 0 || len(jobQueue.Jobs) != 0 {
+		t.Error("an unlinked sender's message must not be saved or queued")
+	}
+}
+
+func TestChannelChatRepositoryPostsReplies(t *testing.T) {
+	slack := model.ChannelRef{Channel: model.ChannelSlack, Target: "C1", ThreadKey: "1"}
+	line := model.ChannelRef{Channel: model.ChannelLine, Target: "U1"}
+	cases := []struct {
+		name    string
+		thread  *model.ChatThread
+		message *model.ChatMessage
+		posted  bool
+	}{
+		{"assistant reply", &model.ChatThread{ID: "t1", Channel: &slack}, assistantReply("t1", "done"), true},
+		{"user message", &model.ChatThread{ID: "t1", Channel: &slack}, &model.ChatMessage{ThreadID: "t1", Role: model.RoleUser, Content: "hi"}, false},
+		{"app thread", &model.ChatThread{ID: "t1"}, assistantReply("t1", "done"), false},
+		{"unconfigured channel", &model.ChatThread{ID: "t1", Channel: &line}, assistantReply("t1", "done"), false},
+		{"missing thread", &model.ChatThread{ID: "other", Channel: &slack}, assistantReply("t1", "done"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			chatRepo := &storeChatRepository{threads: map[string]*model.ChatThread{c.thread.ID: c.thread}}
+			out := &test.FakeOutbound{}
+			repo := NewChannelChatRepository(chatRepo, map[string]channel.Outbound{model.ChannelSlack: out})
+
+			id, err := repo.SaveMessage(context.Background(), c.message)
+			if err != nil || id != "m1" {
+				t.Fatalf("SaveMessage = %q, %v; want the stored ID", id, err)
+			}
+			if !c.posted {
+				if len(out.Posts) != 0 {
+					t.Errorf("posted %+v, want nothing", out.Posts)
+				}
+				return
+			}
+			if len(out.Posts) != 1 || out.Posts[0].To != slack || out.Posts[0].Text != "done" {
+				t.Errorf("posts = %+v, want the reply in the Slack thread", out.Posts)
+			}
+		})
+	}
+}
+
+func TestChannelChatRepositoryKeepsMessageWhenPostFails(t *testing.T) {
+	slack := model.ChannelRef{Channel: model.ChannelSlack, Target: "C1"}
+	chatRepo := &storeChatRepository{threads: map[string]*model.ChatThread{"t1": {ID: "t1", Channel: &slack}}}
+	out := &test.FakeOutbound{Err: errors.New("slack is down")}
+	repo := NewChannelChatRepository(chatRepo, map[string]channel.Outbound{model.ChannelSlack: out})
+
+	id, err := repo.SaveMessage(context.Background(), assistantReply("t1", "done"))
+	if err != nil || id != "m1" {
+		t.Fatalf("SaveMessage = %q, %v; want the stored ID and no error", id, err)
+	}
+	if len(chatRepo.messages) != 1 {
+		t.Errorf("stored %d messages, want 1", len(chatRepo.messages))
+	}
+}
+
+func TestReceiveKeepsEachSenderInTheirOwnThread(t *testing.T) {
+	svc, chatRepo, jobQueue := newChannelTest()
+	svc.channelRepo.(*test.MockChannelRepository).Accounts[repository.ChannelDocID(model.ChannelSlack, "T1:U2")] = model.ChannelAccount{UserID: "u2"}
+	ctx := context.Background()
+
+	if err := svc.Receive(ctx, slackMessage("Ev1")); err != nil {
+		t.Fatal(err)
+	}
+	other := slackMessage("Ev2")
+	other.UserID = "T1:U2"
+	if err := svc.Receive(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobQueue.Jobs) != 2 || jobQueue.Jobs[0].ThreadID == jobQueue.Jobs[1].ThreadID {
+		t.Fatalf("jobs = %+v, want one run in each sender's thread", jobQueue.Jobs)
+	}
+	for i, want := range []string{"u1", "u2"} {
+		if thread := chatRepo.threads[jobQueue.Jobs[i].ThreadID]; thread.UserID != want {
+			t.Errorf("run %d is in %s's thread, want %s's", i, thread.UserID, want)
+		}
+	}
+}
+
+func TestReceiveRejectsThreadOfAnotherUser(t *testing.T) {
+	svc, chatRepo, jobQueue := newChannelTest()
+	ctx := context.Background()
+	// u1's conversation link points at u2's thread
+	if _, _, err := svc.channelRepo.LinkChannelThread(ctx, &model.ChannelThread{
+		Channel:         model.ChannelSlack,
+		ConversationKey: "T1:C1:1:u1",
+		ThreadID:        "t2",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	chatRepo.threads = map[string]*model.ChatThread{"t2": {ID: "t2", UserID: "u2"}}
+
+	if err := svc.Receive(ctx, slackMessage("Ev1")); !errors.Is(err, ErrUnknownSender) {
+		t.Fatalf("err = %v, want ErrUnknownSender", err)
+	}
+	if len(chatRepo.messages) != 0 || len(jobQueue.Jobs) != 0 {
+		t.Error("a message must not go into another user's thread")
+	}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"youdoyou-server/email"
+	"youdoyou-server/model"
+	"youdoyou-server/queue"
+	"youdoyou-server/repository"
+
+	"github.com/google/uuid"
+)
+
+// maxEmailText limits the email body given to the agent (in runes)
+const maxEmailText = 20000
+
+// emailInstruction is what the agent is asked to do with a forwarded email
+const emailInstruction = "このメールを要約し、対応が必要なタスクを抽出してください。期限があるものは期限も添えてください。"
+
+// ErrUnknownSender is returned for mail or a Slack / LINE message from a
+// sender no user is linked to in channelAccounts, or who does not own the
+// thread the message would continue
+var ErrUnknownSender = errors.New("sender is not linked to a user")
+
+// ErrUnverifiedSender is returned for mail whose From domain passed neither
+// SPF nor DKIM, so the sender may be forged
+var ErrUnverifiedSender = errors.New("sender is not verified by SPF or DKIM")
+
+// EmailService turns forwarded emails into threads. Each email starts a new
+// thread whose first message holds the email (attachments stored in Cloud
+// Storage), and the agent is queued to summarize it and extract tasks. Email
+// text is written by whoever sent the mail, so the thread is read-only.
+type EmailService struct {
+	channelRepo repository.ChannelRepository
+	chatRepo    repository.ChatRepository
+	policyRepo  repository.ToolPolicyRepository
+	attachments repository.AttachmentStore
+	jobQueue    queue.Queue
+}
+
+func NewEmailService(channelRepo repository.ChannelRepository, chatRepo repository.ChatRepository, policyRepo repository.ToolPolicyRepository, attachments repository.AttachmentStore, jobQueue queue.Queue) *EmailService {
+	return &EmailService{
+		channelRepo: channelRepo,
+		chatRepo:    chatRepo,
+		policyRepo:  policyRepo,
+		attachments: attachments,
+		jobQueue:    jobQueue,
+	}
+}
+
+// Receive creates the thread of e and queues the agent run that answers it.
+// The sender must be linked to a user in channelAccounts (email_<address>),
+// and e.Auth must verify the From address. An email delivered again (same Message-ID) returns its existing thread.
+func (s *EmailService) Receive(ctx context.Context, e *email.Email) (string, error) {
+	if e.From == nil {
+		return "", fmt.Errorf("%w: no From address", ErrUnknownSender)
+	}
+	address := strings.ToLower(e.From.Address)
+	if !e.Auth.Verifies(e.From) {
+		return "", fmt.Errorf("%w: %s", ErrUnverifiedSender, address)
+	}
+	account, err := s.channelRepo.GetChannelAccount(ctx, model.ChannelEmail, address)
+	if err != nil {
+		return "", err
+	}
+	if account == nil {
+		return "", fmt.Errorf("%w: %s", ErrUnknownSender, address)
+	}
+
+	now := time.Now()
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate UUID v7: %w", err)
+	}
+	threadID := id.String()
+	if e.MessageID != "" {
+		link, created, err := s.channelRepo.LinkChannelThread(ctx, &model.ChannelThread{
+			Channel:         model.ChannelEmail,
+			ConversationKey: e.MessageID,
+			ThreadID:        threadID,
+			CreatedAt:       now,
+		})
+		if err != nil {
+			return "", err
+		}
+		threadID = link.ThreadID
+		if !created {
+			// Redelivery: done unless the earlier attempt failed before saving the email
+			if thread, err := s.chatRepo.GetThread(ctx, threadID); err == nil && thread.ReplyCount > 0 {
+				log.Printf("Email %s was already received as thread %s", e.MessageID, threadID)
+				return threadID, nil
+			}
+		}
+	}
+
+	attachments, err := s.storeAttachments(ctx, threadID, e.Attachments)
+	if err != nil {
+		return "", err
+	}
+	content := emailContent(e) + "\n\n---\n" + emailInstruction
+
+	// Before the thread exists, so no run on it can have write tools
+	policy := &model.ToolPolicy{ID: repository.ThreadPolicyID(threadID), ReadOnly: true, UpdatedAt: now}
+	if err := s.policyRepo.SaveToolPolicy(ctx, policy); err != nil {
+		return "", err
+	}
+
+	thread := &model.ChatThread{
+		ID:           threadID,
+		UserID:       account.UserID,
+		FirstMessage: content,
+		LastReadAt:   now,
+		CreatedAt:    now,
+	}
+	if err := s.chatRepo.CreateThread(ctx, thread); err != nil {
+		return "", fmt.Errorf("failed to create thread: %w", err)
+	}
+
+	message := &model.ChatMessage{
+		ThreadID:    threadID,
+		Role:        model.RoleUser,
+		Content:     content,
+		Status:      model.MessageStatusPending,
+		Source:      model.MessageSourceEmail,
+		Attachments: attachments,
+		CreatedAt:   now,
+	}
+	messageID, err := s.chatRepo.SaveMessage(ctx, message)
+	if err != nil {
+		return "", fmt.Errorf("failed to save email message: %w", err)
+	}
+
+	if err := s.jobQueue.Enqueue(ctx, queue.Job{ThreadID: threadID, MessageID: messageID}); err != nil {
+		return "", fmt.Errorf("failed to enqueue agent job: %w", err)
+	}
+	log.Printf("Created thread %s for email from %s (%d attachments)", threadID, address, len(attachments))
+	return threadID, nil
+}
+
+// storeAttachments uploads the files under threads/<threadID>/email/
+func (s *EmailService) storeAttachments(ctx context.Context, threadID string, files []email.Attachment) ([]model.Attachment, error) {
+	attachments := make([]model.Attachment, 0, len(files))
+	for i, file := range files {
+		name := path.Base(strings.ReplaceAll(file.Name, "\\", "/"))
+		objectPath := fmt.Sprintf("threads/%s/email/%d-%s", threadID, i+1, name)
+		url, err := s.attachments.PutAttachment(ctx, objectPath, file.ContentType, file.Data)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, model.Attachment{
+			Type:     attachmentType(file.ContentType),
+			URL:      url,
+			MimeType: file.ContentType,
+			Name:     name,
+			Size:     int64(len(file.Data)),
+		})
+	}
+	return attachments, nil
+}
+
+func attachmentType(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return model.AttachmentTypeImage
+	case strings.HasPrefix(contentType, "text/"):
+		return model.AttachmentTypeText
+	case strings.HasPrefix(contentType, "audio/"):
+		return model.AttachmentTypeAudio
+	case strings.HasPrefix(contentType, "video/"):
+		return model.AttachmentTypeVideo
+	default:
+		return model.AttachmentTypeDocument
+	}
+}
+
+// emailContent renders the email as the text of a user message
+func emailContent(e *email.Email) string {
+	var b strings.Builder
+	b.WriteString("件名: " + e.Subject + "\n")
+	// mail.Address.String would MIME-encode a Japanese name
+	from := e.From.Address
+	if e.From.Name != "" {
+		from = e.From.Name + " <" + from + ">"
+	}
+	b.WriteString("差出人: " + from + "\n")
+	if !e.Date.IsZero() {
+		b.WriteString("日時: " + e.Date.Format("2006-01-02 15:04 -0700") + "\n")
+	}
+	if len(e.Attachments) > 0 {
+		names := make([]string, len(e.Attachments))
+		for i, a := range e.Attachments {
+			names[i] = a.Name
+		}
+		b.WriteString("添付: " + strings.Join(names, ", ") + "\n")
+	}
+
+	text := e.Text
+	if runes := []rune(text); len(runes) > maxEmailText {
+		text = string(runes[:maxEmailText]) + "\n…(以下省略)"
+	}
+	b.WriteString("\n" + text)
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"testing"
+
+	"youdoyou-server/email"
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+	"youdoyou-server/test"
+)
+
+func newEmailTest() (*EmailService, *storeChatRepository, *test.MockToolPolicyRepository, *test.FakeQueue) {
+	channelRepo := &test.MockChannelRepository{Accounts: map[string]model.ChannelAccount{
+		repository.ChannelDocID(model.ChannelEmail, "taro@example.com"): {UserID: "u1"},
+	}}
+	chatRepo := &storeChatRepository{}
+	policyRepo := &test.MockToolPolicyRepository{}
+	jobQueue := &test.FakeQueue{}
+	return NewEmailService(channelRepo, chatRepo, policyRepo, &test.MockAttachmentStore{}, jobQueue), chatRepo, policyRepo, jobQueue
+}
+
+func forwardedEmail(auth *email.Auth) *email.Email {
+	return &email.Email{
+		MessageID: "abc@example.com",
+		From:      &mail.Address{Name: "Taro", Address: "Taro@example.com"},
+		Subject:   "請求書",
+		Text:      "今月の請求書です",
+		Auth:      auth,
+	}
+}
+
+func TestEmailReceiveStartsReadOnlyThread(t *testing.T) {
+	svc, chatRepo, policyRepo, jobQueue := newEmailTest()
+	ctx := context.Background()
+
+	threadID, err := svc.Receive(ctx, forwardedEmail(&email.Auth{DKIMDomains: []string{"example.com"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if thread := chatRepo.threads[threadID]; thread == nil || thread.UserID != "u1" {
+		t.Errorf("thread = %+v, want u1's thread", thread)
+	}
+	if len(jobQueue.Jobs) != 1 || jobQueue.Jobs[0].ThreadID != threadID {
+		t.Errorf("jobs = %+v, want one run on the thread", jobQueue.Jobs)
+	}
+	policy, err := policyRepo.GetToolPolicy(ctx, repository.ThreadPolicyID(threadID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy == nil || !policy.ReadOnly {
+		t.Errorf("policy = %+v, want the thread read-only", policy)
+	}
+}
+
+func TestEmailReceiveRejectsUnverifiedSender(t *testing.T) {
+	cases := map[string]*email.Auth{
+		"no verdict":        nil,
+		"spf fail":          {SPF: "fail", SPFDomain: "example.com"},
+		"other domain dkim": {DKIMDomains: []string{"attacker.test"}},
+	}
+	for name, auth := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc, chatRepo, _, jobQueue := newEmailTest()
+
+			if _, err := svc.Receive(context.Background(), forwardedEmail(auth)); !errors.Is(err, ErrUnverifiedSender) {
+				t.Fatalf("err = %v, want ErrUnverifiedSender", err)
+			}
+			if len(chatRepo.threads) != 0 || len(jobQueue.Jobs) != 0 {
+				t.Error("unverified mail must not start a thread")
+			}
+		})
+	}
+}
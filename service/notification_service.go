@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/notify"
+	"youdoyou-server/repository"
+)
+
+// maxPreviewLength limits the message text shown in a push
+const maxPreviewLength = 120
+
+// pushSendTimeout bounds each push, so a slow FCM call does not hold up the
+// run that saved the message
+const pushSendTimeout = 10 * time.Second
+
+// Push text when the message content must not leave the app
+const (
+	hiddenPushTitle = "YouDoYou"
+	hiddenPushBody  = "新しいメッセージがあります"
+)
+
+// NotificationService pushes assistant replies to the devices of the
+// thread's owner
+type NotificationService struct {
+	notificationRepo repository.NotificationRepository
+	chatRepo         repository.ChatRepository
+	sender           notify.Sender
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepository, chatRepo repository.ChatRepository, sender notify.Sender) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		chatRepo:         chatRepo,
+		sender:           sender,
+	}
+}
+
+// NotifyReply sends a push for a saved assistant message unless the user
+// muted it. Private threads and users who hide previews get a push without
+// the thread title or message text. Tokens FCM rejects are forgotten.
+func (s *NotificationService) NotifyReply(ctx context.Context, messageID string, message *model.ChatMessage) error {
+	if message.Role != model.RoleAssistant {
+		return nil
+	}
+
+	thread, err := s.chatRepo.GetThread(ctx, message.ThreadID)
+	if err != nil {
+		return err
+	}
+	settings, err := s.notificationRepo.GetNotificationSettings(ctx, thread.UserID)
+	if err != nil {
+		return err
+	}
+	if muted(settings, thread.ID, time.Now()) {
+		return nil
+	}
+
+	devices, err := s.notificationRepo.ListDeviceTokens(ctx, thread.UserID)
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+
+	n := pushNotification(thread, messageID, message, thread.IsPrivate || settings.HidePreviews)
+	var errs []error
+	for _, device := range devices {
+		sendCtx, cancel := context.WithTimeout(ctx, pushSendTimeout)
+		err := s.sender.Send(sendCtx, device.Token, n)
+		cancel()
+		if errors.Is(err, notify.ErrInvalidToken) {
+			log.Printf("Forgetting invalid device token of user %s: %v", thread.UserID, err)
+			err = s.notificationRepo.DeleteDeviceToken(ctx, device.UserID, device.Token)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// muted reports whether the user's settings silence pushes for threadID at now
+func muted(settings *model.NotificationSettings, threadID string, now time.Time) bool {
+	return settings.Muted || settings.MutedUntil.After(now) || slices.Contains(settings.MutedThreads, threadID)
+}
+
+func pushNotification(thread *model.ChatThread, messageID string, message *model.ChatMessage, hidden bool) notify.Notification {
+	n := notify.Notification{
+		Title: hiddenPushTitle,
+		Body:  hiddenPushBody,
+		Data: map[string]string{
+			"threadId":  thread.ID,
+			"messageId": messageID,
+		},
+	}
+	if hidden {
+		return n
+	}
+
+	if thread.Title != "" {
+		n.Title = thread.Title
+	}
+	if preview := previewText(message.Content); preview != "" {
+		n.Body = preview
+	}
+	return n
+}
+
+// previewText flattens whitespace and shortens content for a push body
+func previewText(content string) string {
+	text := strings.Join(strings.Fields(content), " ")
+	if runes := []rune(text); len(runes) > maxPreviewLength {
+		text = string(runes[:maxPreviewLength-1]) + "…"
+	}
+	return text
+}
+
+// NotifyingChatRepository sends a push after each assistant message it
+// saves, whichever service saved it (agent replies, error replies, reminders).
+// A push that fails is logged; the message is saved either way.
+type NotifyingChatRepository struct {
+	repository.ChatRepository
+	notifications *NotificationService
+}
+
+func NewNotifyingChatRepository(chatRepo repository.ChatRepository, notifications *NotificationService) repository.ChatRepository {
+	return &NotifyingChatRepository{
+		ChatRepository: chatRepo,
+		notifications:  notifications,
+	}
+}
+
+func (r *NotifyingChatRepository) SaveMessage(ctx context.Context, message *model.ChatMessage) (string, error) {
+	id, err := r.ChatRepository.SaveMessage(ctx, message)
+	if err != nil || message.Role != model.RoleAssistant {
+		return id, err
+	}
+
+	// The message is stored; a run that just timed out still notifies
+	if err := r.notifications.NotifyReply(context.WithoutCancel(ctx), id, message); err != nil {
+		log.Printf("Warning: Failed to push message %s of thread %s: %v", id, message.ThreadID, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/notify"
+	"youdoyou-server/test"
+)
+
+// threadChatRepository serves fixed threads on top of the no-op mock
+type threadChatRepository struct {
+	test.MockChatRepository
+	threads map[string]*model.ChatThread
+}
+
+func (r *threadChatRepository) GetThread(ctx context.Context, threadID string) (*model.ChatThread, error) {
+	return r.threads[threadID], nil
+}
+
+func newNotificationTest(t *testing.T, thread *model.ChatThread, settings *model.NotificationSettings, tokens ...string) (*NotificationService, *test.MockNotificationRepository, *test.FakePushSender) {
+	t.Helper()
+	ctx := context.Background()
+	repo := &test.MockNotificationRepository{}
+	for _, token := range tokens {
+		if err := repo.SaveDeviceToken(ctx, &model.DeviceToken{UserID: thread.UserID, Token: token}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if settings != nil {
+		settings.ID = thread.UserID
+		if err := repo.SaveNotificationSettings(ctx, settings); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chatRepo := &threadChatRepository{threads: map[string]*model.ChatThread{thread.ID: thread}}
+	sender := &test.FakePushSender{}
+	return NewNotificationService(repo, chatRepo, sender), repo, sender
+}
+
+func assistantReply(threadID string, content string) *model.ChatMessage {
+	return &model.ChatMessage{ThreadID: threadID, Role: model.RoleAssistant, Content: content}
+}
+
+func TestNotifyReplyShowsPreview(t *testing.T) {
+	thread := &model.ChatThread{ID: "t1", UserID: "u1", Title: "Trip"}
+	svc, _, sender := newNotificationTest(t, thread, nil, "tok")
+
+	if err := svc.NotifyReply(context.Background(), "m1", assistantReply("t1", "Your  flight\nis booked")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sender.Sent) != 1 {
+		t.Fatalf("sent %d pushes, want 1", len(sender.Sent))
+	}
+	n := sender.Sent[0].Notification
+	if n.Title != "Trip" || n.Body != "Your flight is booked" {
+		t.Errorf("push = %q / %q, want the thread title and flattened text", n.Title, n.Body)
+	}
+	if n.Data["threadId"] != "t1" || n.Data["messageId"] != "m1" {
+		t.Errorf("data = %v", n.Data)
+	}
+}
+
+func TestNotifyReplyHidesPrivatePreview(t *testing.T) {
+	thread := &model.ChatThread{ID: "t1", UserID: "u1", Title: "Diagnosis", IsPrivate: true}
+	svc, _, sender := newNotificationTest(t, thread, nil, "tok")
+
+	if err := svc.NotifyReply(context.Background(), "m1", assistantReply("t1", "secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sender.Sent) != 1 {
+		t.Fatalf("sent %d pushes, want 1", len(sender.Sent))
+	}
+	n := sender.Sent[0].Notification
+	if n.Title != hiddenPushTitle || n.Body != hiddenPushBody {
+		t.Errorf("push = %q / %q, want the hidden text", n.Title, n.Body)
+	}
+}
+
+func TestNotifyReplyRespectsMute(t *testing.T) {
+	cases := map[string]*model.NotificationSettings{
+		"muted":        {Muted: true},
+		"muted until":  {MutedUntil: time.Now().Add(time.Hour)},
+		"muted thread": {MutedThreads: []string{"t1"}},
+	}
+	for name, settings := range cases {
+		t.Run(name, func(t *testing.T) {
+			thread := &model.ChatThread{ID: "t1", UserID: "u1"}
+			svc, _, sender := newNotificationTest(t, thread, settings, "tok")
+
+			if err := svc.NotifyReply(context.Background(), "m1", assistantReply("t1", "hi")); err != nil {
+				t.Fatal(err)
+			}
+			if len(sender.Sent) != 0 {
+				t.Errorf("sent %d pushes to a muted user", len(sender.Sent))
+			}
+		})
+	}
+}
+
+func TestNotifyReplyForgetsInvalidTokens(t *testing.T) {
+	thread := &model.ChatThread{ID: "t1", UserID: "u1"}
+	svc, repo, sender := newNotificationTest(t, thread, nil, "good", "gone")
+	sender.InvalidTokens = map[string]bool{"gone": true}
+
+	if err := svc.NotifyReply(context.Background(), "m1", assistantReply("t1", "hi")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sender.Sent) != 1 || sender.Sent[0].Token != "good" {
+		t.Errorf("sent = %+v, want one push to the valid token", sender.Sent)
+	}
+	devices, err := repo.ListDeviceTokens(context.Background(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].Token != "good" {
+		t.Errorf("devices = %+v, want only the valid token", devices)
+	}
+}
+
+func TestNotifyReplyTimesOutEachSend(t *testing.T) {
+	thread := &model.ChatThread{ID: "t1", UserID: "u1"}
+	svc, _, _ := newNotificationTest(t, thread, nil, "tok")
+	var deadline time.Time
+	svc.sender = senderFunc(func(ctx context.Context, token string, n notify.Notification) error {
+		deadline, _ = ctx.Deadline()
+		return nil
+	})
+
+	if err := svc.NotifyReply(context.Background(), "m1", assistantReply("t1", "hi")); err != nil {
+		t.Fatal(err)
+	}
+	if deadline.IsZero() || time.Until(deadline) > pushSendTimeout {
+		t.Errorf("send deadline = %v, want within %v", deadline, pushSendTimeout)
+	}
+}
+
+type senderFunc func(ctx context.Context, token string, n notify.Notification) error
+
+func (f senderFunc) Send(ctx context.Context, token string, n notify.Notification) error {
+	return f(ctx, token, n)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/recurrence"
+	"youdoyou-server/repository"
+)
+
+// ReminderService posts reminders for due tasks into their threads as
+// assistant messages
+type ReminderService struct {
+	taskRepo repository.TaskRepository
+	chatRepo repository.ChatRepository
+}
+
+func NewReminderService(taskRepo repository.TaskRepository, chatRepo repository.ChatRepository) *ReminderService {
+	return &ReminderService{
+		taskRepo: taskRepo,
+		chatRepo: chatRepo,
+	}
+}
+
+// ReminderSummary is the outcome of one SendDue pass
+type ReminderSummary struct {
+	Sent   int `json:"sent"`
+	Failed int `json:"failed"`
+}
+
+// SendDue posts the reminders due by now. Recurring tasks move to their next
+// occurrence; others are marked as reminded. A task that fails is retried on
+// the next pass, and one claimed by an overlapping pass is skipped.
+func (s *ReminderService) SendDue(ctx context.Context, now time.Time, limit int) (ReminderSummary, error) {
+	var summary ReminderSummary
+
+	tasks, err := s.taskRepo.ListDueTasks(ctx, now, limit)
+	if err != nil {
+		return summary, err
+	}
+
+	for i := range tasks {
+		sent, err := s.remind(ctx, &tasks[i], now)
+		if err != nil {
+			log.Printf("Warning: Failed to send reminder for task %s: %v", tasks[i].ID, err)
+			summary.Failed++
+			continue
+		}
+		if sent {
+			summary.Sent++
+		}
+	}
+	return summary, nil
+}
+
+// remind posts the task's reminder. It returns false if another pass already
+// claimed it.
+func (s *ReminderService) remind(ctx context.Context, task *model.Task, now time.Time) (bool, error) {
+	// Claim first, so overlapping passes post the reminder only once
+	previous := task.RemindedAt
+	claimed, err := s.taskRepo.ClaimReminder(ctx, task)
+	if err != nil || !claimed {
+		return false, err
+	}
+	if err := s.post(ctx, task, now); err != nil {
+		// Release the claim so the next pass retries
+		task.RemindedAt = previous
+		if relErr := s.taskRepo.SaveTask(ctx, task); relErr != nil {
+			log.Printf("Warning: Failed to release reminder of task %s: %v", task.ID, relErr)
+		}
+		return false, err
+	}
+
+	// Posted; from here on only the task's schedule changes
+	advanced, err := recurrence.AdvanceTask(task, now)
+	if err != nil {
+		log.Printf("Warning: Failed to compute next occurrence of task %s: %v", task.ID, err)
+	}
+	if !advanced {
+		task.RemindedAt = task.DueAt
+	}
+	task.UpdatedAt = now
+	return true, s.taskRepo.SaveTask(ctx, task)
+}
+
+// post saves the reminder message, in a new thread if the task has none
+func (s *ReminderService) post(ctx context.Context, task *model.Task, now time.Time) error {
+	content := reminderContent(task)
+
+	// The thread may have been deleted since the task was created
+	var thread *model.ChatThread
+	if task.ThreadID != "" {
+		thread, _ = s.chatRepo.GetThread(ctx, task.ThreadID)
+	}
+	if thread == nil {
+		thread = &model.ChatThread{
+			UserID:       task.UserID,
+			Timezone:     task.Timezone,
+			FirstMessage: content,
+			LastReadAt:   now,
+			CreatedAt:    now,
+		}
+		if err := s.chatRepo.CreateThread(ctx, thread); err != nil {
+			return fmt.Errorf("failed to create thread: %w", err)
+		}
+		task.ThreadID = thread.ID
+	}
+
+	// Saved like an agent reply, so the Firestore trigger indexes it and the thread shows it unread
+	reminder := &model.ChatMessage{
+		ThreadID:  thread.ID,
+		BranchID:  thread.ActiveBranchID,
+		Role:      model.RoleAssistant,
+		Content:   content,
+		TaskID:    task.ID,
+		CreatedAt: now,
+	}
+	if _, err := s.chatRepo.SaveMessage(ctx, reminder); err != nil {
+		return fmt.Errorf("failed to save reminder: %w", err)
+	}
+	return nil
+}
+
+func reminderContent(task *model.Task) string {
+	content := "⏰ リマインダー: " + task.Title
+	if task.Notes != "" {
+		content += "\n" + task.Notes
+	}
+	if loc, err := time.LoadLocation(task.Timezone); err == nil && !task.DueAt.IsZero() {
+		content += "\n期限: " + task.DueAt.In(loc).Format("2006-01-02 15:04")
+	}
+	return content
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/test"
+)
+
+func TestSendDuePostsReminderOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	taskRepo := &test.MockTaskRepository{}
+	chatRepo := &storeChatRepository{threads: map[string]*model.ChatThread{"t1": {ID: "t1", UserID: "u1"}}}
+	task := &model.Task{UserID: "u1", ThreadID: "t1", Title: "資料を送る", DueAt: now.Add(-time.Minute), Timezone: "Asia/Tokyo"}
+	if err := taskRepo.SaveTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	s := NewReminderService(taskRepo, chatRepo)
+
+	summary, err := s.SendDue(ctx, now, 10)
+	if err != nil || summary.Sent != 1 {
+		t.Fatalf("SendDue = %+v, %v, want 1 sent", summary, err)
+	}
+	if len(chatRepo.messages) != 1 {
+		t.Fatalf("messages = %d, want 1", len(chatRepo.messages))
+	}
+	msg := chatRepo.messages[0]
+	if msg.ThreadID != "t1" || msg.Role != model.RoleAssistant || msg.TaskID != task.ID || !strings.Contains(msg.Content, "資料を送る") {
+		t.Errorf("reminder = %+v", msg)
+	}
+	// Due time in the task's timezone
+	if !strings.Contains(msg.Content, "2026-03-02 17:59") {
+		t.Errorf("content = %q, want the due time in JST", msg.Content)
+	}
+
+	summary, _ = s.SendDue(ctx, now, 10)
+	if summary.Sent != 0 || len(chatRepo.messages) != 1 {
+		t.Errorf("second pass sent %d, want none", summary.Sent)
+	}
+}
+
+func TestSendDueAdvancesRecurringTask(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	taskRepo := &test.MockTaskRepository{}
+	chatRepo := &storeChatRepository{}
+	task := &model.Task{UserID: "u1", Title: "週報", DueAt: now, RRule: "FREQ=WEEKLY", Timezone: "UTC"}
+	if err := taskRepo.SaveTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewReminderService(taskRepo, chatRepo).SendDue(ctx, now, 10); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := taskRepo.GetTask(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !saved.DueAt.Equal(now.AddDate(0, 0, 7)) || !saved.RemindedAt.IsZero() {
+		t.Errorf("task = %+v, want next week's occurrence with no reminder sent", saved)
+	}
+	// Without a thread the reminder starts one
+	if len(chatRepo.threads) != 1 || len(chatRepo.messages) != 1 {
+		t.Errorf("threads = %d, messages = %d, want 1 each", len(chatRepo.threads), len(chatRepo.messages))
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/queue"
+	"youdoyou-server/repository"
+)
+
+// maxRetryDelay caps the exponential backoff between retries
+const maxRetryDelay = 6 * time.Hour
+
+// RetryService records failed agent runs in the failedRuns collection and
+// re-runs them with exponential backoff
+type RetryService struct {
+	failedRunRepo repository.FailedRunRepository
+	chatRepo      repository.ChatRepository
+	agentService  *AgentService
+	maxAttempts   int
+	baseDelay     time.Duration
+}
+
+func NewRetryService(
+	failedRunRepo repository.FailedRunRepository,
+	chatRepo repository.ChatRepository,
+	agentService *AgentService,
+	maxAttempts int,
+	baseDelay time.Duration,
+) *RetryService {
+	return &RetryService{
+		failedRunRepo: failedRunRepo,
+		chatRepo:      chatRepo,
+		agentService:  agentService,
+		maxAttempts:   maxAttempts,
+		baseDelay:     baseDelay,
+	}
+}
+
+// RetrySummary is the outcome of one RetryDue pass
+type RetrySummary struct {
+	Queued   int `json:"queued"`
+	Resolved int `json:"resolved"`
+}
+
+// Run runs the agent and records a failure in the dead-letter queue, or
+// resolves the failed run when a retry succeeds.
+// Runs cancelled by the user are not retried, and neither are initiated runs:
+// a retry answers a message, and a schedule simply runs again at its next time.
+func (s *RetryService) Run(ctx context.Context, threadID string, opts ChatOptions) error {
+	err := s.agentService.Chat(ctx, threadID, opts)
+	if err == nil && opts.Retry {
+		if resErr := s.resolveID(ctx, failedRunID(threadID, opts.ReplyTo)); resErr != nil {
+			log.Printf("Warning: Failed to resolve failed run for thread %s: %v", threadID, resErr)
+		}
+	}
+	if err != nil && !errors.Is(err, ErrRunCancelled) && opts.Initiate == "" {
+		messageID := opts.ReplyTo
+		if messageID == "" {
+			messageID = ClassifyError(err).MessageID
+		}
+		if _, recErr := s.RecordFailure(ctx, threadID, messageID, err); recErr != nil {
+			log.Printf("Warning: Failed to record failed run for thread %s: %v", threadID, recErr)
+		}
+	}
+	return err
+}
+
+// RecordFailure adds a failed attempt to the run's record and schedules the
+// next retry, or marks the run dead when it is not retryable or out of attempts
+func (s *RetryService) RecordFailure(ctx context.Context, threadID string, messageID string, runErr error) (*model.FailedRun, error) {
+	// Record even if the run failed because its context ended
+	ctx = context.WithoutCancel(ctx)
+
+	id := failedRunID(threadID, messageID)
+	run, err := s.failedRunRepo.GetFailedRun(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if run == nil || run.Status != model.FailedRunStatusPending {
+		// First failure, or the message failed again after an earlier resolution
+		run = &model.FailedRun{
+			ID:        id,
+			ThreadID:  threadID,
+			MessageID: messageID,
+			CreatedAt: now,
+		}
+	}
+
+	classified := ClassifyError(runErr)
+	run.Attempts++
+	run.Error = runErr.Error()
+	run.ErrorCode = classified.Code
+	run.UpdatedAt = now
+	if classified.Retryable && run.Attempts < s.maxAttempts {
+		run.Status = model.FailedRunStatusPending
+		run.NextAttemptAt = now.Add(s.backoff(run.Attempts))
+	} else {
+		run.Status = model.FailedRunStatusDead
+	}
+
+	if err := s.failedRunRepo.SaveFailedRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// RetryDue queues a retry job for each pending failed run that is due by the
+// given time. The job's outcome is recorded by Run. A queued run is pushed back
+// by its next backoff, so later passes don't queue it again while it waits.
+func (s *RetryService) RetryDue(ctx context.Context, jobQueue queue.Queue, dueBy time.Time, limit int) (RetrySummary, error) {
+	var summary RetrySummary
+
+	runs, err := s.failedRunRepo.ListDueFailedRuns(ctx, dueBy, limit)
+	if err != nil {
+		return summary, err
+	}
+
+	for _, run := range runs {
+		// Answered in the meantime (e.g. regenerated by the user); don't answer twice
+		if run.MessageID != "" {
+			msg, err := s.chatRepo.GetMessage(ctx, run.ThreadID, run.MessageID)
+			if err == nil && msg.Status == model.MessageStatusCompleted {
+				if err := s.resolve(ctx, &run); err != nil {
+					return summary, err
+				}
+				summary.Resolved++
+				continue
+			}
+		}
+
+		run.NextAttemptAt = time.Now().Add(s.backoff(run.Attempts + 1))
+		run.UpdatedAt = time.Now()
+		if err := s.failedRunRepo.SaveFailedRun(ctx, &run); err != nil {
+			return summary, fmt.Errorf("failed to claim failed run %s: %w", run.ID, err)
+		}
+		log.Printf("Queueing retry of failed run %s (attempt %d)", run.ID, run.Attempts+1)
+		if err := jobQueue.Enqueue(ctx, queue.Job{ThreadID: run.ThreadID, MessageID: run.MessageID, Retry: true}); err != nil {
+			return summary, fmt.Errorf("failed to queue retry of %s: %w", run.ID, err)
+		}
+		summary.Queued++
+	}
+
+	return summary, nil
+}
+
+// resolveID resolves the run with the given ID if it is still pending
+func (s *RetryService) resolveID(ctx context.Context, id string) error {
+	ctx = context.WithoutCancel(ctx)
+	run, err := s.failedRunRepo.GetFailedRun(ctx, id)
+	if err != nil || run == nil || run.Status != model.FailedRunStatusPending {
+		return err
+	}
+	return s.resolve(ctx, run)
+}
+
+func (s *RetryService) resolve(ctx context.Context, run *model.FailedRun) error {
+	run.Status = model.FailedRunStatusResolved
+	run.UpdatedAt = time.Now()
+	return s.failedRunRepo.SaveFailedRun(ctx, run)
+}
+
+// failedRunID keys a failed run by the message it answers, or by its thread
+func failedRunID(threadID string, messageID string) string {
+	if messageID == "" {
+		return threadID
+	}
+	return messageID
+}
+
+// backoff returns baseDelay * 2^(attempts-1), capped at maxRetryDelay
+func (s *RetryService) backoff(attempts int) time.Duration {
+	delay := s.baseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
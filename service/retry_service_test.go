@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/test"
+)
+
+// answeredChatRepository reports m-done as answered and every other message as failed
+type answeredChatRepository struct {
+	test.MockChatRepository
+}
+
+func (r *answeredChatRepository) GetMessage(ctx context.Context, threadID string, messageID string) (*model.ChatMessage, error) {
+	status := model.MessageStatusError
+	if messageID == "m-done" {
+		status = model.MessageStatusCompleted
+	}
+	return &model.ChatMessage{ID: messageID, ThreadID: threadID, Role: model.RoleUser, Status: status}, nil
+}
+
+func TestRecordFailureBacksOffUntilDead(t *testing.T) {
+	ctx := context.Background()
+	runRepo := &test.MockFailedRunRepository{}
+	s := NewRetryService(runRepo, &test.MockChatRepository{}, nil, 3, time.Minute)
+
+	var run *model.FailedRun
+	for attempt, wantDelay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		before := time.Now()
+		var err error
+		run, err = s.RecordFailure(ctx, "t1", "m1", context.DeadlineExceeded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status != model.FailedRunStatusPending || run.Attempts != attempt+1 {
+			t.Fatalf("attempt %d: run = %+v, want pending", attempt+1, run)
+		}
+		if delay := run.NextAttemptAt.Sub(before); delay < wantDelay || delay > wantDelay+time.Second {
+			t.Errorf("attempt %d: next attempt in %s, want %s", attempt+1, delay, wantDelay)
+		}
+	}
+
+	run, err := s.RecordFailure(ctx, "t1", "m1", context.DeadlineExceeded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != model.FailedRunStatusDead || run.Attempts != 3 {
+		t.Errorf("run = %+v, want dead after 3 attempts", run)
+	}
+}
+
+func TestRecordFailureNotRetryable(t *testing.T) {
+	s := NewRetryService(&test.MockFailedRunRepository{}, &test.MockChatRepository{}, nil, 3, time.Minute)
+
+	run, err := s.RecordFailure(context.Background(), "t1", "", errors.New("unexpected"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keyed by the thread when no message is known
+	if run.ID != "t1" || run.Status != model.FailedRunStatusDead {
+		t.Errorf("run = %+v, want a dead run t1", run)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	s := NewRetryService(nil, nil, nil, 100, time.Minute)
+	if d := s.backoff(30); d != maxRetryDelay {
+		t.Errorf("backoff(30) = %s, want %s", d, maxRetryDelay)
+	}
+}
+
+func TestRetryDueSkipsAnsweredMessages(t *testing.T) {
+	ctx := context.Background()
+	runRepo := &test.MockFailedRunRepository{}
+	past := time.Now().Add(-time.Minute)
+	for _, id := range []string{"m-done", "m-failed"} {
+		run := &model.FailedRun{ID: id, ThreadID: "t1", MessageID: id, Status: model.FailedRunStatusPending, Attempts: 1, NextAttemptAt: past}
+		if err := runRepo.SaveFailedRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobQueue := &test.FakeQueue{}
+	s := NewRetryService(runRepo, &answeredChatRepository{}, nil, 3, time.Minute)
+
+	summary, err := s.RetryDue(ctx, jobQueue, time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Queued != 1 || summary.Resolved != 1 {
+		t.Errorf("summary = %+v, want 1 queued and 1 resolved", summary)
+	}
+	if len(jobQueue.Jobs) != 1 || jobQueue.Jobs[0].MessageID != "m-failed" || !jobQueue.Jobs[0].Retry {
+		t.Errorf("jobs = %+v, want a retry of m-failed", jobQueue.Jobs)
+	}
+
+	// The queued run is pushed back, so the next pass leaves it alone
+	summary, err = s.RetryDue(ctx, jobQueue, time.Now(), 10)
+	if err != nil || summary.Queued != 0 {
+		t.Errorf("second pass = %+v, %v, want nothing queued", summary, err)
+	}
+}
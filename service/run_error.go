@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"youdoyou-server/model"
+)
+
+// RunError is a failed agent run with the error code and retryable flag
+// recorded on the user message
+type RunError struct {
+	Code      string
+	Retryable bool
+	// MessageID is the user message the run was answering, if known
+	MessageID string
+	// Partial is text the model produced before the run stopped
+	Partial string
+	Err     error
+}
+
+func (e *RunError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Code, e.Err)
+}
+
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError returns err as a RunError, guessing the code for plain errors
+func ClassifyError(err error) *RunError {
+	return classifyError(err, model.ErrorCodeInternal)
+}
+
+func classifyError(err error, fallback string) *RunError {
+	var runErr *RunError
+	if errors.As(err, &runErr) {
+		return runErr
+	}
+
+	switch {
+	case errors.Is(err, ErrRunCancelled):
+		return &RunError{Code: model.ErrorCodeCancelled, Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &RunError{Code: model.ErrorCodeTimeout, Retryable: true, Err: err}
+	case errors.Is(err, context.Canceled):
+		return &RunError{Code: model.ErrorCodeCancelled, Err: err}
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "429") || strings.Contains(msg, "RESOURCE_EXHAUSTED"):
+		return &RunError{Code: model.ErrorCodeRateLimited, Retryable: true, Err: err}
+	case strings.Contains(msg, "503") || strings.Contains(msg, "UNAVAILABLE") || strings.Contains(msg, "DEADLINE_EXCEEDED"):
+		return &RunError{Code: fallback, Retryable: true, Err: err}
+	}
+	// Model calls fail transiently far more often than they fail permanently
+	return &RunError{Code: fallback, Retryable: fallback == model.ErrorCodeModelError, Err: err}
+}
+
+// errorReplies are the assistant messages saved when a run fails
+var errorReplies = map[string]string{
+	model.ErrorCodeTimeout:      "応答に時間がかかりすぎたため、処理を中断しました。もう一度お試しください。",
+	model.ErrorCodeRateLimited:  "現在アクセスが集中しています。少し時間をおいてからもう一度お試しください。",
+	model.ErrorCodeModelError:   "AIの呼び出しに失敗しました。しばらくしてからもう一度お試しください。",
+	model.ErrorCodeStorageError: "データの読み書きに失敗したため、返信を保存できませんでした。もう一度お試しください。",
+	model.ErrorCodeCancelled:    "処理をキャンセルしました。",
+	model.ErrorCodeInternal:     "申し訳ありません、エラーが発生したため返信できませんでした。",
+}
+
+// updateStatus records the processing status on user messages (best effort)
+func (s *AgentService) updateStatus(ctx context.Context, threadID string, messages []*model.ChatMessage, status string, runErr *RunError) {
+	var code string
+	var retryable bool
+	if runErr != nil {
+		code, retryable = runErr.Code, runErr.Retryable
+	}
+	for _, msg := range messages {
+		if msg.ID == "" || msg.Role != model.RoleUser {
+			continue
+		}
+		if err := s.chatRepo.UpdateMessageStatus(ctx, threadID, msg.ID, status, code, retryable); err != nil {
+			log.Printf("Warning: Failed to set status %s on message %s: %v", status, msg.ID, err)
+		}
+	}
+}
+
+// recordFailure marks the answered user messages as failed and saves an
+// assistant message explaining the failure after target, so the client is not
+// left waiting. Retries only update the status. Returns the classified error.
+func (s *AgentService) recordFailure(ctx context.Context, threadID string, target *model.ChatMessage, answering []*model.ChatMessage, opts ChatOptions, err error) *RunError {
+	runErr := ClassifyError(err)
+	if target != nil && target.Role == model.RoleUser {
+		runErr.MessageID = target.ID
+	}
+
+	// The run's context may be the reason it failed; still record the outcome
+	ctx = context.WithoutCancel(ctx)
+	s.updateStatus(ctx, threadID, answering, model.MessageStatusError, runErr)
+
+	if opts.Retry {
+		return runErr
+	}
+
+	content, ok := errorReplies[runErr.Code]
+	if !ok {
+		content = errorReplies[model.ErrorCodeInternal]
+	}
+	if runErr.Partial != "" {
+		content = runErr.Partial + "\n\n---\n" + content
+	}
+	reply := &model.ChatMessage{
+		ThreadID:  threadID,
+		Role:      model.RoleAssistant,
+		Content:   content,
+		ErrorCode: runErr.Code,
+		Retryable: runErr.Retryable,
+		CreatedAt: time.Now(),
+	}
+	reply.ParentID, reply.BranchID = replyLineage(nil, target, opts)
+	if _, err := s.chatRepo.SaveMessage(ctx, reply); err != nil {
+		log.Printf("Warning: Failed to save error reply for thread %s: %v", threadID, err)
+	}
+	return runErr
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"youdoyou-server/model"
+)
+
+// statusChatRepository records message status updates
+type statusChatRepository struct {
+	storeChatRepository
+	statuses map[string]string
+}
+
+func (r *statusChatRepository) UpdateMessageStatus(ctx context.Context, threadID string, messageID string, status string, errorCode string, retryable bool) error {
+	if r.statuses == nil {
+		r.statuses = make(map[string]string)
+	}
+	r.statuses[messageID] = status + "/" + errorCode
+	return nil
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err       error
+		code      string
+		retryable bool
+	}{
+		{context.DeadlineExceeded, model.ErrorCodeTimeout, true},
+		{fmt.Errorf("run: %w", ErrRunCancelled), model.ErrorCodeCancelled, false},
+		{errors.New("googleapi: Error 429: RESOURCE_EXHAUSTED"), model.ErrorCodeRateLimited, true},
+		{errors.New("rpc error: code = UNAVAILABLE"), model.ErrorCodeInternal, true},
+		{errors.New("unexpected"), model.ErrorCodeInternal, false},
+		{&RunError{Code: model.ErrorCodeStorageError, Err: errors.New("write failed")}, model.ErrorCodeStorageError, false},
+	}
+	for _, c := range cases {
+		runErr := ClassifyError(c.err)
+		if runErr.Code != c.code || runErr.Retryable != c.retryable {
+			t.Errorf("ClassifyError(%v) = %s retryable=%v, want %s retryable=%v", c.err, runErr.Code, runErr.Retryable, c.code, c.retryable)
+		}
+	}
+	if runErr := classifyError(errors.New("bad response"), model.ErrorCodeModelError); !runErr.Retryable {
+		t.Error("model errors should be retryable")
+	}
+}
+
+func TestRecordFailureSavesErrorReply(t *testing.T) {
+	chatRepo := &statusChatRepository{}
+	s := NewAgentService(chatRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+	history := []model.ChatMessage{
+		{ID: "m1", Role: model.RoleUser, Status: model.MessageStatusPending},
+		{ID: "m2", Role: model.RoleUser},
+	}
+	answering := answeredMessages(history)
+	runErr := &RunError{Code: model.ErrorCodeTimeout, Retryable: true, Partial: "途中まで", Err: context.DeadlineExceeded}
+
+	got := s.recordFailure(context.Background(), "t1", &history[1], answering, ChatOptions{}, runErr)
+	if got.MessageID != "m2" {
+		t.Errorf("MessageID = %q, want m2", got.MessageID)
+	}
+	// Both the coalesced message and the answered one failed
+	for _, id := range []string{"m1", "m2"} {
+		if chatRepo.statuses[id] != model.MessageStatusError+"/"+model.ErrorCodeTimeout {
+			t.Errorf("status of %s = %q, want error/timeout", id, chatRepo.statuses[id])
+		}
+	}
+	if len(chatRepo.messages) != 1 {
+		t.Fatalf("saved %d messages, want one error reply", len(chatRepo.messages))
+	}
+	reply := chatRepo.messages[0]
+	if reply.Role != model.RoleAssistant || reply.ErrorCode != model.ErrorCodeTimeout || !reply.Retryable {
+		t.Errorf("reply = %+v, want a retryable timeout reply", reply)
+	}
+	if !strings.HasPrefix(reply.Content, "途中まで") || !strings.Contains(reply.Content, errorReplies[model.ErrorCodeTimeout]) {
+		t.Errorf("content = %q, want the partial text and the timeout notice", reply.Content)
+	}
+}
+
+func TestRecordFailureOnRetryOnlyUpdatesStatus(t *testing.T) {
+	chatRepo := &statusChatRepository{}
+	s := NewAgentService(chatRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+	target := &model.ChatMessage{ID: "m1", Role: model.RoleUser}
+
+	s.recordFailure(context.Background(), "t1", target, []*model.ChatMessage{target}, ChatOptions{Retry: true}, errors.New("unexpected"))
+	if len(chatRepo.messages) != 0 {
+		t.Errorf("saved %+v, want no reply on retry", chatRepo.messages)
+	}
+	if chatRepo.statuses["m1"] != model.MessageStatusError+"/"+model.ErrorCodeInternal {
+		t.Errorf("status = %q, want error/internal", chatRepo.statuses["m1"])
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"youdoyou-server/model"
+)
+
+// ErrRunCancelled is the cancellation cause of a run stopped by the user
+var ErrRunCancelled = errors.New("run cancelled by user")
+
+// RunRegistry tracks the agent runs in progress on this instance so they can
+// be cancelled. Runs on other instances see the thread's cancelRequestedAt.
+type RunRegistry struct {
+	mu     sync.Mutex
+	nextID int
+	runs   map[string]map[int]context.CancelCauseFunc
+}
+
+func NewRunRegistry() *RunRegistry {
+	return &RunRegistry{runs: make(map[string]map[int]context.CancelCauseFunc)}
+}
+
+// Register returns a context that Cancel can stop, and a function to call
+// when the run ends
+func (r *RunRegistry) Register(ctx context.Context, threadID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	id := r.nextID
+	if r.runs[threadID] == nil {
+		r.runs[threadID] = make(map[int]context.CancelCauseFunc)
+	}
+	r.runs[threadID][id] = cancel
+
+	return ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.runs[threadID], id)
+		if len(r.runs[threadID]) == 0 {
+			delete(r.runs, threadID)
+		}
+		cancel(nil)
+	}
+}
+
+// Cancel stops the thread's runs on this instance and returns how many there were
+func (r *RunRegistry) Cancel(threadID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cancel := range r.runs[threadID] {
+		cancel(ErrRunCancelled)
+	}
+	return len(r.runs[threadID])
+}
+
+// Cancel stops the thread's runs: immediately on this instance, and at their
+// next turn on other instances via the thread's cancelRequestedAt.
+// Returns the number of runs stopped on this instance.
+func (s *AgentService) Cancel(ctx context.Context, threadID string) (int, error) {
+	if err := s.chatRepo.RequestCancel(ctx, threadID, time.Now()); err != nil {
+		return 0, fmt.Errorf("failed to request cancel: %w", err)
+	}
+	return s.runs.Cancel(threadID), nil
+}
+
+// cancelled reports whether the run should stop. checkStore also reads the
+// thread, to see cancels requested through another instance.
+func (s *AgentService) cancelled(ctx context.Context, threadID string, startedAt time.Time, checkStore bool) bool {
+	if errors.Is(context.Cause(ctx), ErrRunCancelled) {
+		return true
+	}
+	if !checkStore {
+		return false
+	}
+	thread, err := s.chatRepo.GetThread(ctx, threadID)
+	return err == nil && thread.CancelRequestedAt.After(startedAt)
+}
+
+func cancelledError(partial string) *RunError {
+	return &RunError{Code: model.ErrorCodeCancelled, Err: ErrRunCancelled, Partial: partial}
+}
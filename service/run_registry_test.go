@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/test"
+)
+
+// cancelChatRepository records cancel requests on thread t1
+type cancelChatRepository struct {
+	test.MockChatRepository
+	cancelRequestedAt time.Time
+}
+
+func (r *cancelChatRepository) RequestCancel(ctx context.Context, threadID string, at time.Time) error {
+	r.cancelRequestedAt = at
+	return nil
+}
+
+func (r *cancelChatRepository) GetThread(ctx context.Context, threadID string) (*model.ChatThread, error) {
+	return &model.ChatThread{ID: threadID, CancelRequestedAt: r.cancelRequestedAt}, nil
+}
+
+func TestRunRegistryCancel(t *testing.T) {
+	r := NewRunRegistry()
+	ctx1, done1 := r.Register(context.Background(), "t1")
+	ctx2, done2 := r.Register(context.Background(), "t1")
+	other, doneOther := r.Register(context.Background(), "t2")
+	defer doneOther()
+
+	if n := r.Cancel("t1"); n != 2 {
+		t.Errorf("Cancel = %d, want 2", n)
+	}
+	for _, ctx := range []context.Context{ctx1, ctx2} {
+		if !errors.Is(context.Cause(ctx), ErrRunCancelled) {
+			t.Errorf("cause = %v, want ErrRunCancelled", context.Cause(ctx))
+		}
+	}
+	if other.Err() != nil {
+		t.Error("the run on another thread was cancelled")
+	}
+
+	done1()
+	done2()
+	if n := r.Cancel("t1"); n != 0 {
+		t.Errorf("Cancel after the runs ended = %d, want 0", n)
+	}
+}
+
+func TestCancelStopsRunsOnOtherInstances(t *testing.T) {
+	chatRepo := &cancelChatRepository{}
+	s := NewAgentService(chatRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+	startedAt := time.Now().Add(-time.Second)
+
+	if s.cancelled(context.Background(), "t1", startedAt, true) {
+		t.Fatal("run cancelled before any request")
+	}
+	if _, err := s.Cancel(context.Background(), "t1"); err != nil {
+		t.Fatal(err)
+	}
+	// A run elsewhere only sees the request when it reads the thread
+	if s.cancelled(context.Background(), "t1", startedAt, false) {
+		t.Error("run cancelled without checking the store")
+	}
+	if !s.cancelled(context.Background(), "t1", startedAt, true) {
+		t.Error("run not cancelled after the request")
+	}
+	// Runs started after the request keep going
+	if s.cancelled(context.Background(), "t1", time.Now().Add(time.Second), true) {
+		t.Error("a later run was cancelled by an earlier request")
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/queue"
+	"youdoyou-server/recurrence"
+	"youdoyou-server/repository"
+)
+
+// ScheduleService dispatches due schedules as agent jobs that answer the
+// schedule's prompt in initiate mode
+type ScheduleService struct {
+	scheduleRepo repository.ScheduleRepository
+	chatRepo     repository.ChatRepository
+	jobQueue     queue.Queue
+	grace        time.Duration
+}
+
+// NewScheduleService creates the dispatcher. A run that starts at most grace
+// after its scheduled time is on time; later runs follow the schedule's catchUp.
+func NewScheduleService(scheduleRepo repository.ScheduleRepository, chatRepo repository.ChatRepository, jobQueue queue.Queue, grace time.Duration) *ScheduleService {
+	return &ScheduleService{
+		scheduleRepo: scheduleRepo,
+		chatRepo:     chatRepo,
+		jobQueue:     jobQueue,
+		grace:        grace,
+	}
+}
+
+// ScheduleSummary is the outcome of one DispatchDue pass
+type ScheduleSummary struct {
+	Dispatched int `json:"dispatched"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+}
+
+// DispatchDue enqueues a run for each schedule due by now. Catch-up rules:
+//   - A schedule runs at most once per pass, for the latest scheduled time
+//     that has passed. Earlier missed times are dropped, never replayed.
+//   - That run is on time if it is at most grace late. A later run follows
+//     the schedule's catchUp: once (the default) runs it now, skip drops it.
+//   - nextRunAt always moves to the first scheduled time after now.
+//
+// A run that cannot be enqueued is put back and dispatched by the next pass,
+// under the same rules.
+func (s *ScheduleService) DispatchDue(ctx context.Context, now time.Time, limit int) (ScheduleSummary, error) {
+	var summary ScheduleSummary
+
+	schedules, err := s.scheduleRepo.ListDueSchedules(ctx, now, limit)
+	if err != nil {
+		return summary, err
+	}
+
+	for i := range schedules {
+		if err := s.dispatch(ctx, &schedules[i], now, &summary); err != nil {
+			log.Printf("Warning: Failed to dispatch schedule %s: %v", schedules[i].ID, err)
+			summary.Failed++
+		}
+	}
+	return summary, nil
+}
+
+func (s *ScheduleService) dispatch(ctx context.Context, schedule *model.Schedule, now time.Time, summary *ScheduleSummary) error {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	due := schedule.NextRunAt
+
+	claimed := *schedule
+	claimed.UpdatedAt = now
+	runAt, err := recurrence.LastCron(schedule.Cron, due, now, loc)
+	if err == nil {
+		claimed.NextRunAt, err = recurrence.NextCron(schedule.Cron, now, loc)
+	}
+	if err != nil {
+		// Disable a broken schedule instead of failing on every pass
+		claimed.Enabled = false
+		if _, claimErr := s.scheduleRepo.ClaimScheduleRun(ctx, &claimed, due); claimErr != nil {
+			log.Printf("Warning: Failed to disable schedule %s: %v", schedule.ID, claimErr)
+		}
+		return err
+	}
+
+	late := now.Sub(runAt) > s.grace
+	run := !late || schedule.CatchUp != model.CatchUpSkip
+	if run {
+		claimed.LastRunAt = runAt
+	}
+
+	// Overlapping passes race for the same scheduled time; only one claims it
+	ok, err := s.scheduleRepo.ClaimScheduleRun(ctx, &claimed, due)
+	if err != nil || !ok {
+		return err
+	}
+	if !run {
+		log.Printf("Skipped schedule %s: run at %s is %s late", schedule.ID, runAt.Format(time.RFC3339), now.Sub(runAt).Round(time.Second))
+		summary.Skipped++
+		return nil
+	}
+
+	threadID, err := s.thread(ctx, &claimed, now)
+	if err == nil {
+		err = s.jobQueue.Enqueue(ctx, queue.Job{
+			ThreadID:   threadID,
+			ScheduleID: claimed.ID,
+			Prompt:     claimed.Prompt,
+			RunAt:      runAt,
+		})
+	}
+	if err != nil {
+		// Put the scheduled time back so the next pass dispatches it
+		claimed.NextRunAt, claimed.LastRunAt = due, schedule.LastRunAt
+		if saveErr := s.scheduleRepo.SaveSchedule(context.WithoutCancel(ctx), &claimed); saveErr != nil {
+			log.Printf("Warning: Failed to put back schedule %s: %v", schedule.ID, saveErr)
+		}
+		return err
+	}
+	summary.Dispatched++
+	return nil
+}
+
+// thread returns the thread a run posts to, creating one for schedules that
+// start a new thread each time or whose thread is gone. A thread created for
+// the latter is kept on the schedule.
+func (s *ScheduleService) thread(ctx context.Context, schedule *model.Schedule, now time.Time) (string, error) {
+	if !schedule.NewThread && schedule.ThreadID != "" {
+		if thread, err := s.chatRepo.GetThread(ctx, schedule.ThreadID); err == nil && thread != nil {
+			return thread.ID, nil
+		}
+	}
+
+	thread := &model.ChatThread{
+		UserID:       schedule.UserID,
+		FirstMessage: schedule.Prompt,
+		Timezone:     schedule.Timezone,
+		LastReadAt:   now,
+		CreatedAt:    now,
+	}
+	if err := s.chatRepo.CreateThread(ctx, thread); err != nil {
+		return "", fmt.Errorf("failed to create thread: %w", err)
+	}
+	if !schedule.NewThread {
+		schedule.ThreadID = thread.ID
+		if err := s.scheduleRepo.SaveSchedule(ctx, schedule); err != nil {
+			log.Printf("Warning: Failed to keep thread %s on schedule %s: %v", thread.ID, schedule.ID, err)
+		}
+	}
+	return thread.ID, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"youdoyou-server/lock"
+
+	"github.com/google/uuid"
+)
+
+// lockPollInterval is how often an initiated run checks whether its thread is free
+const lockPollInterval = 2 * time.Second
+
+// ThreadSerializer runs at most one agent run per thread at a time. Replies
+// that arrive while one is active are coalesced into a single follow-up run, so
+// messages sent in quick succession are answered once and in order.
+type ThreadSerializer struct {
+	locker lock.Locker
+	run    func(ctx context.Context, threadID string, opts ChatOptions) error
+}
+
+func NewThreadSerializer(locker lock.Locker, run func(ctx context.Context, threadID string, opts ChatOptions) error) *ThreadSerializer {
+	return &ThreadSerializer{
+		locker: locker,
+		run:    run,
+	}
+}
+
+func (s *ThreadSerializer) Run(ctx context.Context, threadID string, opts ChatOptions) error {
+	owner := uuid.NewString()
+	if opts.Initiate != "" || opts.BranchID != "" || opts.Retry {
+		// Initiated runs, regenerations and retries can't be folded into a plain
+		// follow-up without losing their options; they wait their turn
+		if err := s.wait(ctx, threadID, owner); err != nil {
+			return err
+		}
+	} else {
+		acquired, err := s.locker.Acquire(ctx, threadID, owner, opts.ReplyTo)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			log.Printf("Thread %s is busy; coalesced into a follow-up run", threadID)
+			return nil
+		}
+	}
+
+	// Each follow-up gets the same time budget as the first run
+	var budget time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		budget = time.Until(deadline)
+	}
+	runCtx, cancel := ctx, context.CancelFunc(func() {})
+
+	for {
+		runErr := s.runOnce(runCtx, threadID, opts)
+		cancel()
+
+		// Release even if the run's context has ended, or the thread stays locked until the lease expires
+		messageID, followUp, err := s.locker.Release(context.WithoutCancel(ctx), threadID, owner)
+		if err != nil {
+			return errors.Join(runErr, err)
+		}
+		if !followUp {
+			return runErr
+		}
+		if runErr != nil {
+			log.Printf("Run for thread %s failed before its follow-up: %v", threadID, runErr)
+		}
+
+		log.Printf("Running follow-up for thread %s", threadID)
+		opts = ChatOptions{ReplyTo: messageID}
+		if budget > 0 {
+			runCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), budget)
+		}
+	}
+}
+
+// wait polls until owner holds the thread's lease or ctx ends
+func (s *ThreadSerializer) wait(ctx context.Context, threadID string, owner string) error {
+	for {
+		acquired, err := s.locker.TryAcquire(ctx, threadID, owner)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("thread %s stayed busy: %w", threadID, ctx.Err())
+		}
+	}
+}
+
+// runOnce turns a panic into an error so the lease is always released
+func (s *ThreadSerializer) runOnce(ctx context.Context, threadID string, opts ChatOptions) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("agent run panicked: %v", r)
+		}
+	}()
+	return s.run(ctx, threadID, opts)
+}
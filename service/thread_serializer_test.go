@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"youdoyou-server/lock"
+)
+
+// recordingRun records the ReplyTo of each run; the first run blocks until release is closed
+type recordingRun struct {
+	mu      sync.Mutex
+	replies []string
+	started chan struct{}
+	release chan struct{}
+}
+
+func newRecordingRun() *recordingRun {
+	return &recordingRun{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (r *recordingRun) run(ctx context.Context, threadID string, opts ChatOptions) error {
+	r.mu.Lock()
+	r.replies = append(r.replies, opts.ReplyTo)
+	first := len(r.replies) == 1
+	r.mu.Unlock()
+	if first {
+		close(r.started)
+		<-r.release
+	}
+	return nil
+}
+
+func TestSerializerCoalescesConcurrentRuns(t *testing.T) {
+	rec := newRecordingRun()
+	s := NewThreadSerializer(lock.NewMemoryLocker(), rec.run)
+
+	done := make(chan error)
+	go func() { done <- s.Run(context.Background(), "t1", ChatOptions{ReplyTo: "m1"}) }()
+	<-rec.started
+
+	// Both arrive while m1 is being answered and return at once
+	for _, id := range []string{"m3", "m2"} {
+		if err := s.Run(context.Background(), "t1", ChatOptions{ReplyTo: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(rec.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// One follow-up, for the latest message
+	if want := []string{"m1", "m3"}; !slices.Equal(rec.replies, want) {
+		t.Errorf("runs = %v, want %v", rec.replies, want)
+	}
+}
+
+func TestSerializerReleasesAfterPanic(t *testing.T) {
+	locker := lock.NewMemoryLocker()
+	s := NewThreadSerializer(locker, func(ctx context.Context, threadID string, opts ChatOptions) error {
+		panic("boom")
+	})
+
+	if err := s.Run(context.Background(), "t1", ChatOptions{}); err == nil {
+		t.Fatal("Run returned nil, want the panic as an error")
+	}
+	acquired, err := locker.TryAcquire(context.Background(), "t1", "other")
+	if err != nil || !acquired {
+		t.Errorf("TryAcquire = %v, %v, want the lease released", acquired, err)
+	}
+}
+
+func TestSerializerInitiatedRunWaitsForThread(t *testing.T) {
+	locker := lock.NewMemoryLocker()
+	if _, err := locker.Acquire(context.Background(), "t1", "holder", ""); err != nil {
+		t.Fatal(err)
+	}
+	ran := false
+	s := NewThreadSerializer(locker, func(ctx context.Context, threadID string, opts ChatOptions) error {
+		ran = true
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Run(ctx, "t1", ChatOptions{Retry: true}); err == nil {
+		t.Error("Run returned nil while the thread was busy")
+	}
+	if ran {
+		t.Error("the retry ran while another run held the thread")
+	}
+	// Nor was it folded into the holder's follow-up
+	if _, followUp, _ := locker.Release(context.Background(), "t1", "holder"); followUp {
+		t.Error("the retry was recorded as a follow-up")
+	}
+}
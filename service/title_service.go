@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// TitleModel is a cheap model used for short classification-style calls
+const TitleModel = "googleai/gemini-2.5-flash-lite"
+
+const maxTags = 3
+
+// ThreadTitle is the structured output of the title generation call
+type ThreadTitle struct {
+	Title string   `json:"title" jsonschema_description:"Short thread title, at most 20 characters"`
+	Tags  []string `json:"tags" jsonschema_description:"1 to 3 short lowercase topic tags"`
+}
+
+type TitleService struct {
+	chatRepo     repository.ChatRepository
+	genkitClient *genkit.Genkit
+}
+
+func NewTitleService(chatRepo repository.ChatRepository, genkitClient *genkit.Genkit) *TitleService {
+	return &TitleService{
+		chatRepo:     chatRepo,
+		genkitClient: genkitClient,
+	}
+}
+
+// Generate asks the model for a title and tags from the opening exchange of a thread
+func (s *TitleService) Generate(ctx context.Context, firstMessage string, reply string) (*ThreadTitle, error) {
+	prompt := fmt.Sprintf(`以下の会話の冒頭から、スレッドのタイトルとタグを作成してください。
+- title: 内容が一目で分かる20文字以内の日本語タイトル
+- tags: 話題を表す短いタグを1〜3個（英語の小文字、例: "work", "travel", "project-x"）
+
+【ユーザー】
+%s
+
+【アシスタント】
+%s`, firstMessage, reply)
+
+	out, _, err := genkit.GenerateData[ThreadTitle](ctx, s.genkitClient,
+		ai.WithModelName(TitleModel),
+		ai.WithPrompt(prompt),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("title generation failed: %w", err)
+	}
+
+	out.Title = strings.TrimSpace(out.Title)
+	out.Tags = normalizeTags(out.Tags)
+	return out, nil
+}
+
+// TitleThread generates and stores a title for the thread. Threads that already
+// have a title are left untouched.
+func (s *TitleService) TitleThread(ctx context.Context, thread *model.ChatThread, reply string) error {
+	if thread.Title != "" {
+		return nil
+	}
+
+	out, err := s.Generate(ctx, thread.FirstMessage, reply)
+	if err != nil {
+		return err
+	}
+	if out.Title == "" {
+		return fmt.Errorf("model returned an empty title")
+	}
+
+	if err := s.chatRepo.UpdateThreadTitle(ctx, thread.ID, out.Title, out.Tags); err != nil {
+		return fmt.Errorf("failed to save title: %w", err)
+	}
+
+	log.Printf("Thread %s titled: %s %v", thread.ID, out.Title, out.Tags)
+	thread.Title = out.Title
+	thread.Tags = out.Tags
+	return nil
+}
+
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		tag = strings.ReplaceAll(tag, " ", "-")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+		if len(result) == maxTags {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"youdoyou-server/model"
+	"youdoyou-server/test"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// titleChatRepository records the stored title
+type titleChatRepository struct {
+	test.MockChatRepository
+	title string
+	tags  []string
+}
+
+func (r *titleChatRepository) UpdateThreadTitle(ctx context.Context, threadID string, title string, tags []string) error {
+	r.title, r.tags = title, tags
+	return nil
+}
+
+// newTitleGenkit answers every TitleModel call with output
+func newTitleGenkit(output string) *genkit.Genkit {
+	g := genkit.Init(context.Background())
+	genkit.DefineModel(g, TitleModel, &ai.ModelOptions{Supports: &ai.ModelSupports{Constrained: ai.ConstrainedSupportAll}},
+		func(ctx context.Context, req *ai.ModelRequest, cb ai.ModelStreamCallback) (*ai.ModelResponse, error) {
+			return &ai.ModelResponse{Request: req, Message: ai.NewModelTextMessage(output)}, nil
+		})
+	return g
+}
+
+func TestTitleThread(t *testing.T) {
+	chatRepo := &titleChatRepository{}
+	s := NewTitleService(chatRepo, newTitleGenkit(`{"title":" 資料レビューの調整 ","tags":["Work","work","Project X","travel","extra"]}`))
+	thread := &model.ChatThread{ID: "t1", FirstMessage: "明日資料をレビューしたい"}
+
+	if err := s.TitleThread(context.Background(), thread, "10時はいかがですか？"); err != nil {
+		t.Fatal(err)
+	}
+	if chatRepo.title != "資料レビューの調整" || thread.Title != "資料レビューの調整" {
+		t.Errorf("title = %q, want it trimmed", chatRepo.title)
+	}
+	// Lowercased, deduplicated, hyphenated and capped
+	if want := []string{"work", "project-x", "travel"}; !slices.Equal(chatRepo.tags, want) {
+		t.Errorf("tags = %v, want %v", chatRepo.tags, want)
+	}
+}
+
+func TestTitleThreadKeepsExistingTitle(t *testing.T) {
+	chatRepo := &titleChatRepository{}
+	s := NewTitleService(chatRepo, newTitleGenkit(`{"title":"new","tags":[]}`))
+
+	if err := s.TitleThread(context.Background(), &model.ChatThread{ID: "t1", Title: "old"}, "reply"); err != nil {
+		t.Fatal(err)
+	}
+	if chatRepo.title != "" {
+		t.Errorf("title overwritten with %q", chatRepo.title)
+	}
+}
+
+func TestTitleThreadRejectsEmptyTitle(t *testing.T) {
+	chatRepo := &titleChatRepository{}
+	s := NewTitleService(chatRepo, newTitleGenkit(`{"title":"  ","tags":["a"]}`))
+
+	if err := s.TitleThread(context.Background(), &model.ChatThread{ID: "t1"}, "reply"); err == nil {
+		t.Error("TitleThread stored an empty title")
+	}
+	if chatRepo.title != "" || chatRepo.tags != nil {
+		t.Errorf("stored %q %v, want nothing", chatRepo.title, chatRepo.tags)
+	}
+}
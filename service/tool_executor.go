@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"youdoyou-server/tool"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// ToolExecutor runs the tool requests of one model turn, in parallel where
+// the tools allow it
+type ToolExecutor struct {
+	concurrency int
+	timeout     time.Duration
+}
+
+// NewToolExecutor runs at most concurrency tool calls at a time and gives up
+// on a call after timeout (0 means no limit)
+func NewToolExecutor(concurrency int, timeout time.Duration) *ToolExecutor {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ToolExecutor{
+		concurrency: concurrency,
+		timeout:     timeout,
+	}
+}
+
+// Run returns the responses to reqs in request order. A tool that is not
+// parallel-safe waits for the calls before it and runs alone. stop is checked
+// before each call starts; if it returns true, Run waits for the calls already
+// started and returns false.
+func (e *ToolExecutor) Run(ctx context.Context, tools map[string]ai.Tool, reqs []*ai.ToolRequest, stop func() bool) ([]*ai.Part, bool) {
+	parts := make([]*ai.Part, len(reqs))
+	sem := make(chan struct{}, e.concurrency)
+	var wg sync.WaitGroup
+
+	for i, req := range reqs {
+		if stop() {
+			wg.Wait()
+			return nil, false
+		}
+
+		// Denied calls get a structured refusal the model can explain to the user
+		if refusal, ok := tool.RunContextFrom(ctx).Permissions.Check(req.Name); !ok {
+			log.Printf("Tool call denied: %s: %s", req.Name, refusal.Reason)
+			parts[i] = toolResponsePart(req, refusal)
+			continue
+		}
+
+		t, ok := tools[req.Name]
+		if !ok {
+			log.Printf("Tool not found: %s", req.Name)
+			parts[i] = toolResponsePart(req, fmt.Sprintf("Error: Tool %s not found", req.Name))
+			continue
+		}
+
+		if !tool.ParallelSafe(req.Name) {
+			wg.Wait()
+			parts[i] = e.call(ctx, t, req)
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, t ai.Tool, req *ai.ToolRequest) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			parts[i] = e.call(ctx, t, req)
+		}(i, t, req)
+	}
+
+	wg.Wait()
+	return parts, true
+}
+
+// call runs one tool and turns its output or error into a response part.
+// A call that outlives the timeout is abandoned and reported to the model.
+func (e *ToolExecutor) call(ctx context.Context, t ai.Tool, req *ai.ToolRequest) *ai.Part {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		out any
+		err error
+	}
+	// Buffered so an abandoned call can still finish
+	done := make(chan result, 1)
+
+	log.Printf("Running tool: %s", req.Name)
+	started := time.Now()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("tool panicked: %v", r)}
+			}
+		}()
+		out, err := t.RunRaw(ctx, req.Input)
+		done <- result{out: out, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			log.Printf("Tool execution failed: %s: %v", req.Name, res.err)
+			return toolResponsePart(req, fmt.Sprintf("Error: %v", res.err))
+		}
+		log.Printf("Tool %s finished in %s", req.Name, time.Since(started).Round(time.Millisecond))
+		return toolResponsePart(req, res.out)
+	case <-ctx.Done():
+		log.Printf("Tool %s stopped after %s: %v", req.Name, time.Since(started).Round(time.Millisecond), ctx.Err())
+		return toolResponsePart(req, fmt.Sprintf("Error: Tool %s did not finish: %v", req.Name, ctx.Err()))
+	}
+}
+
+func toolResponsePart(req *ai.ToolRequest, output any) *ai.Part {
+	return ai.NewToolResponsePart(&ai.ToolResponse{
+		Name:   req.Name,
+		Ref:    req.Ref,
+		Output: output,
+	})
+}
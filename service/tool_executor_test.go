@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"youdoyou-server/tool"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+type echoInput struct {
+	Text string `json:"text"`
+}
+
+func defineTestTool(g *genkit.Genkit, name string, fn func(ctx context.Context, in echoInput) (string, error)) ai.Tool {
+	return genkit.DefineTool(g, name, name, func(tc *ai.ToolContext, in echoInput) (string, error) {
+		return fn(tc, in)
+	})
+}
+
+func toolRequest(name string, text string) *ai.ToolRequest {
+	return &ai.ToolRequest{Name: name, Ref: name + "-" + text, Input: map[string]any{"text": text}}
+}
+
+func outputOf(part *ai.Part) string {
+	out, _ := part.ToolResponse.Output.(string)
+	return out
+}
+
+func TestToolExecutorRunsInParallel(t *testing.T) {
+	g := genkit.Init(context.Background())
+	// Each call waits for the other, so they only finish if run together
+	arrived := make(chan struct{}, 2)
+	both := func(ctx context.Context, in echoInput) (string, error) {
+		arrived <- struct{}{}
+		for len(arrived) < 2 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+		}
+		return in.Text, nil
+	}
+	tools := map[string]ai.Tool{"testParallel": defineTestTool(g, "testParallel", both)}
+
+	e := NewToolExecutor(2, 5*time.Second)
+	parts, ok := e.Run(context.Background(), tools, []*ai.ToolRequest{
+		toolRequest("testParallel", "a"), toolRequest("testParallel", "b"),
+	}, func() bool { return false })
+	if !ok {
+		t.Fatal("Run stopped")
+	}
+	// Responses keep the request order
+	if outputOf(parts[0]) != "a" || outputOf(parts[1]) != "b" {
+		t.Errorf("outputs = %q, %q, want a, b", outputOf(parts[0]), outputOf(parts[1]))
+	}
+}
+
+func TestToolExecutorRunsSequentialToolsAlone(t *testing.T) {
+	g := genkit.Init(context.Background())
+	var order []string
+	record := func(ctx context.Context, in echoInput) (string, error) {
+		order = append(order, in.Text)
+		return in.Text, nil
+	}
+	tools := map[string]ai.Tool{
+		"testSequential": tool.MarkSequential(defineTestTool(g, "testSequential", record)),
+	}
+
+	e := NewToolExecutor(4, 0)
+	_, ok := e.Run(context.Background(), tools, []*ai.ToolRequest{
+		toolRequest("testSequential", "1"), toolRequest("testSequential", "2"), toolRequest("testSequential", "3"),
+	}, func() bool { return false })
+	if !ok {
+		t.Fatal("Run stopped")
+	}
+	if strings.Join(order, ",") != "1,2,3" {
+		t.Errorf("order = %v, want 1,2,3", order)
+	}
+}
+
+func TestToolExecutorReportsFailures(t *testing.T) {
+	g := genkit.Init(context.Background())
+	tools := map[string]ai.Tool{
+		"testSlow": defineTestTool(g, "testSlow", func(ctx context.Context, in echoInput) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}),
+		"testPanic": defineTestTool(g, "testPanic", func(ctx context.Context, in echoInput) (string, error) {
+			panic("boom")
+		}),
+		"testWriter": tool.MarkExternalWrite(defineTestTool(g, "testWriter", func(ctx context.Context, in echoInput) (string, error) {
+			t.Error("denied tool ran")
+			return "", nil
+		})),
+	}
+	ctx := tool.WithRunContext(context.Background(), tool.RunContext{Permissions: tool.Permissions{ReadOnly: true}})
+
+	e := NewToolExecutor(4, 50*time.Millisecond)
+	parts, _ := e.Run(ctx, tools, []*ai.ToolRequest{
+		toolRequest("testSlow", "x"), toolRequest("testPanic", "x"), toolRequest("testMissing", "x"), toolRequest("testWriter", "x"),
+	}, func() bool { return false })
+
+	for i, want := range []string{"did not finish", "panicked", "not found"} {
+		if out := outputOf(parts[i]); !strings.Contains(out, want) {
+			t.Errorf("parts[%d] = %q, want it to contain %q", i, out, want)
+		}
+	}
+	if refusal, ok := parts[3].ToolResponse.Output.(*tool.Refusal); !ok || refusal.Status != "denied" {
+		t.Errorf("parts[3] = %#v, want a refusal", parts[3].ToolResponse.Output)
+	}
+}
+
+func TestToolExecutorStops(t *testing.T) {
+	g := genkit.Init(context.Background())
+	calls := 0
+	tools := map[string]ai.Tool{"testCount": tool.MarkSequential(defineTestTool(g, "testCount", func(ctx context.Context, in echoInput) (string, error) {
+		calls++
+		return "", nil
+	}))}
+
+	e := NewToolExecutor(1, 0)
+	parts, ok := e.Run(context.Background(), tools, []*ai.ToolRequest{
+		toolRequest("testCount", "1"), toolRequest("testCount", "2"),
+	}, func() bool { return calls > 0 })
+	if ok || parts != nil {
+		t.Errorf("Run = %v, %v, want stopped", parts, ok)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
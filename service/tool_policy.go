@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+	"youdoyou-server/tool"
+)
+
+// ToolPolicyDefaults are the server-wide tool permissions from config.Config
+type ToolPolicyDefaults struct {
+	// Groups are the tool groups exposed when no policy narrows them
+	Groups []string
+	// ReadOnlyUsers may not use tools that write to external services
+	ReadOnlyUsers []string
+	// PrivateThreadWrites allows external write tools in private threads
+	PrivateThreadWrites bool
+}
+
+// ToolPolicyService resolves which tools a run may use from the defaults and
+// the user's and thread's toolPolicies documents
+type ToolPolicyService struct {
+	policyRepo repository.ToolPolicyRepository
+	defaults   ToolPolicyDefaults
+}
+
+func NewToolPolicyService(policyRepo repository.ToolPolicyRepository, defaults ToolPolicyDefaults) *ToolPolicyService {
+	return &ToolPolicyService{
+		policyRepo: policyRepo,
+		defaults:   defaults,
+	}
+}
+
+// Resolve returns the permissions for a run on thread. Policies only narrow
+// the defaults. If a policy cannot be read, or the thread is unknown, the run
+// is read-only, along with the error.
+func (s *ToolPolicyService) Resolve(ctx context.Context, thread *model.ChatThread) (tool.Permissions, error) {
+	if thread == nil {
+		// Without the thread there is no owner or policy to check
+		perms := tool.Permissions{Groups: slices.Clone(s.defaults.Groups), ReadOnly: true, Reason: "thread is unavailable"}
+		return perms, errors.New("thread is unavailable")
+	}
+
+	perms := s.userDefaults(thread.UserID)
+	if !perms.ReadOnly && thread.IsPrivate && !s.defaults.PrivateThreadWrites {
+		perms.ReadOnly, perms.Reason = true, "this thread is private"
+	}
+	return s.applyPolicies(ctx, perms, repository.UserPolicyID(thread.UserID), repository.ThreadPolicyID(thread.ID))
+}
+
+// ResolveUser returns the permissions for tool calls made by a user outside
+// any thread (e.g. over MCP)
+func (s *ToolPolicyService) ResolveUser(ctx context.Context, userID string) (tool.Permissions, error) {
+	return s.applyPolicies(ctx, s.userDefaults(userID), repository.UserPolicyID(userID))
+}
+
+func (s *ToolPolicyService) userDefaults(userID string) tool.Permissions {
+	perms := tool.Permissions{Groups: slices.Clone(s.defaults.Groups)}
+	if slices.Contains(s.defaults.ReadOnlyUsers, userID) {
+		perms.ReadOnly, perms.Reason = true, "this user is read-only"
+	}
+	return perms
+}
+
+// applyPolicies narrows perms by the toolPolicies documents with the given IDs
+func (s *ToolPolicyService) applyPolicies(ctx context.Context, perms tool.Permissions, ids ...string) (tool.Permissions, error) {
+	for _, id := range ids {
+		policy, err := s.policyRepo.GetToolPolicy(ctx, id)
+		if err != nil {
+			perms.ReadOnly, perms.Reason = true, "tool policy is unavailable"
+			return perms, err
+		}
+		if policy != nil {
+			applyToolPolicy(&perms, policy)
+		}
+	}
+	return perms, nil
+}
+
+// applyToolPolicy narrows perms by policy; the most restrictive setting wins
+func applyToolPolicy(perms *tool.Permissions, policy *model.ToolPolicy) {
+	if len(policy.Groups) > 0 {
+		var groups []string
+		for _, g := range perms.Groups {
+			if slices.Contains(policy.Groups, g) {
+				groups = append(groups, g)
+			}
+		}
+		// Non-nil even when empty, so no group is exposed
+		perms.Groups = append([]string{}, groups...)
+	}
+	perms.DeniedTools = append(perms.DeniedTools, policy.DenyTools...)
+	if policy.ReadOnly && !perms.ReadOnly {
+		perms.ReadOnly, perms.Reason = true, "restricted by policy"
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"youdoyou-server/model"
+	"youdoyou-server/repository"
+	"youdoyou-server/test"
+)
+
+// failingPolicyRepository cannot read any policy
+type failingPolicyRepository struct {
+	test.MockToolPolicyRepository
+}
+
+func (r *failingPolicyRepository) GetToolPolicy(ctx context.Context, id string) (*model.ToolPolicy, error) {
+	return nil, errors.New("unavailable")
+}
+
+func newPolicyTest(defaults ToolPolicyDefaults, policies ...model.ToolPolicy) *ToolPolicyService {
+	repo := &test.MockToolPolicyRepository{}
+	for _, p := range policies {
+		_ = repo.SaveToolPolicy(context.Background(), &p)
+	}
+	return NewToolPolicyService(repo, defaults)
+}
+
+func TestResolveUsesDefaults(t *testing.T) {
+	s := newPolicyTest(ToolPolicyDefaults{Groups: []string{"calendar", "search"}})
+
+	perms, err := s.Resolve(context.Background(), &model.ChatThread{ID: "t1", UserID: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perms.ReadOnly || !slices.Equal(perms.Groups, []string{"calendar", "search"}) {
+		t.Errorf("perms = %+v, want the default groups and writes allowed", perms)
+	}
+}
+
+func TestResolveNarrowsByUserAndThreadPolicy(t *testing.T) {
+	s := newPolicyTest(ToolPolicyDefaults{Groups: []string{"calendar", "notion", "search"}},
+		model.ToolPolicy{ID: repository.UserPolicyID("u1"), Groups: []string{"calendar", "search", "tasks"}, DenyTools: []string{"createNotionPage"}},
+		model.ToolPolicy{ID: repository.ThreadPolicyID("t1"), Groups: []string{"search"}, ReadOnly: true},
+	)
+
+	perms, err := s.Resolve(context.Background(), &model.ChatThread{ID: "t1", UserID: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A policy cannot add a group the defaults do not expose
+	if !slices.Equal(perms.Groups, []string{"search"}) {
+		t.Errorf("Groups = %v, want [search]", perms.Groups)
+	}
+	if !slices.Contains(perms.DeniedTools, "createNotionPage") {
+		t.Errorf("DeniedTools = %v, want createNotionPage", perms.DeniedTools)
+	}
+	if !perms.ReadOnly || perms.Reason != "restricted by policy" {
+		t.Errorf("ReadOnly = %v (%q), want read-only by policy", perms.ReadOnly, perms.Reason)
+	}
+}
+
+func TestResolvePolicyWithoutMatchingGroupsExposesNone(t *testing.T) {
+	s := newPolicyTest(ToolPolicyDefaults{Groups: []string{"calendar"}},
+		model.ToolPolicy{ID: repository.ThreadPolicyID("t1"), Groups: []string{"notion"}},
+	)
+
+	perms, err := s.Resolve(context.Background(), &model.ChatThread{ID: "t1", UserID: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nil would mean every group
+	if perms.Groups == nil || len(perms.Groups) != 0 {
+		t.Errorf("Groups = %#v, want an empty non-nil list", perms.Groups)
+	}
+}
+
+func TestResolvePrivateThreadIsReadOnly(t *testing.T) {
+	thread := &model.ChatThread{ID: "t1", UserID: "u1", IsPrivate: true}
+
+	perms, _ := newPolicyTest(ToolPolicyDefaults{}).Resolve(context.Background(), thread)
+	if !perms.ReadOnly || perms.Reason != "this thread is private" {
+		t.Errorf("perms = %+v, want read-only for a private thread", perms)
+	}
+
+	perms, _ = newPolicyTest(ToolPolicyDefaults{PrivateThreadWrites: true}).Resolve(context.Background(), thread)
+	if perms.ReadOnly {
+		t.Errorf("perms = %+v, want writes allowed with PrivateThreadWrites", perms)
+	}
+}
+
+func TestResolveReadOnlyUser(t *testing.T) {
+	s := newPolicyTest(ToolPolicyDefaults{ReadOnlyUsers: []string{"u1"}})
+
+	perms, _ := s.ResolveUser(context.Background(), "u1")
+	if !perms.ReadOnly || perms.Reason != "this user is read-only" {
+		t.Errorf("u1 perms = %+v, want read-only", perms)
+	}
+	perms, _ = s.ResolveUser(context.Background(), "u2")
+	if perms.ReadOnly {
+		t.Errorf("u2 perms = %+v, want writes allowed", perms)
+	}
+}
+
+func TestResolveFailsClosed(t *testing.T) {
+	s := NewToolPolicyService(&failingPolicyRepository{}, ToolPolicyDefaults{})
+
+	perms, err := s.Resolve(context.Background(), &model.ChatThread{ID: "t1", UserID: "u1"})
+	if err == nil || !perms.ReadOnly {
+		t.Errorf("Resolve = %+v, %v, want read-only with an error", perms, err)
+	}
+
+	perms, err = s.Resolve(context.Background(), nil)
+	if err == nil || !perms.ReadOnly {
+		t.Errorf("Resolve(nil) = %+v, %v, want read-only with an error", perms, err)
+	}
+}
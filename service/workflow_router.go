@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"youdoyou-server/model"
+	"youdoyou-server/tool"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// Routing modes of WorkflowRouter
+const (
+	// RoutingRules picks groups by keyword and exposes every group when none matches
+	RoutingRules = "rules"
+	// RoutingModel falls back to a cheap model call when no keyword matches
+	RoutingModel = "model"
+	// RoutingOff exposes every group
+	RoutingOff = "off"
+)
+
+// maxRouterContext limits the previous reply sent to the router model
+const maxRouterContext = 500
+
+// routingRules are keywords that select a tool group. Matching is
+// case-insensitive on the latest user message.
+var routingRules = []struct {
+	group    string
+	keywords []string
+}{
+	{tool.GroupCalendar, []string{"予定", "カレンダー", "スケジュール", "会議", "ミーティング", "打ち合わせ", "calendar", "schedule", "meeting"}},
+	{tool.GroupNotion, []string{"notion", "ノーション", "タスク", "todo", "データベース", "ページ", "議事録", "登録"}},
+	{tool.GroupTasks, []string{"リマインド", "リマインダー", "思い出させて", "知らせて", "やること", "タスク", "todo", "remind"}},
+	{tool.GroupSchedules, []string{"毎朝", "毎晩", "毎日", "毎週", "毎月", "定期", "自動で", "every day", "every morning", "every week"}},
+	{tool.GroupSearch, []string{"以前", "前に", "前回", "この前", "先週", "決めた", "話した", "覚えて", "思い出し", "remember", "last time"}},
+}
+
+// RouteChoice is the structured output of the router model call
+type RouteChoice struct {
+	Groups []string `json:"groups" jsonschema_description:"Tool groups needed to answer: calendar, notion, search, tasks, schedules, mcp. Empty if no tool is needed"`
+	Reason string   `json:"reason" jsonschema_description:"One short sentence explaining the choice"`
+}
+
+// WorkflowRouter decides which tool groups the agent sees for a message, so
+// only the relevant tool schemas go into the prompt
+type WorkflowRouter struct {
+	genkitClient *genkit.Genkit
+	mode         string
+}
+
+func NewWorkflowRouter(genkitClient *genkit.Genkit, mode string) *WorkflowRouter {
+	return &WorkflowRouter{
+		genkitClient: genkitClient,
+		mode:         mode,
+	}
+}
+
+// Route classifies the message being answered. previous is the assistant
+// reply before it, used as context by the model. It never fails; when in
+// doubt every group is exposed.
+func (r *WorkflowRouter) Route(ctx context.Context, message string, previous string) *model.ToolRouting {
+	if r.mode == RoutingOff {
+		return &model.ToolRouting{Groups: tool.AllGroups, Method: model.RoutingMethodOff}
+	}
+
+	if groups, matched := matchRoutingRules(message); len(groups) > 0 {
+		return &model.ToolRouting{
+			Groups: groups,
+			Method: model.RoutingMethodRules,
+			Reason: "keywords: " + strings.Join(matched, ", "),
+		}
+	}
+
+	if r.mode != RoutingModel {
+		return &model.ToolRouting{Groups: tool.AllGroups, Method: model.RoutingMethodFallback, Reason: "no keyword matched"}
+	}
+
+	choice, err := r.classify(ctx, message, previous)
+	if err != nil {
+		log.Printf("Warning: Tool routing failed, exposing all tools: %v", err)
+		return &model.ToolRouting{Groups: tool.AllGroups, Method: model.RoutingMethodFallback, Reason: err.Error()}
+	}
+	return &model.ToolRouting{Groups: choice.Groups, Method: model.RoutingMethodModel, Reason: choice.Reason}
+}
+
+// matchRoutingRules returns the groups whose keywords appear in message and
+// the keywords that matched
+func matchRoutingRules(message string) ([]string, []string) {
+	text := strings.ToLower(message)
+	var groups, matched []string
+	for _, rule := range routingRules {
+		for _, kw := range rule.keywords {
+			if strings.Contains(text, kw) {
+				groups = append(groups, rule.group)
+				matched = append(matched, kw)
+				break
+			}
+		}
+	}
+	return groups, matched
+}
+
+// classify asks a cheap model which groups the message needs
+func (r *WorkflowRouter) classify(ctx context.Context, message string, previous string) (*RouteChoice, error) {
+	if runes := []rune(previous); len(runes) > maxRouterContext {
+		previous = string(runes[len(runes)-maxRouterContext:])
+	}
+
+	prompt := fmt.Sprintf(`ユーザーのメッセージに答えるために必要なツールのグループを選んでください。
+- calendar: 予定・カレンダーの確認
+- notion: Notion のタスクやページの検索・作成
+- search: 過去の会話の検索（以前の話題や決定事項を思い出す）
+- tasks: やること・リマインダーの登録、確認、完了
+- schedules: 定期実行（「毎朝〜して」など、決まった時間にアシスタントが自動で行う依頼）の登録、確認、削除
+- mcp: 外部サービスのツール（上記以外の外部連携）
+ツールが不要な場合（雑談、一般的な質問など）は空にしてください。
+
+【直前のアシスタントの返信】
+%s
+
+【ユーザー】
+%s`, previous, message)
+
+	out, _, err := genkit.GenerateData[RouteChoice](ctx, r.genkitClient,
+		ai.WithModelName(TitleModel),
+		ai.WithPrompt(prompt),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("routing call failed: %w", err)
+	}
+
+	// Drop groups the model made up
+	var groups []string
+	for _, g := range out.Groups {
+		g = strings.ToLower(strings.TrimSpace(g))
+		if slices.Contains(tool.AllGroups, g) && !slices.Contains(groups, g) {
+			groups = append(groups, g)
+		}
+	}
+	out.Groups = groups
+	return out, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"youdoyou-server/model"
+	"youdoyou-server/tool"
+)
+
+func TestRouteByKeywords(t *testing.T) {
+	r := NewWorkflowRouter(nil, RoutingRules)
+
+	cases := []struct {
+		message string
+		groups  []string
+	}{
+		{"明日の予定を教えて", []string{tool.GroupCalendar}},
+		{"Check my Calendar", []string{tool.GroupCalendar}},
+		{"先週話した会議の件", []string{tool.GroupCalendar, tool.GroupSearch}},
+		{"毎朝ニュースをまとめて", []string{tool.GroupSchedules}},
+	}
+	for _, c := range cases {
+		routing := r.Route(context.Background(), c.message, "")
+		if routing.Method != model.RoutingMethodRules || !slices.Equal(routing.Groups, c.groups) {
+			t.Errorf("Route(%q) = %s %v, want rules %v", c.message, routing.Method, routing.Groups, c.groups)
+		}
+	}
+}
+
+func TestRouteFallsBackToAllGroups(t *testing.T) {
+	// No keyword matches; rules mode does not call the model
+	routing := NewWorkflowRouter(nil, RoutingRules).Route(context.Background(), "こんにちは", "")
+	if routing.Method != model.RoutingMethodFallback || !slices.Equal(routing.Groups, tool.AllGroups) {
+		t.Errorf("Route = %s %v, want fallback with every group", routing.Method, routing.Groups)
+	}
+
+	routing = NewWorkflowRouter(nil, RoutingOff).Route(context.Background(), "明日の予定を教えて", "")
+	if routing.Method != model.RoutingMethodOff || !slices.Equal(routing.Groups, tool.AllGroups) {
+		t.Errorf("Route = %s %v, want off with every group", routing.Method, routing.Groups)
+	}
+}
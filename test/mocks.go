@@ -2,9 +2,14 @@ package test
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"time"
 
+	"youdoyou-server/channel"
 	"youdoyou-server/model"
+	"youdoyou-server/notify"
+	"youdoyou-server/queue"
 	"youdoyou-server/repository"
 )
 
@@ -19,6 +24,18 @@ func (m *MockChatRepository) GetUnmemorizedMessages(ctx context.Context, threadI
 	return []model.ChatMessage{}, nil
 }
 
+func (m *MockChatRepository) GetMessages(ctx context.Context, threadID string) ([]model.ChatMessage, error) {
+	return []model.ChatMessage{}, nil
+}
+
+func (m *MockChatRepository) GetMessagesUpTo(ctx context.Context, threadID string, messageID string) ([]model.ChatMessage, error) {
+	return []model.ChatMessage{}, nil
+}
+
+func (m *MockChatRepository) GetMessage(ctx context.Context, threadID string, messageID string) (*model.ChatMessage, error) {
+	return &model.ChatMessage{ID: messageID, ThreadID: threadID, Role: model.RoleUser}, nil
+}
+
 func (m *MockChatRepository) GetThread(ctx context.Context, threadID string) (*model.ChatThread, error) {
 	return &model.ChatThread{
 		ID:             threadID,
@@ -31,10 +48,42 @@ func (m *MockChatRepository) SaveMessage(ctx context.Context, message *model.Cha
 	return "mock_id", nil
 }
 
+func (m *MockChatRepository) CountMessage(ctx context.Context, threadID string, messageID string) error {
+	return nil
+}
+
 func (m *MockChatRepository) CreateThread(ctx context.Context, thread *model.ChatThread) error {
 	return nil
 }
 
+func (m *MockChatRepository) ListThreads(ctx context.Context, userID string, tag string) ([]model.ChatThread, error) {
+	return []model.ChatThread{}, nil
+}
+
+func (m *MockChatRepository) UpdateThreadTitle(ctx context.Context, threadID string, title string, tags []string) error {
+	return nil
+}
+
+func (m *MockChatRepository) UpdateMessageStatus(ctx context.Context, threadID string, messageID string, status string, errorCode string, retryable bool) error {
+	return nil
+}
+
+func (m *MockChatRepository) RequestCancel(ctx context.Context, threadID string, at time.Time) error {
+	return nil
+}
+
+func (m *MockChatRepository) SetActiveBranch(ctx context.Context, threadID string, branchID string) error {
+	return nil
+}
+
+func (m *MockChatRepository) SetMessageBranch(ctx context.Context, threadID string, messageID string, parentID string, branchID string) error {
+	return nil
+}
+
+func (m *MockChatRepository) RestoreThread(ctx context.Context, thread *model.ChatThread, messages []model.ChatMessage) error {
+	return nil
+}
+
 // Mock CalendarRepository
 type MockCalendarRepository struct {
 	events []model.CalendarEvent
@@ -60,3 +109,390 @@ func (m *MockNotionRepository) QueryDatabase(ctx context.Context, databaseID str
 func (m *MockNotionRepository) CreatePage(ctx context.Context, databaseID string, properties map[string]interface{}) (string, error) {
 	return "page_id", nil
 }
+
+// Mock SearchRepository
+type MockSearchRepository struct {
+	entries []model.SearchEntry
+}
+
+// Ensure interface compliance
+var _ repository.SearchRepository = &MockSearchRepository{}
+
+func (m *MockSearchRepository) IndexMessage(ctx context.Context, entry *model.SearchEntry) error {
+	for i := range m.entries {
+		if m.entries[i].ID == entry.ID {
+			m.entries[i] = *entry
+			return nil
+		}
+	}
+	m.entries = append(m.entries, *entry)
+	return nil
+}
+
+func (m *MockSearchRepository) FindByToken(ctx context.Context, userID string, token string, limit int) ([]model.SearchEntry, error) {
+	var result []model.SearchEntry
+	for _, e := range m.entries {
+		if e.UserID == userID && slices.Contains(e.Tokens, token) {
+			result = append(result, e)
+		}
+	}
+	slices.SortStableFunc(result, func(a, b model.SearchEntry) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// Mock FailedRunRepository
+type MockFailedRunRepository struct {
+	runs map[string]model.FailedRun
+}
+
+// Ensure interface compliance
+var _ repository.FailedRunRepository = &MockFailedRunRepository{}
+
+func (m *MockFailedRunRepository) GetFailedRun(ctx context.Context, id string) (*model.FailedRun, error) {
+	run, ok := m.runs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &run, nil
+}
+
+func (m *MockFailedRunRepository) SaveFailedRun(ctx context.Context, run *model.FailedRun) error {
+	if m.runs == nil {
+		m.runs = make(map[string]model.FailedRun)
+	}
+	m.runs[run.ID] = *run
+	return nil
+}
+
+func (m *MockFailedRunRepository) ListDueFailedRuns(ctx context.Context, now time.Time, limit int) ([]model.FailedRun, error) {
+	var result []model.FailedRun
+	for _, run := range m.runs {
+		if run.Status == model.FailedRunStatusPending && !run.NextAttemptAt.After(now) {
+			result = append(result, run)
+		}
+	}
+	return result, nil
+}
+
+// Mock ToolPolicyRepository
+type MockToolPolicyRepository struct {
+	policies map[string]model.ToolPolicy
+}
+
+// Ensure interface compliance
+var _ repository.ToolPolicyRepository = &MockToolPolicyRepository{}
+
+func (m *MockToolPolicyRepository) GetToolPolicy(ctx context.Context, id string) (*model.ToolPolicy, error) {
+	policy, ok := m.policies[id]
+	if !ok {
+		return nil, nil
+	}
+	return &policy, nil
+}
+
+func (m *MockToolPolicyRepository) SaveToolPolicy(ctx context.Context, policy *model.ToolPolicy) error {
+	if m.policies == nil {
+		m.policies = make(map[string]model.ToolPolicy)
+	}
+	m.policies[policy.ID] = *policy
+	return nil
+}
+
+// Mock TaskRepository
+type MockTaskRepository struct {
+	tasks map[string]model.Task
+}
+
+// Ensure interface compliance
+var _ repository.TaskRepository = &MockTaskRepository{}
+
+func (m *MockTaskRepository) SaveTask(ctx context.Context, task *model.Task) error {
+	if m.tasks == nil {
+		m.tasks = make(map[string]model.Task)
+	}
+	if task.ID == "" {
+		task.ID = fmt.Sprintf("task-%d", len(m.tasks)+1)
+	}
+	m.tasks[task.ID] = *task
+	return nil
+}
+
+func (m *MockTaskRepository) GetTask(ctx context.Context, id string) (*model.Task, error) {
+	task, ok := m.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task %s not found", id)
+	}
+	return &task, nil
+}
+
+func (m *MockTaskRepository) DeleteTask(ctx context.Context, id string) error {
+	delete(m.tasks, id)
+	return nil
+}
+
+func (m *MockTaskRepository) ListTasks(ctx context.Context, userID string, includeCompleted bool) ([]model.Task, error) {
+	var result []model.Task
+	for _, task := range m.tasks {
+		if task.UserID == userID && (includeCompleted || !task.Completed) {
+			result = append(result, task)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTaskRepository) ListDueTasks(ctx context.Context, now time.Time, limit int) ([]model.Task, error) {
+	var result []model.Task
+	for _, task := range m.tasks {
+		if !task.Completed && !task.DueAt.IsZero() && !task.DueAt.After(now) && !task.RemindedAt.Equal(task.DueAt) {
+			result = append(result, task)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockTaskRepository) ClaimReminder(ctx context.Context, task *model.Task) (bool, error) {
+	current, ok := m.tasks[task.ID]
+	if !ok || current.Completed || !current.DueAt.Equal(task.DueAt) || current.RemindedAt.Equal(current.DueAt) {
+		return false, nil
+	}
+	current.RemindedAt = current.DueAt
+	m.tasks[task.ID] = current
+	return true, nil
+}
+
+// Mock ScheduleRepository
+type MockScheduleRepository struct {
+	schedules map[string]model.Schedule
+}
+
+// Ensure interface compliance
+var _ repository.ScheduleRepository = &MockScheduleRepository{}
+
+func (m *MockScheduleRepository) SaveSchedule(ctx context.Context, schedule *model.Schedule) error {
+	if m.schedules == nil {
+		m.schedules = make(map[string]model.Schedule)
+	}
+	if schedule.ID == "" {
+		schedule.ID = fmt.Sprintf("schedule-%d", len(m.schedules)+1)
+	}
+	m.schedules[schedule.ID] = *schedule
+	return nil
+}
+
+func (m *MockScheduleRepository) GetSchedule(ctx context.Context, id string) (*model.Schedule, error) {
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return nil, fmt.Errorf("schedule %s not found", id)
+	}
+	return &schedule, nil
+}
+
+func (m *MockScheduleRepository) DeleteSchedule(ctx context.Context, id string) error {
+	delete(m.schedules, id)
+	return nil
+}
+
+func (m *MockScheduleRepository) ListSchedules(ctx context.Context, userID string) ([]model.Schedule, error) {
+	var result []model.Schedule
+	for _, schedule := range m.schedules {
+		if schedule.UserID == userID {
+			result = append(result, schedule)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockScheduleRepository) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]model.Schedule, error) {
+	var result []model.Schedule
+	for _, schedule := range m.schedules {
+		if schedule.Enabled && !schedule.NextRunAt.After(now) {
+			result = append(result, schedule)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockScheduleRepository) ClaimScheduleRun(ctx context.Context, schedule *model.Schedule, due time.Time) (bool, error) {
+	current, ok := m.schedules[schedule.ID]
+	if !ok || !current.Enabled || !current.NextRunAt.Equal(due) {
+		return false, nil
+	}
+	m.schedules[schedule.ID] = *schedule
+	return true, nil
+}
+
+// Mock NotificationRepository
+type MockNotificationRepository struct {
+	devices  map[string]model.DeviceToken
+	settings map[string]model.NotificationSettings
+}
+
+// Ensure interface compliance
+var _ repository.NotificationRepository = &MockNotificationRepository{}
+
+func (m *MockNotificationRepository) SaveDeviceToken(ctx context.Context, device *model.DeviceToken) error {
+	if m.devices == nil {
+		m.devices = make(map[string]model.DeviceToken)
+	}
+	device.ID = repository.DeviceTokenID(device.Token)
+	m.devices[device.ID] = *device
+	return nil
+}
+
+func (m *MockNotificationRepository) DeleteDeviceToken(ctx context.Context, userID string, token string) error {
+	id := repository.DeviceTokenID(token)
+	if device, ok := m.devices[id]; ok && device.UserID == userID {
+		delete(m.devices, id)
+	}
+	return nil
+}
+
+func (m *MockNotificationRepository) ListDeviceTokens(ctx context.Context, userID string) ([]model.DeviceToken, error) {
+	var result []model.DeviceToken
+	for _, device := range m.devices {
+		if device.UserID == userID {
+			result = append(result, device)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockNotificationRepository) GetNotificationSettings(ctx context.Context, userID string) (*model.NotificationSettings, error) {
+	settings, ok := m.settings[userID]
+	if !ok {
+		return &model.NotificationSettings{ID: userID}, nil
+	}
+	return &settings, nil
+}
+
+func (m *MockNotificationRepository) SaveNotificationSettings(ctx context.Context, settings *model.NotificationSettings) error {
+	if m.settings == nil {
+		m.settings = make(map[string]model.NotificationSettings)
+	}
+	m.settings[settings.ID] = *settings
+	return nil
+}
+
+// FakePushSender records pushes instead of sending them. Tokens in
+// InvalidTokens are rejected like tokens of uninstalled apps.
+type FakePushSender struct {
+	Sent          []FakePush
+	InvalidTokens map[string]bool
+}
+
+// FakePush is one push recorded by FakePushSender
+type FakePush struct {
+	Token        string
+	Notification notify.Notification
+}
+
+// Ensure interface compliance
+var _ notify.Sender = &FakePushSender{}
+
+func (f *FakePushSender) Send(ctx context.Context, token string, n notify.Notification) error {
+	if f.InvalidTokens[token] {
+		return notify.ErrInvalidToken
+	}
+	f.Sent = append(f.Sent, FakePush{Token: token, Notification: n})
+	return nil
+}
+
+// Mock ChannelRepository
+type MockChannelRepository struct {
+	threads  map[string]model.ChannelThread
+	Accounts map[string]model.ChannelAccount // keyed by repository.ChannelDocID
+}
+
+// Ensure interface compliance
+var _ repository.ChannelRepository = &MockChannelRepository{}
+
+func (m *MockChannelRepository) GetChannelThread(ctx context.Context, channel string, conversationKey string) (*model.ChannelThread, error) {
+	link, ok := m.threads[repository.ChannelDocID(channel, conversationKey)]
+	if !ok {
+		return nil, nil
+	}
+	return &link, nil
+}
+
+func (m *MockChannelRepository) LinkChannelThread(ctx context.Context, link *model.ChannelThread) (*model.ChannelThread, bool, error) {
+	if m.threads == nil {
+		m.threads = make(map[string]model.ChannelThread)
+	}
+	id := repository.ChannelDocID(link.Channel, link.ConversationKey)
+	if existing, ok := m.threads[id]; ok {
+		return &existing, false, nil
+	}
+	link.ID = id
+	m.threads[id] = *link
+	return link, true, nil
+}
+
+func (m *MockChannelRepository) UnlinkChannelThread(ctx context.Context, channel string, conversationKey string) error {
+	delete(m.threads, repository.ChannelDocID(channel, conversationKey))
+	return nil
+}
+
+func (m *MockChannelRepository) GetChannelAccount(ctx context.Context, channel string, externalUserID string) (*model.ChannelAccount, error) {
+	account, ok := m.Accounts[repository.ChannelDocID(channel, externalUserID)]
+	if !ok {
+		return nil, nil
+	}
+	return &account, nil
+}
+
+// Mock AttachmentStore
+type MockAttachmentStore struct {
+	Files map[string][]byte // keyed by path
+}
+
+// Ensure interface compliance
+var _ repository.AttachmentStore = &MockAttachmentStore{}
+
+func (m *MockAttachmentStore) PutAttachment(ctx context.Context, path string, contentType string, data []byte) (string, error) {
+	if m.Files == nil {
+		m.Files = make(map[string][]byte)
+	}
+	m.Files[path] = data
+	return "gs://mock/" + path, nil
+}
+
+// FakeOutbound records channel replies instead of calling the Slack / LINE API
+type FakeOutbound struct {
+	Posts []FakePost
+	Err   error
+}
+
+// FakePost is one reply recorded by FakeOutbound
+type FakePost struct {
+	To   model.ChannelRef
+	Text string
+}
+
+// Ensure interface compliance
+var _ channel.Outbound = &FakeOutbound{}
+
+func (f *FakeOutbound) Post(ctx context.Context, to model.ChannelRef, text string) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	f.Posts = append(f.Posts, FakePost{To: to, Text: text})
+	return nil
+}
+
+// FakeQueue records enqueued jobs instead of running them
+type FakeQueue struct {
+	Jobs []queue.Job
+}
+
+// Ensure interface compliance
+var _ queue.Queue = &FakeQueue{}
+
+func (q *FakeQueue) Enqueue(ctx context.Context, job queue.Job) error {
+	q.Jobs = append(q.Jobs, job)
+	return nil
+}
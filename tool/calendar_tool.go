@@ -12,33 +12,22 @@ import (
 
 type CalendarToolInput struct {
 	TimeRange string `json:"timeRange" jsonschema_description:"Time range like 'today', 'this week', 'next 7 days'"`
-	Timezone  string `json:"timezone" jsonschema_description:"Timezone like 'Asia/Tokyo'"`
+	Timezone  string `json:"timezone,omitempty" jsonschema_description:"Timezone like 'Asia/Tokyo'. Defaults to the user's timezone"`
 }
 
 func CreateCalendarTool(g *genkit.Genkit, calendarRepo repository.CalendarRepository) ai.Tool {
-	return genkit.DefineTool(
+	return defineTool(
 		g,
 		"getCalendar",
 		"Retrieves calendar events for the specified time range",
-		func(ctx *ai.ToolContext, input CalendarToolInput) (string, error) {
-			// Context adaptation: *ai.ToolContext -> context.Context
-			// Assuming we can use ctx.Context() or just passed ctx as is depending on signature.
-			// ToolFunc is func(ctx *ToolContext, input In) (Out, error)
-			// But repo expects context.Context.
-			// *ToolContext usually embeds or provides context?
-			// Checking docs later, but assuming ctx.Context() exists or it implements Context.
-
-			// Temporary fix if *ToolContext is not context.Context:
-			// Assuming *ai.ToolContext has Context() method or we can use context.Background() as fallback if unsure.
-			// Actually usually it's passed as context.Context in some versions, but here it is *ai.ToolContext.
-			// I'll assume usage of context.Background() or if *ToolContext is a context.
-			// Let's use context.TODO() for now inside or check if ctx is context.Context
-			// Wait, ToolFunc definition: func(ctx *ToolContext, input In)
-			// I will use context.TODO() for repo call to be safe if I can't find how to get context from ToolContext immediately.
-			// Or better: ctx is likely NOT context.Context directly.
+		func(ctx context.Context, run RunContext, input CalendarToolInput) (string, error) {
+			timezone := input.Timezone
+			if timezone == "" {
+				timezone = run.Timezone
+			}
 
 			// Repository を使って Calendar データを取得
-			events, err := calendarRepo.GetEvents(context.Background(), input.TimeRange, input.Timezone)
+			events, err := calendarRepo.GetEvents(ctx, input.TimeRange, timezone)
 			if err != nil {
 				return "", err
 			}
@@ -0,0 +1,193 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/mcp"
+)
+
+// mcpServerName keeps namespaced tool names (<server>_<tool>) valid for the model
+var mcpServerName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]{0,31}$`)
+
+// MCPConfig is the MCP_CONFIG file listing external MCP servers
+type MCPConfig struct {
+	Servers []MCPServerConfig `json:"servers"`
+}
+
+// MCPServerConfig is one MCP server. Set Command for the stdio transport or
+// URL for streamable HTTP. Env and Headers values may reference environment
+// variables as ${NAME}.
+type MCPServerConfig struct {
+	Name     string `json:"name"`
+	Disabled bool   `json:"disabled,omitempty"`
+
+	// stdio
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+
+	// Streamable HTTP
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Timeout string            `json:"timeout,omitempty"` // e.g. "30s"
+
+	// AllowTools and DenyTools filter the server's tools by name (path.Match
+	// patterns, without the server prefix). Empty AllowTools allows every tool.
+	AllowTools []string `json:"allowTools,omitempty"`
+	DenyTools  []string `json:"denyTools,omitempty"`
+	// Every tool of a server is treated as changing an external service
+	// (denied to read-only runs, never run in parallel) unless ReadOnly is set
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// LoadMCPConfig reads and validates an MCP_CONFIG file
+func LoadMCPConfig(file string) (*MCPConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MCP config: %w", err)
+	}
+	var cfg MCPConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse MCP config %s: %w", file, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range cfg.Servers {
+		if !mcpServerName.MatchString(s.Name) {
+			return nil, fmt.Errorf("invalid MCP server name %q (letters, digits and '-', at most 32)", s.Name)
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("duplicate MCP server name %q", s.Name)
+		}
+		seen[s.Name] = true
+		if (s.Command == "") == (s.URL == "") {
+			return nil, fmt.Errorf("MCP server %s: set exactly one of command or url", s.Name)
+		}
+		if s.Timeout != "" {
+			if _, err := time.ParseDuration(s.Timeout); err != nil {
+				return nil, fmt.Errorf("MCP server %s: invalid timeout: %w", s.Name, err)
+			}
+		}
+	}
+	return &cfg, nil
+}
+
+// MCPTools are the tools discovered on the configured MCP servers
+type MCPTools struct {
+	clients []*mcp.GenkitMCPClient
+	tools   []ai.Tool
+}
+
+// ConnectMCPServers connects to the enabled servers and registers their tools
+// with g as <server>_<tool>. A server that cannot be reached is logged and
+// skipped so one broken server does not take the agent down.
+func ConnectMCPServers(ctx context.Context, g *genkit.Genkit, cfg *MCPConfig) *MCPTools {
+	m := &MCPTools{}
+	if cfg == nil {
+		return m
+	}
+
+	for _, server := range cfg.Servers {
+		if server.Disabled {
+			continue
+		}
+
+		client, err := mcp.NewGenkitMCPClient(mcpClientOptions(server))
+		if err != nil {
+			log.Printf("Warning: Failed to connect to MCP server %s: %v", server.Name, err)
+			continue
+		}
+		discovered, err := client.GetActiveTools(ctx, g)
+		if err != nil {
+			log.Printf("Warning: Failed to list tools of MCP server %s: %v", server.Name, err)
+			client.Disconnect()
+			continue
+		}
+		m.clients = append(m.clients, client)
+
+		var names []string
+		for _, t := range discovered {
+			name := strings.TrimPrefix(t.Name(), server.Name+"_")
+			if !mcpToolAllowed(server, name) {
+				continue
+			}
+			genkit.RegisterAction(g, t)
+			if !server.ReadOnly {
+				MarkExternalWrite(t)
+			}
+			m.tools = append(m.tools, t)
+			names = append(names, t.Name())
+		}
+		log.Printf("MCP server %s: %d of %d tool(s) enabled %v", server.Name, len(names), len(discovered), names)
+	}
+	return m
+}
+
+// Tools returns the discovered tools that passed the name filters
+func (m *MCPTools) Tools() []ai.Tool {
+	if m == nil {
+		return nil
+	}
+	return m.tools
+}
+
+// Close disconnects from every server (and stops stdio server processes)
+func (m *MCPTools) Close() {
+	if m == nil {
+		return
+	}
+	for _, c := range m.clients {
+		if err := c.Disconnect(); err != nil {
+			log.Printf("Warning: Failed to disconnect MCP server %s: %v", c.Name(), err)
+		}
+	}
+}
+
+func mcpClientOptions(server MCPServerConfig) mcp.MCPClientOptions {
+	opts := mcp.MCPClientOptions{Name: server.Name}
+	if server.Command != "" {
+		var env []string
+		for k, v := range server.Env {
+			env = append(env, k+"="+os.ExpandEnv(v))
+		}
+		opts.Stdio = &mcp.StdioConfig{Command: server.Command, Args: server.Args, Env: env}
+		return opts
+	}
+
+	headers := make(map[string]string, len(server.Headers))
+	for k, v := range server.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	// Validated by LoadMCPConfig
+	timeout, _ := time.ParseDuration(server.Timeout)
+	opts.StreamableHTTP = &mcp.StreamableHTTPConfig{BaseURL: server.URL, Headers: headers, Timeout: timeout}
+	return opts
+}
+
+// mcpToolAllowed applies the server's allow / deny patterns to a tool name
+func mcpToolAllowed(server MCPServerConfig, name string) bool {
+	for _, pattern := range server.DenyTools {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false
+		}
+	}
+	if len(server.AllowTools) == 0 {
+		return true
+	}
+	for _, pattern := range server.AllowTools {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MCPServerName identifies youdoyou to MCP clients
+const MCPServerName = "youdoyou"
+
+// MCPCaller returns who is calling over MCP, or an error if the request is
+// not authenticated
+type MCPCaller func(ctx context.Context) (RunContext, error)
+
+// NewMCPServer serves the built-in tools over the Model Context Protocol.
+// The MCP tools are the same ai.Tool values the agent uses, so their schemas
+// and behavior cannot drift apart. Each caller only sees and runs the tools
+// its permissions allow; timeout limits each call (0 means no limit).
+func NewMCPServer(f *ToolFactory, caller MCPCaller, timeout time.Duration) *server.MCPServer {
+	// Tools mounted from other MCP servers are not passed through
+	groups := slices.DeleteFunc(slices.Clone(AllGroups), func(g string) bool { return g == GroupMCP })
+
+	allowed := func(ctx context.Context) (RunContext, map[string]bool, error) {
+		run, err := caller(ctx)
+		if err != nil {
+			return run, nil, err
+		}
+		names := make(map[string]bool)
+		for _, t := range f.CreateToolsByDependencies(groups, run.Permissions) {
+			names[t.Name()] = true
+		}
+		return run, names, nil
+	}
+
+	s := server.NewMCPServer(MCPServerName, "1.0.0",
+		server.WithToolCapabilities(false),
+		server.WithRecovery(),
+		server.WithToolFilter(func(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+			_, names, err := allowed(ctx)
+			if err != nil {
+				return nil
+			}
+			return slices.DeleteFunc(tools, func(t mcp.Tool) bool { return !names[t.Name] })
+		}),
+	)
+
+	for _, t := range f.CreateToolsByDependencies(groups, Permissions{}) {
+		def := t.Definition()
+		schema, err := json.Marshal(def.InputSchema)
+		if err != nil {
+			log.Printf("Warning: Skipping tool %s for MCP: %v", def.Name, err)
+			continue
+		}
+
+		s.AddTool(mcp.NewToolWithRawSchema(def.Name, def.Description, schema),
+			func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				run, names, err := allowed(ctx)
+				if err != nil {
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+				if !names[t.Name()] {
+					refusal, ok := run.Permissions.Check(t.Name())
+					if ok {
+						refusal = &Refusal{Status: "denied", Tool: t.Name(), Reason: "this tool is not enabled"}
+					}
+					result := mcp.NewToolResultStructured(refusal, refusal.Error())
+					result.IsError = true
+					return result, nil
+				}
+
+				if timeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, timeout)
+					defer cancel()
+				}
+				out, err := t.RunRaw(WithRunContext(ctx, run), req.GetArguments())
+				if err != nil {
+					log.Printf("MCP tool %s failed: %v", t.Name(), err)
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+				if text, ok := out.(string); ok {
+					return mcp.NewToolResultText(text), nil
+				}
+				data, err := json.Marshal(out)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode output of %s: %w", t.Name(), err)
+				}
+				return mcp.NewToolResultText(string(data)), nil
+			},
+		)
+	}
+	return s
+}
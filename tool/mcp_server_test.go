@@ -0,0 +1,119 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"youdoyou-server/test"
+
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type callerKey struct{}
+
+// newTestMCPServer serves the task and Notion tools. The caller is the
+// RunContext stored in the request context under callerKey.
+func newTestMCPServer(taskRepo *test.MockTaskRepository) *server.MCPServer {
+	f := NewToolFactory(genkit.Init(context.Background()), nil, nil, &test.MockNotionRepository{}, taskRepo, nil, nil, nil)
+	return NewMCPServer(f, func(ctx context.Context) (RunContext, error) {
+		run, ok := ctx.Value(callerKey{}).(RunContext)
+		if !ok {
+			return RunContext{}, errors.New("unauthorized")
+		}
+		return run, nil
+	}, 0)
+}
+
+// mcpRequest sends one JSON-RPC request as caller (nil = unauthenticated)
+func mcpRequest(t *testing.T, s *server.MCPServer, caller *RunContext, method string, params any) json.RawMessage {
+	t.Helper()
+	ctx := context.Background()
+	if caller != nil {
+		ctx = context.WithValue(ctx, callerKey{}, *caller)
+	}
+	raw, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, ok := s.HandleMessage(ctx, raw).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("%s: no result", method)
+	}
+	out, err := json.Marshal(resp.Result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func listMCPTools(t *testing.T, s *server.MCPServer, caller *RunContext) []string {
+	t.Helper()
+	var result mcp.ListToolsResult
+	if err := json.Unmarshal(mcpRequest(t, s, caller, "tools/list", map[string]any{}), &result); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestMCPServerListsAllowedTools(t *testing.T) {
+	s := newTestMCPServer(&test.MockTaskRepository{})
+
+	names := listMCPTools(t, s, &RunContext{UserID: "u1"})
+	for _, want := range []string{"createTask", "listTasks", "createNotionPage"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("tools = %v, want %s", names, want)
+		}
+	}
+
+	names = listMCPTools(t, s, &RunContext{UserID: "u1", Permissions: Permissions{ReadOnly: true, Groups: []string{GroupTasks}}})
+	if slices.Contains(names, "createNotionPage") || !slices.Contains(names, "listTasks") {
+		t.Errorf("read-only tasks caller sees %v", names)
+	}
+
+	if names := listMCPTools(t, s, nil); len(names) != 0 {
+		t.Errorf("unauthenticated caller sees %v", names)
+	}
+}
+
+func TestMCPServerCallsToolAsCaller(t *testing.T) {
+	taskRepo := &test.MockTaskRepository{}
+	s := newTestMCPServer(taskRepo)
+	caller := &RunContext{UserID: "u1", Timezone: DefaultTimezone}
+
+	var result mcp.CallToolResult
+	raw := mcpRequest(t, s, caller, "tools/call", map[string]any{"name": "createTask", "arguments": map[string]any{"title": "牛乳を買う"}})
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError {
+		t.Fatalf("createTask failed: %s", raw)
+	}
+	tasks, err := taskRepo.ListTasks(context.Background(), "u1", false)
+	if err != nil || len(tasks) != 1 || tasks[0].Title != "牛乳を買う" {
+		t.Errorf("tasks of u1 = %+v, %v, want the new task", tasks, err)
+	}
+}
+
+func TestMCPServerRefusesDeniedTool(t *testing.T) {
+	s := newTestMCPServer(&test.MockTaskRepository{})
+	caller := &RunContext{UserID: "u1", Permissions: Permissions{ReadOnly: true, Reason: "this user is read-only"}}
+
+	raw := mcpRequest(t, s, caller, "tools/call", map[string]any{"name": "createNotionPage", "arguments": map[string]any{}})
+	var result mcp.CallToolResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError || !strings.Contains(string(raw), "this user is read-only") {
+		t.Errorf("result = %s, want a refusal", raw)
+	}
+}
@@ -0,0 +1,97 @@
+package tool
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// startMCPServer serves tools with the given names over streamable HTTP and
+// returns the endpoint. Each tool answers with its own name.
+func startMCPServer(t *testing.T, tools ...string) string {
+	t.Helper()
+	s := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(false))
+	for _, name := range tools {
+		s.AddTool(mcp.NewTool(name, mcp.WithDescription(name)),
+			func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return mcp.NewToolResultText(req.Params.Name), nil
+			})
+	}
+	ts := server.NewTestStreamableHTTPServer(s)
+	t.Cleanup(ts.Close)
+	return ts.URL + "/mcp"
+}
+
+func connect(t *testing.T, servers ...MCPServerConfig) []string {
+	t.Helper()
+	ctx := context.Background()
+	m := ConnectMCPServers(ctx, genkit.Init(ctx), &MCPConfig{Servers: servers})
+	t.Cleanup(m.Close)
+
+	var names []string
+	for _, tool := range m.Tools() {
+		names = append(names, tool.Name())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestConnectMCPServersNamesAndFiltersTools(t *testing.T) {
+	url := startMCPServer(t, "read_note", "list_notes", "delete_note", "write_note")
+
+	names := connect(t, MCPServerConfig{
+		Name:       "notes",
+		URL:        url,
+		AllowTools: []string{"read_*", "list_*", "delete_*"},
+		DenyTools:  []string{"delete_*"},
+	})
+
+	want := []string{"notes_list_notes", "notes_read_note"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("tools = %v, want %v", names, want)
+	}
+}
+
+func TestConnectMCPServersTreatsToolsAsWrites(t *testing.T) {
+	url := startMCPServer(t, "create_ticket")
+
+	names := connect(t, MCPServerConfig{Name: "tickets", URL: url})
+
+	if !slices.Equal(names, []string{"tickets_create_ticket"}) {
+		t.Fatalf("tools = %v", names)
+	}
+	if !IsExternalWrite("tickets_create_ticket") || ParallelSafe("tickets_create_ticket") {
+		t.Error("tools of a server without readOnly must be sequential writes")
+	}
+}
+
+func TestConnectMCPServersReadOnly(t *testing.T) {
+	url := startMCPServer(t, "search_docs")
+
+	names := connect(t, MCPServerConfig{Name: "docs", URL: url, ReadOnly: true})
+
+	if !slices.Equal(names, []string{"docs_search_docs"}) {
+		t.Fatalf("tools = %v", names)
+	}
+	if IsExternalWrite("docs_search_docs") || !ParallelSafe("docs_search_docs") {
+		t.Error("tools of a readOnly server must be parallel-safe reads")
+	}
+}
+
+func TestConnectMCPServersSkipsDisabledAndUnreachable(t *testing.T) {
+	url := startMCPServer(t, "ping")
+
+	names := connect(t,
+		MCPServerConfig{Name: "off", URL: url, Disabled: true},
+		MCPServerConfig{Name: "down", URL: "http://127.0.0.1:1/mcp", Timeout: "1s"},
+		MCPServerConfig{Name: "up", URL: url},
+	)
+
+	if !slices.Equal(names, []string{"up_ping"}) {
+		t.Fatalf("tools = %v, want only the reachable, enabled server's", names)
+	}
+}
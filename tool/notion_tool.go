@@ -16,13 +16,13 @@ type NotionToolInput struct {
 }
 
 func CreateNotionTool(g *genkit.Genkit, notionRepo repository.NotionRepository) ai.Tool {
-	return genkit.DefineTool(
+	return defineTool(
 		g,
 		"getNotion",
 		"Queries Notion database and returns pages matching the filter",
-		func(ctx *ai.ToolContext, input NotionToolInput) (string, error) {
+		func(ctx context.Context, run RunContext, input NotionToolInput) (string, error) {
 			// Repository を使って Notion データを取得
-			pages, err := notionRepo.QueryDatabase(context.Background(), input.DatabaseID, input.Filter)
+			pages, err := notionRepo.QueryDatabase(ctx, input.DatabaseID, input.Filter)
 			if err != nil {
 				return "", err
 			}
@@ -38,20 +38,21 @@ type NotionCreateInput struct {
 	Properties map[string]interface{} `json:"properties" jsonschema_description:"Page properties to create"`
 }
 
+// CreateNotionWriteTool は外部への書き込みを行うため、並列に実行せず、読み取り専用の実行では拒否される
 func CreateNotionWriteTool(g *genkit.Genkit, notionRepo repository.NotionRepository) ai.Tool {
-	return genkit.DefineTool(
+	return MarkExternalWrite(defineTool(
 		g,
 		"createNotionPage",
 		"Creates a new page in Notion database",
-		func(ctx *ai.ToolContext, input NotionCreateInput) (string, error) {
-			pageID, err := notionRepo.CreatePage(context.Background(), input.DatabaseID, input.Properties)
+		func(ctx context.Context, run RunContext, input NotionCreateInput) (string, error) {
+			pageID, err := notionRepo.CreatePage(ctx, input.DatabaseID, input.Properties)
 			if err != nil {
 				return "", err
 			}
 
 			return "Page created with ID: " + pageID, nil
 		},
-	)
+	))
 }
 
 func formatNotionResult(pages []model.NotionPage) string {
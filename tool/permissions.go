@@ -0,0 +1,45 @@
+package tool
+
+// Permissions are the capabilities granted to tools for one run, resolved
+// from the tool policies of the user and thread
+type Permissions struct {
+	// Groups are the tool groups exposed to the model (see CreateToolsByDependencies).
+	// Nil means every group.
+	Groups []string
+	// DeniedTools are tools the run may not call even if their group is exposed
+	DeniedTools []string
+	// ReadOnly denies tools that change external services
+	ReadOnly bool
+	// Reason explains ReadOnly to the model, e.g. "this thread is private"
+	Reason string
+}
+
+// Check returns whether the run may call the named tool, and if not, a
+// refusal to give the model instead of the tool's output
+func (p Permissions) Check(name string) (*Refusal, bool) {
+	for _, denied := range p.DeniedTools {
+		if denied == name {
+			return &Refusal{Status: "denied", Tool: name, Reason: "this tool is disabled by policy"}, false
+		}
+	}
+	if p.ReadOnly && IsExternalWrite(name) {
+		reason := "external write tools are not allowed"
+		if p.Reason != "" {
+			reason += ": " + p.Reason
+		}
+		return &Refusal{Status: "denied", Tool: name, Reason: reason}, false
+	}
+	return nil, true
+}
+
+// Refusal is the tool response for a call the run is not allowed to make.
+// It is structured so the model can tell the user why instead of retrying.
+type Refusal struct {
+	Status string `json:"status"`
+	Tool   string `json:"tool"`
+	Reason string `json:"reason"`
+}
+
+func (r *Refusal) Error() string {
+	return "tool " + r.Tool + " " + r.Status + ": " + r.Reason
+}
@@ -0,0 +1,41 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+func TestPermissionsCheck(t *testing.T) {
+	g := genkit.Init(context.Background())
+	MarkExternalWrite(genkit.DefineTool(g, "testWrite", "writes", func(ctx *ai.ToolContext, in struct{}) (string, error) {
+		return "", nil
+	}))
+
+	cases := []struct {
+		name   string
+		perms  Permissions
+		tool   string
+		ok     bool
+		reason string
+	}{
+		{"allowed", Permissions{}, "testWrite", true, ""},
+		{"denied by name", Permissions{DeniedTools: []string{"searchMessages"}}, "searchMessages", false, "disabled by policy"},
+		{"read-only write", Permissions{ReadOnly: true, Reason: "this thread is private"}, "testWrite", false, "this thread is private"},
+		{"read-only read", Permissions{ReadOnly: true}, "searchMessages", true, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			refusal, ok := c.perms.Check(c.tool)
+			if ok != c.ok {
+				t.Fatalf("Check(%q) ok = %v, want %v", c.tool, ok, c.ok)
+			}
+			if !ok && !strings.Contains(refusal.Reason, c.reason) {
+				t.Errorf("Reason = %q, want it to mention %q", refusal.Reason, c.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package tool
+
+import "context"
+
+// DefaultTimezone is used when the thread has no timezone
+const DefaultTimezone = "Asia/Tokyo"
+
+// RunContext identifies the agent run a tool is called from, so tools can
+// scope data to the caller
+type RunContext struct {
+	UserID   string
+	ThreadID string
+	Timezone string
+	// Permissions limits what tools may do on the caller's behalf
+	Permissions Permissions
+}
+
+type runContextKey struct{}
+
+// WithRunContext returns a context carrying rc for the tools called with it
+func WithRunContext(ctx context.Context, rc RunContext) context.Context {
+	return context.WithValue(ctx, runContextKey{}, rc)
+}
+
+// RunContextFrom returns the RunContext of ctx. Outside an agent run it is
+// empty except for the default timezone.
+func RunContextFrom(ctx context.Context) RunContext {
+	rc, _ := ctx.Value(runContextKey{}).(RunContext)
+	if rc.Timezone == "" {
+		rc.Timezone = DefaultTimezone
+	}
+	return rc
+}
@@ -0,0 +1,30 @@
+package tool
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// ToolFunc is the body of a tool. ctx is the context of the tool call
+// (deadline, cancellation, tracing) and run identifies the caller.
+type ToolFunc[In, Out any] func(ctx context.Context, run RunContext, input In) (Out, error)
+
+// defineTool registers a Genkit tool whose body receives the call's context
+// and RunContext instead of the raw *ai.ToolContext. Calls the run's
+// permissions deny fail with a *Refusal before the body runs.
+func defineTool[In, Out any](g *genkit.Genkit, name string, description string, fn ToolFunc[In, Out]) ai.Tool {
+	return genkit.DefineTool(g, name, description,
+		func(tc *ai.ToolContext, input In) (Out, error) {
+			// *ai.ToolContext embeds the context passed to RunRaw
+			var ctx context.Context = tc
+			run := RunContextFrom(ctx)
+			if refusal, ok := run.Permissions.Check(name); !ok {
+				var zero Out
+				return zero, refusal
+			}
+			return fn(ctx, run, input)
+		},
+	)
+}
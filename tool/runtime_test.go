@@ -0,0 +1,54 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/genkit"
+)
+
+type ctxKey struct{}
+
+func TestDefineToolPassesCallContext(t *testing.T) {
+	g := genkit.Init(context.Background())
+	var gotRun RunContext
+	var gotValue any
+	var hasDeadline bool
+	echo := defineTool(g, "testEcho", "echo", func(ctx context.Context, run RunContext, in struct{}) (string, error) {
+		gotRun, gotValue = run, ctx.Value(ctxKey{})
+		_, hasDeadline = ctx.Deadline()
+		return "ok", nil
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "traced")
+	ctx = WithRunContext(ctx, RunContext{UserID: "u1", ThreadID: "t1"})
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	if _, err := echo.RunRaw(ctx, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if gotRun.UserID != "u1" || gotRun.ThreadID != "t1" || gotRun.Timezone != DefaultTimezone {
+		t.Errorf("run = %+v, want u1/t1 in the default timezone", gotRun)
+	}
+	if gotValue != "traced" || !hasDeadline {
+		t.Errorf("tool did not get the call's context (value %v, deadline %v)", gotValue, hasDeadline)
+	}
+}
+
+func TestDefineToolRefusesDeniedCalls(t *testing.T) {
+	g := genkit.Init(context.Background())
+	denied := defineTool(g, "testDenied", "denied", func(ctx context.Context, run RunContext, in struct{}) (string, error) {
+		t.Error("denied tool ran")
+		return "", nil
+	})
+
+	ctx := WithRunContext(context.Background(), RunContext{Permissions: Permissions{DeniedTools: []string{"testDenied"}}})
+	_, err := denied.RunRaw(ctx, map[string]any{})
+	var refusal *Refusal
+	if !errors.As(err, &refusal) || refusal.Tool != "testDenied" {
+		t.Errorf("RunRaw = %v, want a refusal", err)
+	}
+}
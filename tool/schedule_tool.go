@@ -0,0 +1,162 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/recurrence"
+	"youdoyou-server/repository"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+const (
+	// minScheduleInterval keeps schedules from running the agent too often
+	minScheduleInterval = 15 * time.Minute
+	// scheduleCheckWindow is how far ahead minScheduleInterval is checked
+	scheduleCheckWindow = 366 * 24 * time.Hour
+)
+
+type ScheduleCreateInput struct {
+	Title     string `json:"title" jsonschema_description:"Short name of the schedule, e.g. 'Morning briefing'"`
+	Cron      string `json:"cron" jsonschema_description:"When to run, as a 5-field cron expression (minute hour day month weekday) in the user's timezone, e.g. '0 8 * * 1-5' for weekdays at 8:00"`
+	Prompt    string `json:"prompt" jsonschema_description:"What the assistant should do on each run, written as a request from the user"`
+	NewThread bool   `json:"newThread,omitempty" jsonschema_description:"Post each run to a new thread instead of this one"`
+	CatchUp   string `json:"catchUp,omitempty" jsonschema_description:"If a run was missed: once (default) runs it late, skip waits for the next time"`
+}
+
+type ScheduleListInput struct{}
+
+type ScheduleIDInput struct {
+	ScheduleID string `json:"scheduleId" jsonschema_description:"ID of the schedule"`
+}
+
+// CreateScheduleTools は定期実行 (スケジュール) を操作する Tool を返す
+func CreateScheduleTools(g *genkit.Genkit, scheduleRepo repository.ScheduleRepository) []ai.Tool {
+	return []ai.Tool{
+		MarkSequential(defineTool(g, "createSchedule",
+			"Schedules a request that the assistant runs on its own at the given times, e.g. a daily briefing. Each run's answer is posted to this thread (or a new thread)",
+			func(ctx context.Context, run RunContext, input ScheduleCreateInput) (string, error) {
+				if run.UserID == "" {
+					return "", errNoUser
+				}
+				now := time.Now()
+				schedule := &model.Schedule{
+					UserID:    run.UserID,
+					Title:     strings.TrimSpace(input.Title),
+					Cron:      strings.TrimSpace(input.Cron),
+					Timezone:  run.Timezone,
+					Prompt:    strings.TrimSpace(input.Prompt),
+					NewThread: input.NewThread,
+					CatchUp:   model.CatchUpOnce,
+					Enabled:   true,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+				if schedule.Title == "" || schedule.Prompt == "" {
+					return "", fmt.Errorf("title and prompt are required")
+				}
+				if !input.NewThread {
+					schedule.ThreadID = run.ThreadID
+				}
+				switch input.CatchUp {
+				case "":
+				case model.CatchUpOnce, model.CatchUpSkip:
+					schedule.CatchUp = input.CatchUp
+				default:
+					return "", fmt.Errorf("catchUp must be once or skip")
+				}
+
+				loc, err := time.LoadLocation(run.Timezone)
+				if err != nil {
+					return "", fmt.Errorf("invalid timezone %q", run.Timezone)
+				}
+				if err := checkScheduleInterval(schedule.Cron, now, loc); err != nil {
+					return "", err
+				}
+				schedule.NextRunAt, err = recurrence.NextCron(schedule.Cron, now, loc)
+				if err != nil {
+					return "", err
+				}
+				if err := scheduleRepo.SaveSchedule(ctx, schedule); err != nil {
+					return "", err
+				}
+				return "Schedule created: " + formatSchedule(schedule), nil
+			},
+		)),
+		defineTool(g, "listSchedules",
+			"Lists the user's scheduled requests, next run first",
+			func(ctx context.Context, run RunContext, input ScheduleListInput) (string, error) {
+				if run.UserID == "" {
+					return "", errNoUser
+				}
+				schedules, err := scheduleRepo.ListSchedules(ctx, run.UserID)
+				if err != nil {
+					return "", err
+				}
+				if len(schedules) == 0 {
+					return "No schedules", nil
+				}
+				var result string
+				for i := range schedules {
+					result += "- " + formatSchedule(&schedules[i]) + "\n"
+				}
+				return result, nil
+			},
+		),
+		MarkSequential(defineTool(g, "deleteSchedule",
+			"Deletes a scheduled request so it no longer runs",
+			func(ctx context.Context, run RunContext, input ScheduleIDInput) (string, error) {
+				if run.UserID == "" {
+					return "", errNoUser
+				}
+				schedule, err := scheduleRepo.GetSchedule(ctx, input.ScheduleID)
+				if err != nil || schedule.UserID != run.UserID {
+					return "", fmt.Errorf("schedule %s not found", input.ScheduleID)
+				}
+				if err := scheduleRepo.DeleteSchedule(ctx, schedule.ID); err != nil {
+					return "", err
+				}
+				return "Schedule deleted: " + schedule.Title, nil
+			},
+		)),
+	}
+}
+
+// checkScheduleInterval rejects expressions that fire more often than
+// minScheduleInterval at any point over the next year, which covers the
+// irregular ones (lists, ranges, particular days or months) as well
+func checkScheduleInterval(expr string, now time.Time, loc *time.Location) error {
+	gap, err := recurrence.ShortestCronGap(expr, now, scheduleCheckWindow, minScheduleInterval, loc)
+	if err != nil {
+		return err
+	}
+	if gap < minScheduleInterval {
+		return fmt.Errorf("schedules may run at most every %s", minScheduleInterval)
+	}
+	return nil
+}
+
+func formatSchedule(schedule *model.Schedule) string {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	result := schedule.Title + " (ID: " + schedule.ID + ", cron: " + schedule.Cron + " " + schedule.Timezone
+	if schedule.Enabled {
+		result += ", next: " + schedule.NextRunAt.In(loc).Format("2006-01-02 15:04")
+	} else {
+		result += ", disabled"
+	}
+	if schedule.NewThread {
+		result += ", new thread each run"
+	}
+	if schedule.CatchUp == model.CatchUpSkip {
+		result += ", skips missed runs"
+	}
+	return result + ")\n  " + schedule.Prompt
+}
@@ -0,0 +1,40 @@
+package tool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckScheduleInterval(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A Monday morning, so the irregular runs below are days or months away
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, tokyo)
+
+	cases := []struct {
+		cron string
+		ok   bool
+	}{
+		{"0 17 * * 5", true},
+		{"*/15 * * * *", true},
+		{"0 8 * * 1-5", true},
+		{"0,50 8,12,16,20 * * *", true},
+		{"*/5 * * * *", false},
+		{"0,1 9 * * *", false},
+		// 23:50 and 0:00 are 10 minutes apart, nine runs after now
+		{"0,50 0,8,12,16,20,23 * * *", false},
+		// Bursts weeks or months away
+		{"0,10 9 1 12 *", false},
+		{"*/10 9 15 * *", false},
+	}
+	for _, c := range cases {
+		t.Run(c.cron, func(t *testing.T) {
+			err := checkScheduleInterval(c.cron, now, tokyo)
+			if (err == nil) != c.ok {
+				t.Errorf("checkScheduleInterval(%q) = %v, want ok=%v", c.cron, err, c.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+
+	"youdoyou-server/search"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+type SearchToolInput struct {
+	Query string `json:"query" jsonschema_description:"Keywords to search for in past conversations, e.g. 'Project X 決定'"`
+	Limit int    `json:"limit,omitempty" jsonschema_description:"Maximum number of results (default 10)"`
+}
+
+func CreateSearchTool(g *genkit.Genkit, searcher *search.Searcher) ai.Tool {
+	return defineTool(
+		g,
+		"searchConversations",
+		"Searches past conversation messages by keyword. Use it to recall earlier discussions and decisions",
+		func(ctx context.Context, run RunContext, input SearchToolInput) (string, error) {
+			limit := input.Limit
+			if limit <= 0 {
+				limit = 10
+			}
+
+			// Only the caller's own threads are searched; without a caller nothing is found
+			results, err := searcher.Search(ctx, input.Query, run.UserID, limit)
+			if err != nil {
+				return "", err
+			}
+
+			return formatSearchResult(results), nil
+		},
+	)
+}
+
+func formatSearchResult(results []search.Result) string {
+	if len(results) == 0 {
+		return "No matching messages found"
+	}
+	var result string
+	for _, r := range results {
+		result += fmt.Sprintf("[%s] %s (thread: %s, %s): %s\n",
+			r.CreatedAt.Format("2006-01-02 15:04"), r.Role, r.ThreadTitle, r.ThreadID, r.Snippet)
+	}
+	return result
+}
@@ -0,0 +1,273 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"youdoyou-server/model"
+	"youdoyou-server/recurrence"
+	"youdoyou-server/repository"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+)
+
+// Accepted dueAt formats, interpreted in the user's timezone unless they carry an offset
+var dueAtLayouts = []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+// errNoUser is returned when a task tool runs without a known caller
+var errNoUser = errors.New("tasks are only available to a signed-in user")
+
+type TaskCreateInput struct {
+	Title    string `json:"title" jsonschema_description:"What to do"`
+	Notes    string `json:"notes,omitempty" jsonschema_description:"Optional details"`
+	DueAt    string `json:"dueAt,omitempty" jsonschema_description:"When to remind, e.g. '2025-01-31 09:00' in the user's timezone or RFC 3339. Omit for no reminder"`
+	RRule    string `json:"rrule,omitempty" jsonschema_description:"Recurrence as an iCalendar RRULE, e.g. 'FREQ=WEEKLY;BYDAY=MO'. Requires dueAt (the first occurrence)"`
+	Priority string `json:"priority,omitempty" jsonschema_description:"low, normal (default) or high"`
+}
+
+type TaskListInput struct {
+	IncludeCompleted bool `json:"includeCompleted,omitempty" jsonschema_description:"Also list completed tasks"`
+}
+
+type TaskUpdateInput struct {
+	TaskID   string `json:"taskId" jsonschema_description:"ID of the task"`
+	Title    string `json:"title,omitempty" jsonschema_description:"New title (omit to keep)"`
+	Notes    string `json:"notes,omitempty" jsonschema_description:"New notes (omit to keep)"`
+	DueAt    string `json:"dueAt,omitempty" jsonschema_description:"New due date (omit to keep, 'none' to remove the reminder)"`
+	RRule    string `json:"rrule,omitempty" jsonschema_description:"New RRULE (omit to keep, 'none' to stop recurring)"`
+	Priority string `json:"priority,omitempty" jsonschema_description:"low, normal or high (omit to keep)"`
+}
+
+type TaskIDInput struct {
+	TaskID string `json:"taskId" jsonschema_description:"ID of the task"`
+}
+
+// CreateTaskTools はネイティブのタスク (リマインダー) を操作する Tool を返す
+func CreateTaskTools(g *genkit.Genkit, taskRepo repository.TaskRepository) []ai.Tool {
+	return []ai.Tool{
+		MarkSequential(defineTool(g, "createTask",
+			"Creates a todo / reminder. A reminder is posted to this thread when it is due",
+			func(ctx context.Context, run RunContext, input TaskCreateInput) (string, error) {
+				if run.UserID == "" {
+					return "", errNoUser
+				}
+				now := time.Now()
+				task := &model.Task{
+					UserID:    run.UserID,
+					ThreadID:  run.ThreadID,
+					Title:     strings.TrimSpace(input.Title),
+					Notes:     input.Notes,
+					Timezone:  run.Timezone,
+					Priority:  model.TaskPriorityNormal,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+				if task.Title == "" {
+					return "", fmt.Errorf("title is required")
+				}
+				if err := applyTaskChanges(task, run, "", input.DueAt, input.RRule, input.Priority); err != nil {
+					return "", err
+				}
+				if err := taskRepo.SaveTask(ctx, task); err != nil {
+					return "", err
+				}
+				return "Task created: " + formatTask(task, run.Timezone), nil
+			},
+		)),
+		defineTool(g, "listTasks",
+			"Lists the user's todos / reminders, soonest due first",
+			func(ctx context.Context, run RunContext, input TaskListInput) (string, error) {
+				if run.UserID == "" {
+					return "", errNoUser
+				}
+				tasks, err := taskRepo.ListTasks(ctx, run.UserID, input.IncludeCompleted)
+				if err != nil {
+					return "", err
+				}
+				if len(tasks) == 0 {
+					return "No tasks", nil
+				}
+				var result string
+				for i := range tasks {
+					result += "- " + formatTask(&tasks[i], run.Timezone) + "\n"
+				}
+				return result, nil
+			},
+		),
+		MarkSequential(defineTool(g, "updateTask",
+			"Changes a todo / reminder. Only the given fields change",
+			func(ctx context.Context, run RunContext, input TaskUpdateInput) (string, error) {
+				task, err := ownTask(ctx, taskRepo, run, input.TaskID)
+				if err != nil {
+					return "", err
+				}
+				if input.Title != "" {
+					task.Title = input.Title
+				}
+				if input.Notes != "" {
+					task.Notes = input.Notes
+				}
+				if err := applyTaskChanges(task, run, task.RRule, input.DueAt, input.RRule, input.Priority); err != nil {
+					return "", err
+				}
+				task.UpdatedAt = time.Now()
+				if err := taskRepo.SaveTask(ctx, task); err != nil {
+					return "", err
+				}
+				return "Task updated: " + formatTask(task, run.Timezone), nil
+			},
+		)),
+		MarkSequential(defineTool(g, "completeTask",
+			"Marks a todo as done. A recurring task moves to its next occurrence",
+			func(ctx context.Context, run RunContext, input TaskIDInput) (string, error) {
+				task, err := ownTask(ctx, taskRepo, run, input.TaskID)
+				if err != nil {
+					return "", err
+				}
+
+				// Overdue occurrences are skipped; early completion covers the current one
+				now := time.Now()
+				after := now
+				if task.DueAt.After(now) {
+					after = task.DueAt
+				}
+				advanced, err := recurrence.AdvanceTask(task, after)
+				if err != nil {
+					return "", err
+				}
+				if !advanced {
+					task.Completed = true
+					task.CompletedAt = now
+				}
+				task.UpdatedAt = now
+				if err := taskRepo.SaveTask(ctx, task); err != nil {
+					return "", err
+				}
+				if advanced {
+					return "Done. Next occurrence: " + formatTask(task, run.Timezone), nil
+				}
+				return "Task completed: " + task.Title, nil
+			},
+		)),
+		MarkSequential(defineTool(g, "deleteTask",
+			"Deletes a todo / reminder",
+			func(ctx context.Context, run RunContext, input TaskIDInput) (string, error) {
+				task, err := ownTask(ctx, taskRepo, run, input.TaskID)
+				if err != nil {
+					return "", err
+				}
+				if err := taskRepo.DeleteTask(ctx, task.ID); err != nil {
+					return "", err
+				}
+				return "Task deleted: " + task.Title, nil
+			},
+		)),
+	}
+}
+
+// ownTask loads a task of the caller. Other users' tasks are reported as missing.
+func ownTask(ctx context.Context, taskRepo repository.TaskRepository, run RunContext, id string) (*model.Task, error) {
+	if run.UserID == "" {
+		return nil, errNoUser
+	}
+	task, err := taskRepo.GetTask(ctx, id)
+	if err != nil || task.UserID != run.UserID {
+		return nil, fmt.Errorf("task %s not found", id)
+	}
+	return task, nil
+}
+
+// applyTaskChanges sets the due date, recurrence and priority from tool input.
+// Empty input keeps the current value and "none" clears it.
+func applyTaskChanges(task *model.Task, run RunContext, currentRule string, dueAt string, rule string, priority string) error {
+	switch dueAt {
+	case "":
+	case "none":
+		task.DueAt = time.Time{}
+	default:
+		t, err := parseDueAt(dueAt, run.Timezone)
+		if err != nil {
+			return err
+		}
+		task.DueAt = t
+		task.RemindedAt = time.Time{}
+		task.SeriesStart = time.Time{}
+	}
+
+	switch rule {
+	case "":
+	case "none":
+		task.RRule = ""
+		task.SeriesStart = time.Time{}
+	default:
+		if err := recurrence.Validate(rule); err != nil {
+			return err
+		}
+		task.RRule = strings.TrimPrefix(rule, "RRULE:")
+		task.SeriesStart = time.Time{}
+	}
+	if task.RRule != "" {
+		if task.DueAt.IsZero() {
+			return fmt.Errorf("a recurring task needs dueAt, the first occurrence")
+		}
+		if task.SeriesStart.IsZero() || task.RRule != currentRule {
+			task.SeriesStart = task.DueAt
+		}
+		task.Timezone = run.Timezone
+	}
+
+	if priority != "" {
+		switch priority {
+		case model.TaskPriorityLow, model.TaskPriorityNormal, model.TaskPriorityHigh:
+			task.Priority = priority
+		default:
+			return fmt.Errorf("priority must be low, normal or high")
+		}
+	}
+	return nil
+}
+
+func parseDueAt(value string, timezone string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	for _, layout := range dueAtLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			if layout == "2006-01-02" {
+				// A date alone is reminded in the morning
+				t = t.Add(9 * time.Hour)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid dueAt %q, use '2006-01-02 15:04' or RFC 3339", value)
+}
+
+func formatTask(task *model.Task, timezone string) string {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	result := task.Title + " (ID: " + task.ID
+	if !task.DueAt.IsZero() {
+		result += ", due: " + task.DueAt.In(loc).Format("2006-01-02 15:04")
+	}
+	if task.RRule != "" {
+		result += ", repeats: " + task.RRule
+	}
+	if task.Priority != "" && task.Priority != model.TaskPriorityNormal {
+		result += ", priority: " + task.Priority
+	}
+	if task.Completed {
+		result += ", completed"
+	}
+	return result + ")"
+}
@@ -1,17 +1,44 @@
 package tool
 
 import (
+	"slices"
+	"sync"
+
 	"youdoyou-server/repository"
+	"youdoyou-server/search"
 
 	"github.com/firebase/genkit/go/ai"
 	"github.com/firebase/genkit/go/genkit"
 )
 
+// Tool groups understood by CreateToolsByDependencies
+const (
+	GroupCalendar = "calendar"
+	GroupNotion   = "notion"
+	GroupSearch   = "search"
+	GroupTasks    = "tasks"
+	// GroupSchedules holds the tools that manage scheduled agent runs
+	GroupSchedules = "schedules"
+	// GroupMCP holds the tools of the external MCP servers
+	GroupMCP = "mcp"
+)
+
+// AllGroups lists every tool group
+var AllGroups = []string{GroupCalendar, GroupNotion, GroupSearch, GroupTasks, GroupSchedules, GroupMCP}
+
 type ToolFactory struct {
 	g            *genkit.Genkit
 	chatRepo     repository.ChatRepository
 	calendarRepo repository.CalendarRepository
 	notionRepo   repository.NotionRepository
+	taskRepo     repository.TaskRepository
+	scheduleRepo repository.ScheduleRepository
+	searcher     *search.Searcher
+	mcpTools     *MCPTools
+
+	// Genkit panics when a tool name is defined twice, so each group is defined once
+	mu     sync.Mutex
+	groups map[string][]ai.Tool
 }
 
 func NewToolFactory(
@@ -19,40 +46,88 @@ func NewToolFactory(
 	chatRepo repository.ChatRepository,
 	calendarRepo repository.CalendarRepository,
 	notionRepo repository.NotionRepository,
+	taskRepo repository.TaskRepository,
+	scheduleRepo repository.ScheduleRepository,
+	searcher *search.Searcher,
+	mcpTools *MCPTools,
 ) *ToolFactory {
 	return &ToolFactory{
 		g:            g,
 		chatRepo:     chatRepo,
 		calendarRepo: calendarRepo,
 		notionRepo:   notionRepo,
+		taskRepo:     taskRepo,
+		scheduleRepo: scheduleRepo,
+		searcher:     searcher,
+		mcpTools:     mcpTools,
+		groups:       make(map[string][]ai.Tool),
 	}
 }
 
 // 複数の Tool を一度に返す
 func (f *ToolFactory) CreateAllTools() []ai.Tool {
-	return []ai.Tool{
-		// CreateCalendarTool(f.g, f.calendarRepo),
-		CreateNotionTool(f.g, f.notionRepo),
-		CreateNotionWriteTool(f.g, f.notionRepo),
-	}
+	return f.CreateToolsByDependencies(AllGroups, Permissions{})
 }
 
 // 特定の Tool だけ返す
-// Not used in current service but keeping it compliant
-func (f *ToolFactory) CreateToolsByDependencies(deps []string) []ai.Tool {
+// perms で許可されていないグループ・Tool は除外する
+func (f *ToolFactory) CreateToolsByDependencies(deps []string, perms Permissions) []ai.Tool {
 	var tools []ai.Tool
 
 	for _, dep := range deps {
-		switch dep {
-		case "calendar":
+		if perms.Groups != nil && !slices.Contains(perms.Groups, dep) {
+			continue
+		}
+		for _, t := range f.group(dep) {
+			if _, ok := perms.Check(t.Name()); ok {
+				tools = append(tools, t)
+			}
+		}
+	}
+
+	return tools
+}
+
+// group returns the tools of a group, defining them on first use.
+// Groups whose backend is not configured are empty.
+func (f *ToolFactory) group(dep string) []ai.Tool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if tools, ok := f.groups[dep]; ok {
+		return tools
+	}
+
+	var tools []ai.Tool
+	switch dep {
+	case GroupCalendar:
+		if f.calendarRepo != nil {
 			tools = append(tools, CreateCalendarTool(f.g, f.calendarRepo))
-		case "notion":
+		}
+	case GroupNotion:
+		if f.notionRepo != nil {
 			tools = append(tools,
 				CreateNotionTool(f.g, f.notionRepo),
 				CreateNotionWriteTool(f.g, f.notionRepo),
 			)
 		}
+	case GroupSearch:
+		if f.searcher != nil {
+			tools = append(tools, CreateSearchTool(f.g, f.searcher))
+		}
+	case GroupTasks:
+		if f.taskRepo != nil {
+			tools = append(tools, CreateTaskTools(f.g, f.taskRepo)...)
+		}
+	case GroupSchedules:
+		if f.scheduleRepo != nil {
+			tools = append(tools, CreateScheduleTools(f.g, f.scheduleRepo)...)
+		}
+	case GroupMCP:
+		// Already registered by ConnectMCPServers
+		tools = f.mcpTools.Tools()
 	}
 
+	f.groups[dep] = tools
 	return tools
 }
@@ -0,0 +1,47 @@
+package tool
+
+import (
+	"sync"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// Traits declared by tools, keyed by tool name
+var (
+	traitsMu        sync.RWMutex
+	sequentialTools = map[string]bool{}
+	writeTools      = map[string]bool{}
+)
+
+// MarkSequential declares t unsafe to run in parallel with other tool calls
+func MarkSequential(t ai.Tool) ai.Tool {
+	traitsMu.Lock()
+	defer traitsMu.Unlock()
+	sequentialTools[t.Name()] = true
+	return t
+}
+
+// MarkExternalWrite declares that t changes an external service. Such tools
+// are denied to read-only runs and never run in parallel.
+func MarkExternalWrite(t ai.Tool) ai.Tool {
+	traitsMu.Lock()
+	defer traitsMu.Unlock()
+	sequentialTools[t.Name()] = true
+	writeTools[t.Name()] = true
+	return t
+}
+
+// ParallelSafe reports whether the named tool may run concurrently with
+// other tool calls of the same turn
+func ParallelSafe(name string) bool {
+	traitsMu.RLock()
+	defer traitsMu.RUnlock()
+	return !sequentialTools[name]
+}
+
+// IsExternalWrite reports whether the named tool changes an external service
+func IsExternalWrite(name string) bool {
+	traitsMu.RLock()
+	defer traitsMu.RUnlock()
+	return writeTools[name]
+}